@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/yourusername/dockerfile-parser/internal/version"
+)
+
+// subcommand is an optimizer command run as "optimizer NAME [flags] [args]"
+type subcommand struct {
+	summary string
+	run     func(args []string) int
+}
+
+var subcommands = map[string]subcommand{
+	"pin":    {summary: "Pin FROM lines to the digests in a lockfile", run: runPin},
+	"size":   {summary: "Estimate the size of every layer per stage", run: runSize},
+	"split":  {summary: "Propose a multi-stage split of the final stage", run: runSplit},
+	"whatif": {summary: "Show which layers lose their cache when files or build args change", run: runWhatIf},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch name := os.Args[1]; name {
+	case "version", "-version", "--version":
+		fmt.Println(version.Version)
+	case "help", "-h", "-help", "--help":
+		usage()
+	default:
+		cmd, ok := subcommands[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "optimizer: unknown command %q\n", name)
+			usage()
+			os.Exit(2)
+		}
+		os.Exit(cmd.run(os.Args[2:]))
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: optimizer <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, subcommands[name].summary)
+	}
+	fmt.Fprintf(os.Stderr, "  %-10s %s\n", "version", "Print the optimizer version")
+}
+
+// fail prints an error in the optimizer's format and returns exit status 1
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "optimizer: %v\n", err)
+	return 1
+}
+
+// rewriteFile replaces the content of an existing file, keeping its
+// permissions. The content goes to a temporary file that is renamed over
+// path, so an interrupted write leaves the original in place. A symlink is
+// followed so that the file it points to is rewritten.
+func rewriteFile(path, content string) error {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
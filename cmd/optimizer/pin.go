@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/yourusername/dockerfile-parser/internal/lockfile"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+	"github.com/yourusername/dockerfile-parser/internal/rules"
+)
+
+// runPin rewrites FROM lines to tag@digest from the lockfile. With -record
+// it instead stores the digests already written in FROM lines, which is how
+// a lockfile is seeded without network access.
+func runPin(args []string) int {
+	fs := flag.NewFlagSet("pin", flag.ContinueOnError)
+	lockPath := fs.String("lock", "", "lockfile path (default: "+lockfile.DefaultName+" next to the Dockerfile)")
+	write := fs.Bool("w", false, "write the result to the Dockerfile instead of stdout")
+	record := fs.Bool("record", false, "record digests from FROM lines into the lockfile")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: optimizer pin [-lock file] [-w | -record] Dockerfile")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	path := fs.Arg(0)
+	if *lockPath == "" {
+		*lockPath = filepath.Join(filepath.Dir(path), lockfile.DefaultName)
+	}
+
+	df, err := parser.NewParser().ParseFile(path)
+	if err != nil {
+		return fail(err)
+	}
+
+	if *record {
+		return recordDigests(df, *lockPath)
+	}
+
+	lock, err := lockfile.Load(*lockPath)
+	if err != nil {
+		return fail(err)
+	}
+
+	warnings, missing := rules.PinBaseImages(df, lock)
+	content, applied := rules.ApplyFixes(df.Raw, warnings)
+
+	if *write {
+		if applied > 0 {
+			if err := rewriteFile(path, content); err != nil {
+				return fail(err)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "pinned %d base image(s) in %s\n", applied, path)
+	} else {
+		fmt.Print(content)
+	}
+
+	for _, ref := range missing {
+		fmt.Fprintf(os.Stderr, "optimizer: %s has no digest in %s\n", ref, *lockPath)
+	}
+	if len(missing) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// recordDigests adds the tag@digest references of df to the lockfile
+func recordDigests(df *parser.ParsedDockerfile, lockPath string) int {
+	lock, err := lockfile.Load(lockPath)
+	if os.IsNotExist(errors.Cause(err)) {
+		lock, err = lockfile.New(), nil
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	recorded := 0
+	for _, ref := range rules.BaseImageRefs(df) {
+		if !ref.Pinned() || ref.Tag == "" {
+			continue
+		}
+		if err := lock.Set(ref.String(), ref.Digest); err != nil {
+			return fail(err)
+		}
+		recorded++
+	}
+
+	if err := lock.Save(lockPath); err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "recorded %d digest(s) in %s\n", recorded, lockPath)
+	return 0
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+	"github.com/yourusername/dockerfile-parser/internal/rules"
+)
+
+// runSize prints the estimated size contribution of every layer, grouped
+// by stage
+func runSize(args []string) int {
+	fs := flag.NewFlagSet("size", flag.ContinueOnError)
+	context := fs.String("context", "", "build context used to measure COPY and ADD sources (default: the Dockerfile's directory)")
+	noContext := fs.Bool("no-context", false, "estimate COPY and ADD sources instead of measuring them")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: optimizer size [-context dir | -no-context] Dockerfile")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	path := fs.Arg(0)
+	opts := parser.ParseOptions{BuildContext: *context}
+	switch {
+	case *noContext:
+		opts.BuildContext = ""
+	case opts.BuildContext == "":
+		opts.BuildContext = filepath.Dir(path)
+	}
+
+	df, err := parser.NewParserWithOptions(opts).ParseFile(path)
+	if err != nil {
+		return fail(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for i, stage := range rules.ImpactReport(df) {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		name := stage.Stage.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", stage.Stage.Index)
+		}
+		base := fmt.Sprintf("%.0f MB", stage.BaseMB)
+		if !stage.BaseKnown {
+			base = "unknown"
+		}
+		fmt.Fprintf(w, "Stage %s (FROM %s, base %s)\n", name, stage.Stage.BaseImage, base)
+		fmt.Fprintln(w, "LINE\tINSTRUCTION\tSIZE\tBASIS")
+		for _, layer := range stage.Layers {
+			size := "~" + formatMB(layer.SizeMB)
+			if layer.Measured {
+				size = formatMB(layer.SizeMB)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", layer.Instruction.Range.Start.Line,
+				truncate(layer.Instruction.Command+" "+strings.Join(layer.Instruction.Args, " "), 50), size, layer.Basis)
+		}
+		fmt.Fprintf(w, "\tTotal\t~%s\t%s from layers\n", formatMB(stage.TotalMB()), formatMB(stage.LayersMB()))
+	}
+	if err := w.Flush(); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+// formatMB prints sizes below 1 MB in KB
+func formatMB(mb float64) string {
+	if mb < 1 {
+		return fmt.Sprintf("%.0f KB", mb*1024)
+	}
+	return fmt.Sprintf("%.0f MB", mb)
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
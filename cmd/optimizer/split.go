@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+	"github.com/yourusername/dockerfile-parser/internal/rules"
+)
+
+// runSplit prints the Dockerfile with its final stage split into a builder
+// stage and a runtime stage
+func runSplit(args []string) int {
+	fs := flag.NewFlagSet("split", flag.ContinueOnError)
+	write := fs.Bool("w", false, "write the result to the Dockerfile instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: optimizer split [-w] Dockerfile")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	path := fs.Arg(0)
+	df, err := parser.NewParser().ParseFile(path)
+	if err != nil {
+		return fail(err)
+	}
+
+	fix := rules.ProposeMultiStage(df)
+	if fix == nil {
+		fmt.Fprintf(os.Stderr, "optimizer: no supported build step in the final stage of %s\n", path)
+		return 1
+	}
+	content, _ := rules.ApplyFixes(df.Raw, []parser.Warning{{Fix: fix}})
+
+	if *write {
+		if err := rewriteFile(path, content); err != nil {
+			return fail(err)
+		}
+		return 0
+	}
+	fmt.Print(content)
+	return 0
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/yourusername/dockerfile-parser/internal/cachesim"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// runWhatIf reports which instructions lose their build cache when context
+// files or build arguments change
+func runWhatIf(args []string) int {
+	fs := flag.NewFlagSet("whatif", flag.ContinueOnError)
+	context := fs.String("context", "", "build context holding .dockerignore (default: the Dockerfile's directory)")
+	target := fs.String("target", "", "stage to build (default: the last stage)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: optimizer whatif [-context dir] [-target stage] Dockerfile CHANGE...")
+		fmt.Fprintln(fs.Output(), "A CHANGE is a context path such as src/main.go, or ARG NAME for a build argument.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return 2
+	}
+
+	path := fs.Arg(0)
+	changes := make([]cachesim.Change, 0, fs.NArg()-1)
+	for _, arg := range fs.Args()[1:] {
+		change, err := cachesim.ParseChange(arg)
+		if err != nil {
+			return fail(err)
+		}
+		changes = append(changes, change)
+	}
+
+	if *context == "" {
+		*context = filepath.Dir(path)
+	}
+	opts := parser.ParseOptions{BuildContext: *context, TargetStage: *target}
+	df, err := parser.NewParserWithOptions(opts).ParseFile(path)
+	if err != nil {
+		return fail(err)
+	}
+
+	sim, err := cachesim.New(df, *context)
+	if err != nil {
+		return fail(err)
+	}
+	result := sim.Simulate(changes...)
+
+	for _, stage := range result.Stages {
+		name := stage.Stage.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", stage.Stage.Index)
+		}
+		switch {
+		case stage.Skipped:
+			fmt.Printf("stage %s: not built for the target\n", name)
+		case stage.FirstInvalidated == nil:
+			fmt.Printf("stage %s: fully cached\n", name)
+		default:
+			fmt.Printf("stage %s: cache lost at line %d (%s), %d layers rebuilt\n",
+				name, stage.FirstInvalidated.Range.Start.Line, stage.Reason, stage.Rebuilt)
+		}
+	}
+	fmt.Printf("%d layers rebuilt in total\n", result.RebuiltLayers)
+	return 0
+}
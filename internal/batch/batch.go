@@ -0,0 +1,162 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/yourusername/dockerfile-parser/internal/cache"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// Options configures a batch run
+type Options struct {
+	Workers      int                 // Maximum concurrent files; defaults to GOMAXPROCS
+	ParseOptions parser.ParseOptions // Options applied to every file
+	Analyzer     parser.Analyzer     // Optional analysis run after a successful parse
+	Cache        *cache.Cache        // Optional result cache; unchanged files skip work
+	CacheSalt    string              // Extra cache key input, e.g. the analyzer configuration
+}
+
+// Result holds the outcome for a single Dockerfile
+type Result struct {
+	Path       string
+	Dockerfile *parser.ParsedDockerfile
+	Warnings   []parser.Warning
+	Err        error // Parse or analysis failure for this file only
+	Duration   time.Duration
+	Cached     bool // Whether the result came from the cache
+}
+
+// Run parses and analyses paths concurrently using a bounded worker pool.
+// Results are returned in the same order as paths. A failure in one file is
+// recorded on its Result and never aborts the batch; files not yet started
+// when ctx is cancelled report the context error.
+func Run(ctx context.Context, paths []string, opts Options) []Result {
+	results := make([]Result, len(paths))
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// Rules read the build context, so cached warnings are only valid for
+	// the context they were computed against; without a fingerprint the
+	// cache is skipped
+	contextKey := ""
+	if opts.Cache != nil && opts.ParseOptions.BuildContext != "" {
+		fingerprint, err := cache.ContextFingerprint(opts.ParseOptions.BuildContext)
+		if err != nil {
+			opts.Cache = nil
+		}
+		contextKey = fingerprint
+	}
+
+	var g errgroup.Group
+	g.SetLimit(workers)
+
+	for i, path := range paths {
+		i, path := i, path
+		results[i].Path = path
+
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		g.Go(func() error {
+			results[i] = processFile(ctx, path, opts, contextKey)
+			return nil
+		})
+	}
+
+	// Workers never return errors; per-file failures live on each Result
+	_ = g.Wait()
+
+	if opts.Cache != nil {
+		// Eviction failures only affect disk usage, never results
+		_ = opts.Cache.Evict()
+	}
+	return results
+}
+
+// processFile parses and analyses a single file. contextKey fingerprints
+// the build context for the cache key.
+func processFile(ctx context.Context, path string, opts Options, contextKey string) (result Result) {
+	start := time.Now()
+	result.Path = path
+
+	defer func() {
+		if r := recover(); r != nil {
+			result.Err = &parser.DockerfileError{
+				Code:     parser.CodeInternalError,
+				Position: parser.Position{FilePath: path},
+				Message:  "Analysis aborted unexpectedly",
+				Details:  fmt.Sprint(r),
+			}
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+
+	content, err := parser.ReadFileContext(ctx, path, opts.ParseOptions.MaxFileSize)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	key := ""
+	if opts.Cache != nil {
+		key, err = cache.Key([]byte(content), opts.ParseOptions, opts.CacheSalt, contextKey)
+		if err == nil {
+			if entry, ok := opts.Cache.Get(key); ok {
+				result.Dockerfile = entry.Dockerfile
+				result.Warnings = entry.Warnings
+				result.Cached = true
+				return result
+			}
+		}
+	}
+
+	// Parsers keep per-call state, so each file gets its own
+	p := parser.NewParserWithOptions(opts.ParseOptions)
+	df, err := p.ParseNamedContext(ctx, path, content)
+	result.Dockerfile = df
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if opts.Analyzer != nil {
+		warnings, err := opts.Analyzer.Analyze(ctx, df)
+		result.Warnings = warnings
+		if err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	if key != "" {
+		// A failed write only costs a future cache miss
+		_ = opts.Cache.Put(key, df, result.Warnings)
+	}
+	return result
+}
+
+// Failed returns the results that carry an error
+func Failed(results []Result) []Result {
+	failed := make([]Result, 0)
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
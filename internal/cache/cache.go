@@ -0,0 +1,253 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+	"github.com/yourusername/dockerfile-parser/internal/version"
+)
+
+// entryExt is the file extension of cache entries on disk
+const entryExt = ".json"
+
+// Options configures an on-disk cache
+type Options struct {
+	Dir     string        // Directory holding cache entries
+	MaxSize int64         // Evict least recently used entries above this many bytes; zero disables
+	MaxAge  time.Duration // Evict entries unused for longer than this; zero disables
+}
+
+// Cache stores parse and analysis results keyed by content hash
+type Cache struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+	mu      sync.Mutex // Serializes eviction
+}
+
+// Entry is a cached parse and analysis result
+type Entry struct {
+	Key        string                    `json:"key"`
+	Version    string                    `json:"version"`
+	CreatedAt  time.Time                 `json:"createdAt"`
+	Dockerfile *parser.ParsedDockerfile  `json:"dockerfile"`
+	Errors     []*parser.DockerfileError `json:"errors,omitempty"`
+	Warnings   []parser.Warning          `json:"warnings,omitempty"`
+}
+
+// New opens the cache directory, creating it if needed
+func New(opts Options) (*Cache, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("cache directory must be set")
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "create cache directory")
+	}
+	return &Cache{
+		dir:     opts.Dir,
+		maxSize: opts.MaxSize,
+		maxAge:  opts.MaxAge,
+	}, nil
+}
+
+// Key derives the cache key for content parsed with opts. Build arguments
+// are part of opts; extra carries any other inputs that affect results,
+// such as the analyzer configuration. The tool version is always included.
+func Key(content []byte, opts parser.ParseOptions, extra ...string) (string, error) {
+	// Encoding a struct with a map sorts map keys, so the hash is stable
+	encodedOpts, err := json.Marshal(opts)
+	if err != nil {
+		return "", errors.Wrap(err, "encode parse options")
+	}
+
+	h := sha256.New()
+	writeField(h, []byte(version.Version))
+	writeField(h, encodedOpts)
+	writeField(h, content)
+	for _, e := range extra {
+		writeField(h, []byte(e))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeField writes a length-prefixed field so that field boundaries
+// cannot be confused
+func writeField(h hash.Hash, b []byte) {
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(b)))
+	h.Write(n[:])
+	h.Write(b)
+}
+
+// Get returns the entry for key. Corrupt or stale entries are removed and
+// reported as misses.
+func (c *Cache) Get(key string) (*Entry, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key || entry.Version != version.Version {
+		os.Remove(path)
+		return nil, false
+	}
+	if entry.Dockerfile != nil {
+		entry.Dockerfile.LinkStages()
+		entry.Dockerfile.Errors = entry.restoreErrors()
+	}
+
+	// Record the access so that eviction is least-recently-used
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	return &entry, true
+}
+
+// Put stores the result for key. The write is atomic, so concurrent
+// readers never observe a partial entry.
+func (c *Cache) Put(key string, df *parser.ParsedDockerfile, warnings []parser.Warning) error {
+	entry := Entry{
+		Key:        key,
+		Version:    version.Version,
+		CreatedAt:  time.Now(),
+		Dockerfile: df,
+		Warnings:   warnings,
+	}
+	if df != nil {
+		for _, err := range df.Errors {
+			entry.Errors = append(entry.Errors, toDockerfileError(err))
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "encode cache entry")
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "create cache shard")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "create cache entry")
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "write cache entry")
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "write cache entry")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "commit cache entry")
+	}
+	return nil
+}
+
+// Evict removes entries older than MaxAge, then the least recently used
+// entries until the cache fits in MaxSize
+func (c *Cache) Evict() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type cachedFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	files := make([]cachedFile, 0)
+	err := filepath.Walk(c.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Entries may disappear while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, entryExt) {
+			return nil
+		}
+		files = append(files, cachedFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "scan cache directory")
+	}
+
+	// Oldest first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+
+	cutoff := time.Now().Add(-c.maxAge)
+	for _, f := range files {
+		expired := c.maxAge > 0 && f.modTime.Before(cutoff)
+		oversized := c.maxSize > 0 && total > c.maxSize
+		if !expired && !oversized {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "evict cache entry")
+		}
+		total -= f.size
+	}
+	return nil
+}
+
+// path shards entries by the first two hex digits of the key
+func (c *Cache) path(key string) string {
+	shard := "00"
+	if len(key) >= 2 {
+		shard = key[:2]
+	}
+	return filepath.Join(c.dir, shard, key+entryExt)
+}
+
+// restoreErrors converts the stored errors back into the ParsedDockerfile form
+func (e *Entry) restoreErrors() []error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	result := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		result = append(result, err)
+	}
+	return result
+}
+
+// toDockerfileError keeps structured errors and wraps anything else
+func toDockerfileError(err error) *parser.DockerfileError {
+	var dockerfileErr *parser.DockerfileError
+	if errors.As(err, &dockerfileErr) {
+		return dockerfileErr
+	}
+	return &parser.DockerfileError{
+		Code:    parser.CodeInternalError,
+		Message: err.Error(),
+	}
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// ContextFingerprint summarises a build context for use as a Key input.
+// Rules that measure or look up context files give different warnings when
+// the context changes, so the fingerprint covers the .dockerignore file and
+// the path, size and modification time of every file. Contents other than
+// .dockerignore are not read.
+func ContextFingerprint(dir string) (string, error) {
+	h := sha256.New()
+
+	ignore, err := os.ReadFile(filepath.Join(dir, ".dockerignore"))
+	if err != nil && !os.IsNotExist(err) {
+		return "", errors.Wrap(err, "read .dockerignore")
+	}
+	writeField(h, ignore)
+
+	// WalkDir visits entries in lexical order, so the hash is stable
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		var meta [16]byte
+		binary.LittleEndian.PutUint64(meta[:8], uint64(info.Size()))
+		binary.LittleEndian.PutUint64(meta[8:], uint64(info.ModTime().UnixNano()))
+		writeField(h, []byte(filepath.ToSlash(rel)))
+		writeField(h, meta[:])
+		return nil
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "scan build context")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,151 @@
+package cachesim
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IgnoreFile is the name of the file listing paths excluded from the
+// build context
+const IgnoreFile = ".dockerignore"
+
+// ignorePattern is one line of a .dockerignore file
+type ignorePattern struct {
+	re     *regexp.Regexp
+	negate bool // Pattern starts with !, re-including matches
+}
+
+// Ignore decides which context paths a .dockerignore file excludes
+type Ignore struct {
+	patterns []ignorePattern
+}
+
+// LoadIgnore reads the .dockerignore file of a build context. A context
+// without one excludes nothing.
+func LoadIgnore(contextDir string) (*Ignore, error) {
+	data, err := os.ReadFile(filepath.Join(contextDir, IgnoreFile))
+	if os.IsNotExist(err) {
+		return &Ignore{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read "+IgnoreFile)
+	}
+	return ParseIgnore(data)
+}
+
+// ParseIgnore parses .dockerignore content. Patterns follow Docker's
+// rules: paths are relative to the context root, ** matches any number of
+// directories, ! re-includes and the last matching pattern wins.
+func ParseIgnore(data []byte) (*Ignore, error) {
+	ig := &Ignore{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		negate := strings.HasPrefix(line, "!")
+		if negate {
+			line = strings.TrimSpace(line[1:])
+		}
+		line = cleanContextPath(line)
+		if line == "" {
+			continue
+		}
+
+		re, err := globRegexp(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s line %d", IgnoreFile, n)
+		}
+		ig.patterns = append(ig.patterns, ignorePattern{re: re, negate: negate})
+	}
+	return ig, scanner.Err()
+}
+
+// Ignored reports whether a context path is excluded. A pattern that
+// matches a directory excludes everything below it.
+func (ig *Ignore) Ignored(p string) bool {
+	p = cleanContextPath(p)
+	ignored := false
+	for _, pattern := range ig.patterns {
+		if matchesPathOrParent(pattern.re, p) {
+			ignored = !pattern.negate
+		}
+	}
+	return ignored
+}
+
+// matchesPathOrParent matches p or any directory containing it
+func matchesPathOrParent(re *regexp.Regexp, p string) bool {
+	for {
+		if re.MatchString(p) {
+			return true
+		}
+		dir := path.Dir(p)
+		if dir == "." || dir == "/" || dir == p {
+			return false
+		}
+		p = dir
+	}
+}
+
+// globRegexp converts a glob with Docker's ** extension into an anchored
+// regular expression over slash-separated paths
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch ch {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// **/ matches zero or more directories
+					i++
+					sb.WriteString("(.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+				continue
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				return nil, errors.Errorf("unterminated character class in %q", glob)
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// cleanContextPath normalizes a path relative to the context root
+func cleanContextPath(p string) string {
+	p = path.Clean("/" + filepath.ToSlash(p))
+	return strings.TrimPrefix(p, "/")
+}
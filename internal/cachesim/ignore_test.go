@@ -0,0 +1,98 @@
+package cachesim
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIgnore(t *testing.T) {
+	tests := []struct {
+		name    string
+		ignore  string
+		ignored []string
+		kept    []string
+	}{
+		{
+			name:    "plain names match at the root",
+			ignore:  "node_modules\n.git\n",
+			ignored: []string{"node_modules", "node_modules/left-pad/index.js", ".git/HEAD"},
+			kept:    []string{"web/node_modules", "src/main.go"},
+		},
+		{
+			name:    "single star stays within a directory",
+			ignore:  "*.log\ndocs/*.md",
+			ignored: []string{"debug.log", "docs/intro.md"},
+			kept:    []string{"logs/debug.log", "docs/guide/intro.md", "README.md"},
+		},
+		{
+			name:    "double star crosses directories",
+			ignore:  "**/*.pyc\n**/__pycache__",
+			ignored: []string{"app.pyc", "pkg/mod/app.pyc", "pkg/__pycache__/x.cpython-312.pyc"},
+			kept:    []string{"app.py"},
+		},
+		{
+			name:    "negation re-includes and the last match wins",
+			ignore:  "*.md\n!README.md\nREADME.md",
+			ignored: []string{"CHANGELOG.md", "README.md"},
+		},
+		{
+			name:    "negation of a file below an ignored directory",
+			ignore:  "build\n!build/keep.txt",
+			ignored: []string{"build/out.o"},
+			kept:    []string{"build/keep.txt"},
+		},
+		{
+			name:    "comments, blank lines and leading slashes",
+			ignore:  "# generated\n\n/dist\n./tmp/",
+			ignored: []string{"dist/app.js", "tmp/cache"},
+			kept:    []string{"src/dist/app.js"},
+		},
+		{
+			name:    "character classes and single characters",
+			ignore:  "file[0-9].txt\nlog?",
+			ignored: []string{"file1.txt", "log1"},
+			kept:    []string{"filex.txt", "log12"},
+		},
+		{
+			name:   "empty file ignores nothing",
+			ignore: "",
+			kept:   []string{"anything", "a/b/c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig, err := ParseIgnore([]byte(tt.ignore))
+			require.NoError(t, err)
+			for _, p := range tt.ignored {
+				assert.True(t, ig.Ignored(p), "%s should be ignored", p)
+			}
+			for _, p := range tt.kept {
+				assert.False(t, ig.Ignored(p), "%s should be kept", p)
+			}
+		})
+	}
+}
+
+func TestParseIgnoreErrors(t *testing.T) {
+	_, err := ParseIgnore([]byte("ok\nfile[0-9.txt\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestLoadIgnore(t *testing.T) {
+	dir := t.TempDir()
+
+	ig, err := LoadIgnore(dir)
+	require.NoError(t, err)
+	assert.False(t, ig.Ignored("node_modules"), "a context without .dockerignore excludes nothing")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, IgnoreFile), []byte("node_modules\n"), 0o644))
+	ig, err = LoadIgnore(dir)
+	require.NoError(t, err)
+	assert.True(t, ig.Ignored("node_modules/x"))
+}
@@ -0,0 +1,365 @@
+package cachesim
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/yourusername/dockerfile-parser/internal/lexer"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// Change is an edit that may invalidate the build cache: either a file in
+// the build context or the value of a build argument
+type Change struct {
+	Path string // Context path relative to the context root
+	Arg  string // Build argument name
+}
+
+// ParseChange reads a change written as a context path, or as ARG NAME or
+// arg:NAME for a build argument
+func ParseChange(s string) (Change, error) {
+	s = strings.TrimSpace(s)
+	for _, prefix := range []string{"ARG ", "arg ", "ARG:", "arg:"} {
+		if strings.HasPrefix(s, prefix) {
+			name := strings.TrimSpace(s[len(prefix):])
+			if name == "" {
+				return Change{}, errors.Errorf("change %q names no build argument", s)
+			}
+			return Change{Arg: name}, nil
+		}
+	}
+	p := cleanContextPath(s)
+	if p == "" {
+		return Change{}, errors.Errorf("change %q names no context path", s)
+	}
+	return Change{Path: p}, nil
+}
+
+func (c Change) String() string {
+	if c.Arg != "" {
+		return "ARG " + c.Arg
+	}
+	return c.Path
+}
+
+// StageResult is the effect of the changes on one stage
+type StageResult struct {
+	Stage            *parser.Stage
+	FirstInvalidated *parser.Instruction // Nil when the stage stays cached
+	Reason           string              // Why FirstInvalidated misses the cache
+	Rebuilt          int                 // Layer-creating instructions that run again
+	Skipped          bool                // Stage is not needed for the target
+}
+
+// Result is the effect of the changes on the whole build
+type Result struct {
+	Stages        []StageResult
+	RebuiltLayers int // Rebuilt layers of the stages the target needs
+}
+
+// Simulator models the build cache of a Dockerfile and its context
+type Simulator struct {
+	df     *parser.ParsedDockerfile
+	ignore *Ignore
+}
+
+// New creates a simulator. The .dockerignore file is read from contextDir,
+// or from ParseOptions.BuildContext when contextDir is empty.
+func New(df *parser.ParsedDockerfile, contextDir string) (*Simulator, error) {
+	if contextDir == "" {
+		contextDir = df.ParseOptions.BuildContext
+	}
+	ignore := &Ignore{}
+	if contextDir != "" {
+		var err error
+		if ignore, err = LoadIgnore(contextDir); err != nil {
+			return nil, err
+		}
+	}
+	return &Simulator{df: df, ignore: ignore}, nil
+}
+
+// NewWithIgnore creates a simulator with already parsed ignore rules
+func NewWithIgnore(df *parser.ParsedDockerfile, ignore *Ignore) *Simulator {
+	if ignore == nil {
+		ignore = &Ignore{}
+	}
+	return &Simulator{df: df, ignore: ignore}
+}
+
+// Simulate reports which instructions lose their cache when the changes
+// are made. Once an instruction misses the cache every later instruction
+// of its stage does too, and so do stages built on or copying from it.
+func (s *Simulator) Simulate(changes ...Change) Result {
+	var result Result
+	invalidated := make(map[*parser.Stage]bool)
+	needed := s.neededStages()
+
+	for _, stage := range s.df.Stages {
+		sr := StageResult{Stage: stage, Skipped: !needed[stage]}
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			if sr.FirstInvalidated == nil {
+				reason, ok := s.invalidates(stage, i, changes, invalidated)
+				if !ok {
+					continue
+				}
+				sr.FirstInvalidated, sr.Reason = inst, reason
+			}
+			if inst.Command != "FROM" && lexer.InstructionImpact(inst.Command).LayerCreating {
+				sr.Rebuilt++
+			}
+		}
+
+		invalidated[stage] = sr.FirstInvalidated != nil
+		if !sr.Skipped {
+			result.RebuiltLayers += sr.Rebuilt
+		}
+		result.Stages = append(result.Stages, sr)
+	}
+	return result
+}
+
+// invalidates reports whether instruction idx of the stage misses the
+// cache because of the changes or an invalidated stage it depends on
+func (s *Simulator) invalidates(stage *parser.Stage, idx int, changes []Change, invalidated map[*parser.Stage]bool) (string, bool) {
+	inst := &stage.Instructions[idx]
+
+	if inst.Command == "FROM" {
+		if stage.BaseStage != nil && invalidated[stage.BaseStage] {
+			return fmt.Sprintf("base stage %s is rebuilt", stageLabel(stage.BaseStage)), true
+		}
+		for _, c := range changes {
+			if c.Arg != "" && s.globalArg(c.Arg) && referencesVariable(inst, c.Arg) {
+				return fmt.Sprintf("FROM uses ARG %s", c.Arg), true
+			}
+		}
+		return "", false
+	}
+
+	for _, c := range changes {
+		if c.Arg == "" {
+			continue
+		}
+		if !argInScope(stage, idx, c.Arg) {
+			continue
+		}
+		// Every RUN sees the build arguments in scope as environment
+		// variables; other instructions only when they expand them
+		if inst.Command == "RUN" {
+			return fmt.Sprintf("RUN runs with ARG %s in its environment", c.Arg), true
+		}
+		if referencesVariable(inst, c.Arg) {
+			return fmt.Sprintf("%s expands ARG %s", inst.Command, c.Arg), true
+		}
+	}
+
+	for _, src := range s.sources(inst) {
+		if src.stage != nil {
+			if invalidated[src.stage] {
+				return fmt.Sprintf("%s reads from rebuilt stage %s", src.via, stageLabel(src.stage)), true
+			}
+			continue
+		}
+		for _, c := range changes {
+			if c.Path != "" && !s.ignore.Ignored(c.Path) && matchesSource(src.pattern, c.Path) {
+				return fmt.Sprintf("%s %s includes %s", src.via, displaySource(src.pattern), c.Path), true
+			}
+		}
+	}
+	return "", false
+}
+
+// source is content an instruction reads: context paths matching pattern,
+// or the output of another stage
+type source struct {
+	pattern string
+	stage   *parser.Stage
+	via     string // Instruction or mount that reads it
+}
+
+// sources lists what a COPY, ADD or RUN with bind mounts reads
+func (s *Simulator) sources(inst *parser.Instruction) []source {
+	result := make([]source, 0)
+	switch inst.Command {
+	case "COPY", "ADD":
+		if inst.Heredoc != nil || len(inst.Args) < 2 {
+			return result
+		}
+		if from, ok := inst.Flags["from"]; ok {
+			if stage := s.stageByRef(from); stage != nil {
+				result = append(result, source{stage: stage, via: inst.Command + " --from"})
+			}
+			return result
+		}
+		for _, arg := range inst.Args[:len(inst.Args)-1] {
+			src := strings.Trim(arg, `[]",`)
+			if isRemoteSource(src) {
+				continue
+			}
+			result = append(result, source{pattern: cleanContextPath(src), via: inst.Command})
+		}
+
+	case "RUN":
+		for _, mount := range strings.Fields(inst.Flags["mount"]) {
+			opts := mountOptions(mount)
+			if typ := opts["type"]; typ != "" && typ != "bind" {
+				continue
+			}
+			src := opts["source"]
+			if src == "" {
+				src = opts["src"]
+			}
+			if from := opts["from"]; from != "" {
+				if stage := s.stageByRef(from); stage != nil {
+					result = append(result, source{stage: stage, via: "RUN --mount"})
+				}
+				continue
+			}
+			result = append(result, source{pattern: cleanContextPath(src), via: "RUN --mount"})
+		}
+	}
+	return result
+}
+
+// neededStages returns the stages reachable from the target stage, which
+// is ParseOptions.TargetStage or the last stage
+func (s *Simulator) neededStages() map[*parser.Stage]bool {
+	needed := make(map[*parser.Stage]bool)
+	if len(s.df.Stages) == 0 {
+		return needed
+	}
+	target := s.df.Stages[len(s.df.Stages)-1]
+	if name := s.df.ParseOptions.TargetStage; name != "" {
+		if stage := s.stageByRef(name); stage != nil {
+			target = stage
+		}
+	}
+
+	var visit func(stage *parser.Stage)
+	visit = func(stage *parser.Stage) {
+		if stage == nil || needed[stage] {
+			return
+		}
+		needed[stage] = true
+		visit(stage.BaseStage)
+		for i := range stage.Instructions {
+			for _, src := range s.sources(&stage.Instructions[i]) {
+				visit(src.stage)
+			}
+		}
+	}
+	visit(target)
+	return needed
+}
+
+// stageByRef resolves a --from value naming an earlier stage by name or
+// index; image references resolve to nil
+func (s *Simulator) stageByRef(ref string) *parser.Stage {
+	for _, stage := range s.df.Stages {
+		if stage.Name != "" && strings.EqualFold(stage.Name, ref) {
+			return stage
+		}
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		for _, stage := range s.df.Stages {
+			if stage.Index == n {
+				return stage
+			}
+		}
+	}
+	return nil
+}
+
+// globalArg reports whether an ARG before the first FROM declares name
+func (s *Simulator) globalArg(name string) bool {
+	_, ok := s.df.GlobalArgs[name]
+	return ok
+}
+
+// argInScope reports whether the stage declares ARG name before idx
+func argInScope(stage *parser.Stage, idx int, name string) bool {
+	for _, inst := range stage.Instructions[:idx] {
+		if inst.Command != "ARG" {
+			continue
+		}
+		for _, arg := range inst.Args {
+			declared, _, _ := strings.Cut(arg, "=")
+			if declared == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// referencesVariable reports whether the instruction's arguments or flags
+// expand $name or ${name}
+func referencesVariable(inst *parser.Instruction, name string) bool {
+	re := regexp.MustCompile(`\$(\{` + regexp.QuoteMeta(name) + `[}:]|` + regexp.QuoteMeta(name) + `\b)`)
+	for _, arg := range inst.Args {
+		if re.MatchString(arg) {
+			return true
+		}
+	}
+	for _, value := range inst.Flags {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSource reports whether a changed context path is copied by a
+// source pattern, either directly or because the pattern names one of its
+// directories. Sources with variables are assumed to match.
+func matchesSource(pattern, changed string) bool {
+	if pattern == "" || strings.Contains(pattern, "$") {
+		return true
+	}
+	for p := changed; p != "." && p != ""; p = path.Dir(p) {
+		if ok, err := path.Match(pattern, p); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isRemoteSource reports whether an ADD source is fetched rather than
+// read from the context
+func isRemoteSource(src string) bool {
+	lower := strings.ToLower(src)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "git@") || strings.HasPrefix(lower, "git://")
+}
+
+// mountOptions splits a --mount value into its key=value options
+func mountOptions(mount string) map[string]string {
+	opts := make(map[string]string)
+	for _, field := range strings.Split(mount, ",") {
+		key, value, _ := strings.Cut(field, "=")
+		opts[strings.ToLower(key)] = value
+	}
+	return opts
+}
+
+// displaySource shows the context root as "."
+func displaySource(pattern string) string {
+	if pattern == "" {
+		return "."
+	}
+	return pattern
+}
+
+// stageLabel names a stage in messages
+func stageLabel(stage *parser.Stage) string {
+	if stage.Name != "" {
+		return stage.Name
+	}
+	return fmt.Sprintf("#%d", stage.Index)
+}
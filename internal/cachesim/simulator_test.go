@@ -0,0 +1,184 @@
+package cachesim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// parseDockerfile parses src, failing the test on error
+func parseDockerfile(t *testing.T, src string) *parser.ParsedDockerfile {
+	t.Helper()
+	df, err := parser.NewParser().Parse(src)
+	require.NoError(t, err)
+	return df
+}
+
+func TestParseChange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Change
+		wantErr bool
+	}{
+		{in: "src/main.go", want: Change{Path: "src/main.go"}},
+		{in: "./package.json", want: Change{Path: "package.json"}},
+		{in: "ARG VERSION", want: Change{Arg: "VERSION"}},
+		{in: "arg:VERSION", want: Change{Arg: "VERSION"}},
+		{in: "arg:", wantErr: true},
+		{in: ".", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseChange(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	// stageOutcome is the line of the first invalidated instruction of a
+	// stage, 0 when it stays cached, and how many layers are rebuilt
+	type stageOutcome struct {
+		first   int
+		rebuilt int
+		skipped bool
+	}
+
+	tests := []struct {
+		name       string
+		dockerfile string
+		ignore     string
+		target     string
+		changes    []Change
+		want       []stageOutcome
+		wantTotal  int
+	}{
+		{
+			name: "source change after the dependency install",
+			dockerfile: `FROM node:20
+WORKDIR /app
+COPY package.json package-lock.json ./
+RUN npm ci
+COPY . .
+RUN npm run build
+`,
+			changes:   []Change{{Path: "src/index.js"}},
+			want:      []stageOutcome{{first: 5, rebuilt: 2}},
+			wantTotal: 2,
+		},
+		{
+			name: "manifest change reruns the install",
+			dockerfile: `FROM node:20
+WORKDIR /app
+COPY package.json package-lock.json ./
+RUN npm ci
+COPY . .
+RUN npm run build
+`,
+			changes:   []Change{{Path: "package.json"}},
+			want:      []stageOutcome{{first: 3, rebuilt: 4}},
+			wantTotal: 4,
+		},
+		{
+			name: "ignored path keeps the cache",
+			dockerfile: `FROM node:20
+COPY . .
+RUN npm ci
+`,
+			ignore:    "node_modules\n",
+			changes:   []Change{{Path: "node_modules/x/index.js"}},
+			want:      []stageOutcome{{}},
+			wantTotal: 0,
+		},
+		{
+			name: "build argument reaches every later RUN",
+			dockerfile: `FROM golang:1.22
+COPY . .
+ARG VERSION
+RUN go build -ldflags "-X main.version=$VERSION" -o /app
+RUN echo done
+`,
+			changes:   []Change{{Arg: "VERSION"}},
+			want:      []stageOutcome{{first: 4, rebuilt: 2}},
+			wantTotal: 2,
+		},
+		{
+			name: "builder change rebuilds the stage copying from it",
+			dockerfile: `FROM golang:1.22 AS build
+COPY . .
+RUN go build -o /app
+FROM alpine
+RUN apk add --no-cache ca-certificates
+COPY --from=build /app /app
+`,
+			changes:   []Change{{Path: "main.go"}},
+			want:      []stageOutcome{{first: 2, rebuilt: 2}, {first: 6, rebuilt: 1}},
+			wantTotal: 3,
+		},
+		{
+			name: "stages the target does not need are skipped",
+			dockerfile: `FROM alpine AS test
+COPY . .
+RUN make test
+FROM alpine
+COPY app /app
+`,
+			changes:   []Change{{Path: "main.c"}},
+			want:      []stageOutcome{{first: 2, rebuilt: 2, skipped: true}, {}},
+			wantTotal: 0,
+		},
+		{
+			name: "target stage decides what is needed",
+			dockerfile: `FROM alpine AS test
+COPY . .
+RUN make test
+FROM alpine
+COPY app /app
+`,
+			target:    "test",
+			changes:   []Change{{Path: "main.c"}},
+			want:      []stageOutcome{{first: 2, rebuilt: 2}, {skipped: true}},
+			wantTotal: 2,
+		},
+		{
+			name: "global argument used by FROM",
+			dockerfile: `ARG BASE=alpine
+FROM ${BASE}
+RUN echo hi
+`,
+			changes:   []Change{{Arg: "BASE"}},
+			want:      []stageOutcome{{first: 2, rebuilt: 1}},
+			wantTotal: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			df := parseDockerfile(t, tt.dockerfile)
+			df.ParseOptions.TargetStage = tt.target
+			ignore, err := ParseIgnore([]byte(tt.ignore))
+			require.NoError(t, err)
+
+			result := NewWithIgnore(df, ignore).Simulate(tt.changes...)
+			got := make([]stageOutcome, 0, len(result.Stages))
+			for _, stage := range result.Stages {
+				outcome := stageOutcome{rebuilt: stage.Rebuilt, skipped: stage.Skipped}
+				if stage.FirstInvalidated != nil {
+					outcome.first = stage.FirstInvalidated.Range.Start.Line
+				}
+				got = append(got, outcome)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantTotal, result.RebuiltLayers)
+		})
+	}
+}
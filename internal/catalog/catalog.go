@@ -0,0 +1,269 @@
+package catalog
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/yourusername/dockerfile-parser/internal/image"
+)
+
+//go:embed images.yaml
+var bundled []byte
+
+// Codename suffixes that select a distribution release without changing
+// the variant
+var codenames = []string{"-bookworm", "-bullseye", "-buster", "-trixie", "-jammy", "-noble", "-focal"}
+
+// alpineRelease matches a pinned Alpine release such as -alpine3.19
+var alpineRelease = regexp.MustCompile(`-alpine[0-9.]+$`)
+
+// Image is one variant of a base image, such as node slim
+type Image struct {
+	ID           string   `yaml:"id"`
+	Repository   string   `yaml:"repository"`   // Repository without registry host
+	Suffix       string   `yaml:"suffix"`       // Tag suffix that selects the variant
+	Template     string   `yaml:"template"`     // Reference with {version} and {major} placeholders
+	SizeMB       int      `yaml:"size_mb"`      // Approximate uncompressed size
+	Distro       string   `yaml:"distro"`       // debian, ubuntu, alpine, distroless or none
+	Libc         string   `yaml:"libc"`         // glibc, musl or none
+	Shell        bool     `yaml:"shell"`        // Whether /bin/sh exists
+	Tools        []string `yaml:"tools"`        // Network clients such as curl, wget and nc, nil when unknown
+	User         string   `yaml:"user"`         // Default user
+	Notes        []string `yaml:"notes"`        // Caveats when switching to this image
+	Alternatives []string `yaml:"alternatives"` // IDs of smaller images that can replace this one
+}
+
+// Catalog is a set of known base images
+type Catalog struct {
+	Images []Image `yaml:"images"`
+	byID   map[string]*Image
+}
+
+// Match is a reference resolved to a catalog image
+type Match struct {
+	Image   *Image
+	Version string // Tag without the variant suffix, empty for latest
+}
+
+// Suggestion is a smaller image that can replace a matched one
+type Suggestion struct {
+	Image     *Image
+	Reference string   // Rendered reference to use in FROM
+	SavingMB  int      // Approximate size reduction
+	Caveats   []string // Compatibility differences to check before switching
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultCatalog *Catalog
+)
+
+// Default returns the catalog bundled with the optimizer
+func Default() *Catalog {
+	defaultOnce.Do(func() {
+		c, err := Parse(bundled)
+		if err != nil {
+			panic(fmt.Sprintf("bundled image catalog: %v", err))
+		}
+		defaultCatalog = c
+	})
+	return defaultCatalog
+}
+
+// Load reads a catalog from a YAML file in the bundled format
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read image catalog")
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a YAML catalog
+func Parse(data []byte) (*Catalog, error) {
+	c := &Catalog{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, errors.Wrap(err, "parse image catalog")
+	}
+
+	c.byID = make(map[string]*Image, len(c.Images))
+	for i := range c.Images {
+		img := &c.Images[i]
+		if img.ID == "" || img.Repository == "" || img.Template == "" {
+			return nil, fmt.Errorf("catalog image %d needs id, repository and template", i)
+		}
+		if _, dup := c.byID[img.ID]; dup {
+			return nil, fmt.Errorf("duplicate catalog image %s", img.ID)
+		}
+		c.byID[img.ID] = img
+	}
+	for _, img := range c.Images {
+		for _, alt := range img.Alternatives {
+			if _, ok := c.byID[alt]; !ok {
+				return nil, fmt.Errorf("catalog image %s lists unknown alternative %s", img.ID, alt)
+			}
+		}
+	}
+	return c, nil
+}
+
+// Lookup finds the catalog variant for ref, preferring the longest
+// matching tag suffix
+func (c *Catalog) Lookup(ref image.Reference) (Match, bool) {
+	repo := ref.Repository()
+	if strings.HasPrefix(ref.Name, "gcr.io/") {
+		repo = strings.TrimPrefix(ref.Name, "gcr.io/")
+	}
+
+	tag := ref.Tag
+	if tag == image.DefaultTag {
+		tag = ""
+	}
+	for _, codename := range codenames {
+		tag = strings.TrimSuffix(tag, codename)
+	}
+	tag = alpineRelease.ReplaceAllString(tag, "-alpine")
+
+	var best *Image
+	for i := range c.Images {
+		img := &c.Images[i]
+		if !matchesRepository(img, repo) {
+			continue
+		}
+		if !strings.HasSuffix(tag, img.Suffix) && strings.TrimPrefix(img.Suffix, "-") != tag {
+			continue
+		}
+		if best == nil || len(img.Suffix) > len(best.Suffix) {
+			best = img
+		}
+	}
+	if best == nil {
+		return Match{}, false
+	}
+
+	version := strings.TrimSuffix(tag, best.Suffix)
+	if version == strings.TrimPrefix(best.Suffix, "-") {
+		version = ""
+	}
+	return Match{Image: best, Version: version}, true
+}
+
+// matchesRepository compares repositories, accepting distroless versioned
+// names such as distroless/nodejs20-debian12 for distroless/nodejs
+func matchesRepository(img *Image, repo string) bool {
+	if img.Repository == repo || "library/"+img.Repository == repo {
+		return true
+	}
+	if !strings.HasPrefix(img.Repository, "distroless/") || !strings.HasPrefix(repo, img.Repository) {
+		return false
+	}
+	rest := strings.TrimPrefix(repo, img.Repository)
+	return rest != "" && rest[0] >= '0' && rest[0] <= '9'
+}
+
+// Suggest returns the smaller alternatives to a matched image, largest
+// saving first. Alternatives whose reference needs a version the match
+// does not have are left out.
+func (c *Catalog) Suggest(m Match) []Suggestion {
+	suggestions := make([]Suggestion, 0)
+	for _, id := range m.Image.Alternatives {
+		alt := c.byID[id]
+		if alt.SizeMB >= m.Image.SizeMB {
+			continue
+		}
+		ref, ok := alt.Render(m.Version)
+		if !ok {
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{
+			Image:     alt,
+			Reference: ref,
+			SavingMB:  m.Image.SizeMB - alt.SizeMB,
+			Caveats:   Caveats(m.Image, alt),
+		})
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].SavingMB > suggestions[j].SavingMB
+	})
+	return suggestions
+}
+
+// Render fills the template with version. A template that needs a version
+// cannot be rendered for latest, except for same-repository variant tags,
+// which fall back to the bare suffix as in node:slim.
+func (img *Image) Render(version string) (string, bool) {
+	if version == "" {
+		switch {
+		case strings.Contains(img.Template, "{major}"):
+			return "", false
+		case strings.HasSuffix(img.Template, ":{version}"):
+			return strings.TrimSuffix(img.Template, ":{version}"), true
+		case strings.Contains(img.Template, ":{version}-"):
+			return strings.Replace(img.Template, "{version}-", "", 1), true
+		}
+	}
+
+	major := version
+	if dot := strings.IndexByte(major, '.'); dot >= 0 {
+		major = major[:dot]
+	}
+	if strings.Contains(img.Template, "{major}") && (major == "" || strings.Trim(major, "0123456789") != "") {
+		return "", false
+	}
+
+	ref := strings.ReplaceAll(img.Template, "{version}", version)
+	return strings.ReplaceAll(ref, "{major}", major), true
+}
+
+// Caveats lists the differences between two images that can break a
+// Dockerfile when switching from one to the other
+func Caveats(from, to *Image) []string {
+	caveats := make([]string, 0)
+	if from.Libc != to.Libc {
+		switch to.Libc {
+		case "musl":
+			caveats = append(caveats, "uses musl instead of glibc; prebuilt native binaries and modules may fail")
+		case "none":
+			caveats = append(caveats, "has no C library; only static binaries run")
+		}
+	}
+	if from.Shell && !to.Shell {
+		caveats = append(caveats, "has no shell; RUN and shell-form CMD/ENTRYPOINT do not work")
+	}
+	if from.Distro != to.Distro && to.Distro == "alpine" {
+		caveats = append(caveats, "uses apk instead of the "+from.Distro+" package manager")
+	}
+	if from.User != to.User && to.User != "" {
+		caveats = append(caveats, "runs as "+to.User+" by default")
+	}
+	return append(caveats, to.Notes...)
+}
+
+// DefaultUser returns the user an image runs as when no USER is set, and
+// whether the catalog knows it. Distroless nonroot tags run as nonroot.
+func (c *Catalog) DefaultUser(ref image.Reference) (string, bool) {
+	if strings.HasPrefix(ref.Name, "gcr.io/distroless/") && strings.Contains(ref.Tag, "nonroot") {
+		return "nonroot", true
+	}
+	match, ok := c.Lookup(ref)
+	if !ok || match.Image.User == "" {
+		return "", false
+	}
+	return match.Image.User, true
+}
+
+// Distro returns the distribution family of an image, or "" when unknown
+func (c *Catalog) Distro(ref image.Reference) string {
+	if match, ok := c.Lookup(ref); ok {
+		return match.Image.Distro
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package image
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultTag is the tag Docker uses when a reference names none
+const DefaultTag = "latest"
+
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// Reference is an image reference split into its parts, as written in a
+// FROM line: [registry/]repository[:tag][@digest]
+type Reference struct {
+	Name   string // Registry and repository, e.g. python or ghcr.io/org/app
+	Tag    string // Empty when omitted
+	Digest string // Empty when omitted
+}
+
+// ParseReference splits an image reference into name, tag and digest
+func ParseReference(ref string) (Reference, error) {
+	var r Reference
+	if ref == "" {
+		return r, fmt.Errorf("empty image reference")
+	}
+	if strings.ContainsAny(ref, " \t\n") {
+		return r, fmt.Errorf("invalid image reference %q", ref)
+	}
+
+	name := ref
+	if at := strings.IndexByte(name, '@'); at >= 0 {
+		r.Digest = name[at+1:]
+		name = name[:at]
+		if !ValidDigest(r.Digest) {
+			return r, fmt.Errorf("invalid digest %q in image reference %q", r.Digest, ref)
+		}
+	}
+
+	// A colon after the last slash starts the tag; earlier ones are ports
+	if colon := strings.LastIndexByte(name, ':'); colon > strings.LastIndexByte(name, '/') {
+		r.Tag = name[colon+1:]
+		name = name[:colon]
+		if r.Tag == "" {
+			return r, fmt.Errorf("empty tag in image reference %q", ref)
+		}
+	}
+
+	if name == "" {
+		return r, fmt.Errorf("missing repository in image reference %q", ref)
+	}
+	r.Name = name
+	return r, nil
+}
+
+// ValidDigest reports whether digest has the form sha256:<64 hex digits>
+func ValidDigest(digest string) bool {
+	return digestPattern.MatchString(digest)
+}
+
+// String renders the reference in name[:tag][@digest] form
+func (r Reference) String() string {
+	s := r.Name
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// TagOrDefault returns the tag, or latest when none is given
+func (r Reference) TagOrDefault() string {
+	if r.Tag == "" {
+		return DefaultTag
+	}
+	return r.Tag
+}
+
+// Tagged returns name:tag with the default tag filled in and no digest;
+// it identifies the reference in lockfiles and catalogs
+func (r Reference) Tagged() string {
+	return r.Name + ":" + r.TagOrDefault()
+}
+
+// Repository returns the name without a registry host, e.g. library paths
+// stay as written while ghcr.io/org/app becomes org/app
+func (r Reference) Repository() string {
+	slash := strings.IndexByte(r.Name, '/')
+	if slash < 0 {
+		return r.Name
+	}
+	host := r.Name[:slash]
+	if strings.ContainsAny(host, ".:") || host == "localhost" {
+		return r.Name[slash+1:]
+	}
+	return r.Name
+}
+
+// Pinned reports whether the reference carries a digest
+func (r Reference) Pinned() bool {
+	return r.Digest != ""
+}
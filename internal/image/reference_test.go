@@ -0,0 +1,67 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		ref     string
+		want    Reference
+		wantErr bool
+	}{
+		{ref: "python", want: Reference{Name: "python"}},
+		{ref: "python:3.12-slim", want: Reference{Name: "python", Tag: "3.12-slim"}},
+		{ref: "ghcr.io/org/app:v1", want: Reference{Name: "ghcr.io/org/app", Tag: "v1"}},
+		{ref: "localhost:5000/app", want: Reference{Name: "localhost:5000/app"}},
+		{ref: "localhost:5000/app:dev", want: Reference{Name: "localhost:5000/app", Tag: "dev"}},
+		{ref: "node@" + digest, want: Reference{Name: "node", Digest: digest}},
+		{ref: "node:20@" + digest, want: Reference{Name: "node", Tag: "20", Digest: digest}},
+		{ref: "", wantErr: true},
+		{ref: "python 3", wantErr: true},
+		{ref: "python:", wantErr: true},
+		{ref: ":3.12", wantErr: true},
+		{ref: "node@sha256:abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseReference(tt.ref)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.ref, got.String())
+		})
+	}
+}
+
+func TestReferenceParts(t *testing.T) {
+	tests := []struct {
+		ref        string
+		tagged     string
+		repository string
+	}{
+		{ref: "python", tagged: "python:latest", repository: "python"},
+		{ref: "library/python:3", tagged: "library/python:3", repository: "library/python"},
+		{ref: "ghcr.io/org/app:v1", tagged: "ghcr.io/org/app:v1", repository: "org/app"},
+		{ref: "localhost/app", tagged: "localhost/app:latest", repository: "app"},
+		{ref: "registry:5000/team/app", tagged: "registry:5000/team/app:latest", repository: "team/app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			r, err := ParseReference(tt.ref)
+			require.NoError(t, err)
+			assert.Equal(t, tt.tagged, r.Tagged())
+			assert.Equal(t, tt.repository, r.Repository())
+		})
+	}
+}
@@ -18,16 +18,23 @@ type Lexer struct {
 	inHeredoc    bool
 	heredocID    string
 	lineTokens   []*Token // Tokens in current logical line
+	limits       Limits
 }
 
 // NewLexer creates a new lexer for tokenizing Dockerfile content
 func NewLexer(r io.Reader) *Lexer {
-	scanner := NewScanner(r)
+	return NewLexerWithLimits(r, Limits{})
+}
+
+// NewLexerWithLimits creates a lexer whose scanner enforces the given limits
+func NewLexerWithLimits(r io.Reader, limits Limits) *Lexer {
+	scanner := NewScannerWithLimits(r, limits)
 	l := &Lexer{
 		scanner:    scanner,
 		tokens:     make([]*Token, 0),
 		errors:     make([]error, 0),
 		lineTokens: make([]*Token, 0),
+		limits:     limits,
 	}
 	// Initialize by reading first two tokens
 	l.nextToken()
@@ -50,7 +57,7 @@ func (l *Lexer) PeekToken() *Token {
 // nextToken advances the lexer to the next token
 func (l *Lexer) nextToken() {
 	l.currentToken = l.peekToken
-	token, err := l.scanner.Scan()
+	token, err := l.scanner.SafeScan()
 	
 	if err != nil {
 		if err != io.EOF {
@@ -62,7 +69,7 @@ func (l *Lexer) nextToken() {
 	
 	// Skip whitespace tokens unless in heredoc
 	if !l.inHeredoc && token.Type == TOKEN_WHITESPACE {
-		token, err = l.scanner.Scan()
+		token, err = l.scanner.SafeScan()
 		if err != nil {
 			if err != io.EOF {
 				l.errors = append(l.errors, err)
@@ -253,6 +260,11 @@ func (l *Lexer) ProcessAllInstructions() ([]*InstructionTokens, []error) {
 		if inst != nil {
 			instructions = append(instructions, inst)
 		}
+		// Stop lexing as soon as the input is known to be too large
+		if max := l.limits.MaxInstructions; max > 0 && len(instructions) > max {
+			l.errors = append(l.errors, parser.NewLimitError(parser.Position{Line: inst.Instruction.Line, Column: inst.Instruction.Column}, "MaxInstructions", int64(max)))
+			break
+		}
 	}
 	
 	return instructions, l.errors
@@ -3,6 +3,7 @@ package lexer
 import (
     "bufio"
     "bytes"
+    "fmt"
     "io"
     "strings"
     "unicode"
@@ -24,17 +25,62 @@ type Scanner struct {
     lastToken    *Token
     stageDepth   int
     variables    map[string]bool
+    limits       Limits
+}
+
+// Limits bounds the work the scanner does on a single input.
+// A zero value for any field disables that limit.
+type Limits struct {
+    MaxLineLength   int // Maximum length of a physical line
+    MaxHeredocSize  int // Maximum size of a heredoc body in bytes
+    MaxInstructions int // Maximum number of instructions; enforced by the lexer
 }
 
 func NewScanner(r io.Reader) *Scanner {
+    return NewScannerWithLimits(r, Limits{})
+}
+
+// NewScannerWithLimits creates a scanner that rejects input exceeding limits
+func NewScannerWithLimits(r io.Reader, limits Limits) *Scanner {
     return &Scanner{
         reader:       bufio.NewReader(r),
         position:     parser.Position{Line: 1, Column: 0},
         errorHandler: parser.NewErrorHandler(),
         variables:    make(map[string]bool),
+        limits:       limits,
     }
 }
 
+// SafeScan wraps Scan so that arbitrary input yields a token or an error,
+// never a panic, and enforces the configured limits.
+func (s *Scanner) SafeScan() (token *Token, err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            token = nil
+            err = &parser.DockerfileError{
+                Code:     parser.CodeInternalError,
+                Position: s.position,
+                Message:  "Scanner failed on malformed input",
+                Details:  fmt.Sprint(r),
+            }
+        }
+    }()
+
+    token, err = s.Scan()
+    if err != nil {
+        return nil, err
+    }
+    if token == nil {
+        return nil, io.EOF
+    }
+
+    if s.limits.MaxLineLength > 0 && s.position.Column > s.limits.MaxLineLength {
+        return nil, parser.NewLimitError(s.position, "MaxLineLength", int64(s.limits.MaxLineLength))
+    }
+
+    return token, nil
+}
+
 // Core scanning methods from previous implementation...
 
 // Enhanced scanning methods:
@@ -65,6 +111,10 @@ func (s *Scanner) scanHeredocContent() (*Token, error) {
         }
         
         s.buffer.WriteRune(s.char)
+
+        if s.limits.MaxHeredocSize > 0 && s.buffer.Len() > s.limits.MaxHeredocSize {
+            return nil, parser.NewLimitError(startPos, "MaxHeredocSize", int64(s.limits.MaxHeredocSize))
+        }
     }
     
     return &Token{
@@ -227,25 +277,19 @@ func (s *Scanner) scanContinuation() (*Token, error) {
 
 // Helper methods
 
+// peekLine returns the next line without consuming it. Lines longer than
+// the reader buffer are truncated, which is enough to match a heredoc word.
 func (s *Scanner) peekLine() (string, error) {
     s.peekBuffer.Reset()
-    for {
-        ch, _, err := s.reader.ReadRune()
-        if err != nil {
-            if err == io.EOF {
-                break
-            }
-            return "", err
-        }
-        
-        if ch == '\n' {
-            s.reader.UnreadRune()
-            break
-        }
-        
-        s.peekBuffer.WriteRune(ch)
-        s.reader.UnreadRune()
+    buf, err := s.reader.Peek(s.reader.Size())
+    if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+        return "", err
+    }
+    
+    if idx := bytes.IndexByte(buf, '\n'); idx >= 0 {
+        buf = buf[:idx]
     }
+    s.peekBuffer.Write(buf)
     return s.peekBuffer.String(), nil
 }
 
@@ -167,30 +167,45 @@ func getCategoryForToken(typ TokenType) string {
     }
 }
 
+// instructionImpacts describes every instruction. Only FROM, RUN, COPY and
+// ADD add filesystem content; the others change image metadata, which
+// Docker records as empty layers.
+var instructionImpacts = map[TokenType]TokenImpact{
+    TOKEN_INSTRUCTION_FROM:        {LayerCreating: true, CacheBreaking: true, SizeImpact: 10},
+    TOKEN_INSTRUCTION_RUN:         {LayerCreating: true, CacheBreaking: true, SizeImpact: 8},
+    TOKEN_INSTRUCTION_COPY:        {LayerCreating: true, CacheBreaking: true, SizeImpact: 7},
+    TOKEN_INSTRUCTION_ADD:         {LayerCreating: true, CacheBreaking: true, SizeImpact: 7},
+    TOKEN_INSTRUCTION_ARG:         {CacheBreaking: true, SizeImpact: 1},
+    TOKEN_INSTRUCTION_ENV:         {SizeImpact: 1},
+    TOKEN_INSTRUCTION_WORKDIR:     {SizeImpact: 1},
+    TOKEN_INSTRUCTION_USER:        {SizeImpact: 1},
+    TOKEN_INSTRUCTION_SHELL:       {SizeImpact: 1},
+    TOKEN_INSTRUCTION_VOLUME:      {SizeImpact: 1},
+    TOKEN_INSTRUCTION_ONBUILD:     {SizeImpact: 1},
+    TOKEN_INSTRUCTION_LABEL:       {SizeImpact: 1},
+    TOKEN_INSTRUCTION_MAINTAINER:  {SizeImpact: 1},
+    TOKEN_INSTRUCTION_EXPOSE:      {SizeImpact: 1},
+    TOKEN_INSTRUCTION_CMD:         {SizeImpact: 1},
+    TOKEN_INSTRUCTION_ENTRYPOINT:  {SizeImpact: 1},
+    TOKEN_INSTRUCTION_STOPSIGNAL:  {SizeImpact: 1},
+    TOKEN_INSTRUCTION_HEALTHCHECK: {SizeImpact: 1},
+}
+
 // Analyze instruction impact for ML optimization
 func getInstructionImpact(typ TokenType) TokenImpact {
-    impacts := map[TokenType]TokenImpact{
-        TOKEN_INSTRUCTION_FROM: {
-            LayerCreating: true,
-            CacheBreaking: true,
-            SizeImpact:    10,
-        },
-        TOKEN_INSTRUCTION_RUN: {
-            LayerCreating: true,
-            CacheBreaking: true,
-            SizeImpact:    8,
-        },
-        TOKEN_INSTRUCTION_COPY: {
-            LayerCreating: true,
-            CacheBreaking: true,
-            SizeImpact:    7,
-        },
-        // Add other instructions...
-    }
-    
-    if impact, exists := impacts[typ]; exists {
+    if impact, exists := instructionImpacts[typ]; exists {
         return impact
     }
     
     return TokenImpact{} // Default impact
 }
+
+// InstructionImpact returns the impact of the instruction with the given
+// keyword, such as "RUN"
+func InstructionImpact(keyword string) TokenImpact {
+    typ, ok := Keywords[strings.ToUpper(keyword)]
+    if !ok {
+        return TokenImpact{}
+    }
+    return getInstructionImpact(typ)
+}
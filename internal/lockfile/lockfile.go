@@ -0,0 +1,132 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/yourusername/dockerfile-parser/internal/image"
+)
+
+// DefaultName is the lockfile name looked up next to a Dockerfile
+const DefaultName = "dockerfile.lock"
+
+// formatVersion is the lockfile schema version written by Save
+const formatVersion = 1
+
+// Lockfile maps tagged base images to the content digests they are pinned
+// to. Digests are those of the image index, so one entry covers every
+// platform.
+type Lockfile struct {
+	Version int              `json:"version"`
+	Images  map[string]Entry `json:"images"`
+}
+
+// Entry is the locked state of one tagged image
+type Entry struct {
+	Digest string `json:"digest"`
+}
+
+// New creates an empty lockfile
+func New() *Lockfile {
+	return &Lockfile{
+		Version: formatVersion,
+		Images:  make(map[string]Entry),
+	}
+}
+
+// Load reads and validates a lockfile
+func Load(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read lockfile")
+	}
+
+	lock := New()
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, errors.Wrapf(err, "parse lockfile %s", path)
+	}
+	if lock.Version != formatVersion {
+		return nil, fmt.Errorf("lockfile %s has unsupported version %d", path, lock.Version)
+	}
+	if lock.Images == nil {
+		lock.Images = make(map[string]Entry)
+	}
+	for ref, entry := range lock.Images {
+		if !image.ValidDigest(entry.Digest) {
+			return nil, fmt.Errorf("lockfile %s: invalid digest %q for %s", path, entry.Digest, ref)
+		}
+	}
+	return lock, nil
+}
+
+// Save writes the lockfile atomically with sorted keys
+func (l *Lockfile) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "encode lockfile")
+	}
+	data = append(data, '\n')
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".lock-*")
+	if err != nil {
+		return errors.Wrap(err, "create lockfile")
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "write lockfile")
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "write lockfile")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "commit lockfile")
+	}
+	return nil
+}
+
+// Lookup returns the locked digest for an image reference; a missing tag
+// means latest and any digest in ref is ignored
+func (l *Lockfile) Lookup(ref string) (string, bool) {
+	parsed, err := image.ParseReference(ref)
+	if err != nil {
+		return "", false
+	}
+	entry, ok := l.Images[parsed.Tagged()]
+	return entry.Digest, ok
+}
+
+// Set locks an image reference to digest
+func (l *Lockfile) Set(ref, digest string) error {
+	parsed, err := image.ParseReference(ref)
+	if err != nil {
+		return err
+	}
+	if !image.ValidDigest(digest) {
+		return fmt.Errorf("invalid digest %q for %s", digest, ref)
+	}
+	l.Images[parsed.Tagged()] = Entry{Digest: digest}
+	return nil
+}
+
+// Fingerprint returns a stable description of the locked digests, suitable
+// as extra cache key input
+func (l *Lockfile) Fingerprint() string {
+	if l == nil {
+		return ""
+	}
+	refs := make([]string, 0, len(l.Images))
+	for ref, entry := range l.Images {
+		refs = append(refs, ref+"@"+entry.Digest)
+	}
+	sort.Strings(refs)
+	return strings.Join(refs, "\n")
+}
@@ -15,6 +15,7 @@ var (
     ErrMissingStage       = errors.New("referenced stage not found")
     ErrDuplicateStage     = errors.New("duplicate stage name")
     ErrInvalidBase        = errors.New("invalid base image specification")
+    ErrLimitExceeded      = errors.New("parse limit exceeded")
 )
 
 // ErrorCode represents specific error types for better error handling
@@ -29,6 +30,7 @@ const (
     CodeVariableError
     CodeIOError
     CodeInternalError
+    CodeLimitExceeded
 )
 
 // DockerfileError provides detailed error information
@@ -40,7 +42,7 @@ type DockerfileError struct {
     Details  string    // Technical details
     Snippet  string    // Problematic code snippet
     Hints    []string  // Suggested fixes
-    Cause    error    // Underlying error
+    Cause    error    `json:"-"` // Underlying error
 }
 
 func (e *DockerfileError) Error() string {
@@ -140,6 +142,23 @@ func NewInstructionError(pos Position, instruction string, message string) *Dock
     }
 }
 
+// NewLimitError reports that a configured ParseOptions limit was exceeded
+func NewLimitError(pos Position, limit string, max int64) *DockerfileError {
+    return &DockerfileError{
+        Code:     CodeLimitExceeded,
+        Position: pos,
+        Message:  fmt.Sprintf("%s limit exceeded (max %d)", limit, max),
+        Details:  "input rejected by ParseOptions." + limit,
+        Cause:    ErrLimitExceeded,
+    }
+}
+
+// IsLimitError reports whether err was caused by a tripped parse limit
+func IsLimitError(err error) bool {
+    var dockerfileErr *DockerfileError
+    return errors.As(err, &dockerfileErr) && dockerfileErr.Code == CodeLimitExceeded
+}
+
 // Helper functions for error handling
 func getSyntaxErrorHints(errMsg string) []string {
     hints := make([]string, 0)
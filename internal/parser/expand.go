@@ -0,0 +1,202 @@
+package parser
+
+import (
+	"strings"
+)
+
+// VariableLookup resolves a variable name to its value
+type VariableLookup func(name string) (string, bool)
+
+// ExpandVariables substitutes $VAR and ${VAR} references in s, including the
+// ${VAR:-default} and ${VAR:+alternate} modifiers. Values that themselves
+// reference variables are expanded recursively; maxDepth bounds that nesting
+// and a value of zero disables the bound.
+func ExpandVariables(s string, lookup VariableLookup, maxDepth int, pos Position) (string, error) {
+	e := &expander{lookup: lookup, maxDepth: maxDepth, pos: pos, active: make(map[string]bool)}
+	return e.expand(s, 1)
+}
+
+type expander struct {
+	lookup   VariableLookup
+	maxDepth int
+	pos      Position
+	active   map[string]bool // Variables currently being expanded
+}
+
+func (e *expander) expand(s string, depth int) (string, error) {
+	if e.maxDepth > 0 && depth > e.maxDepth {
+		return "", NewLimitError(e.pos, "MaxExpansionDepth", int64(e.maxDepth))
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+
+		// Escaped dollar sign is kept literally
+		if ch == '\\' && i+1 < len(s) && s[i+1] == '$' {
+			sb.WriteByte('$')
+			i++
+			continue
+		}
+
+		if ch != '$' || i+1 >= len(s) {
+			sb.WriteByte(ch)
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := matchingBrace(s, i+1)
+			if end < 0 {
+				// Unterminated reference, keep as-is
+				sb.WriteString(s[i:])
+				break
+			}
+			value, err := e.expandBraced(s[i+2:end], depth)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(value)
+			i = end
+			continue
+		}
+
+		j := i + 1
+		for j < len(s) && isVariableNameByte(s[j]) {
+			j++
+		}
+		if j == i+1 {
+			sb.WriteByte(ch)
+			continue
+		}
+		value, err := e.resolve(s[i+1:j], depth)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(value)
+		i = j - 1
+	}
+
+	return sb.String(), nil
+}
+
+// expandBraced handles the body of a ${...} reference
+func (e *expander) expandBraced(body string, depth int) (string, error) {
+	name := body
+	modifier := ""
+	word := ""
+	if idx := strings.Index(body, ":"); idx >= 0 && idx+1 < len(body) {
+		name = body[:idx]
+		modifier = body[idx : idx+2]
+		word = body[idx+2:]
+	}
+
+	value, err := e.resolve(name, depth)
+	if err != nil {
+		return "", err
+	}
+
+	switch modifier {
+	case ":-":
+		if value == "" {
+			return e.expand(word, depth+1)
+		}
+	case ":+":
+		if value != "" {
+			return e.expand(word, depth+1)
+		}
+		return "", nil
+	}
+
+	return value, nil
+}
+
+// resolve looks up a variable and expands references in its value.
+// Self-referencing values resolve to empty rather than recursing forever.
+func (e *expander) resolve(name string, depth int) (string, error) {
+	value, ok := e.lookup(name)
+	if !ok || e.active[name] {
+		return "", nil
+	}
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+
+	e.active[name] = true
+	defer delete(e.active, name)
+	return e.expand(value, depth+1)
+}
+
+// VariableReference is a $VAR or ${VAR} occurrence in a string
+type VariableReference struct {
+	Name    string
+	Start   int  // Byte offset of the $
+	End     int  // Byte offset just after the reference
+	Guarded bool // Uses ${VAR:-default} or ${VAR:+alternate}, so an unset VAR is expected
+}
+
+// VariableReferences lists the variable references in s, skipping escaped
+// dollar signs. References inside modifier words are included.
+func VariableReferences(s string) []VariableReference {
+	refs := make([]VariableReference, 0)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && s[i+1] == '$' {
+			i++
+			continue
+		}
+		if s[i] != '$' || i+1 >= len(s) {
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := matchingBrace(s, i+1)
+			if end < 0 {
+				break
+			}
+			body := s[i+2 : end]
+			name, word, guarded := body, "", false
+			if idx := strings.Index(body, ":"); idx >= 0 {
+				name, word, guarded = body[:idx], body[idx+1:], true
+			}
+			refs = append(refs, VariableReference{Name: name, Start: i, End: end + 1, Guarded: guarded})
+			for _, inner := range VariableReferences(word) {
+				offset := i + 2 + len(name) + 1
+				inner.Start += offset
+				inner.End += offset
+				refs = append(refs, inner)
+			}
+			i = end
+			continue
+		}
+
+		j := i + 1
+		for j < len(s) && isVariableNameByte(s[j]) {
+			j++
+		}
+		if j > i+1 {
+			refs = append(refs, VariableReference{Name: s[i+1 : j], Start: i, End: j})
+			i = j - 1
+		}
+	}
+	return refs
+}
+
+// matchingBrace returns the index of the '}' closing the '{' at open
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isVariableNameByte(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
@@ -169,6 +169,38 @@ func (p *InstructionParser) parseRunInstruction(tokens *lexer.InstructionTokens,
 		}
 	}
 
+	// Leading --mount, --network and --security flags; --mount may repeat,
+	// so repeated values are space separated
+	command := make([]string, 0, len(tokens.Arguments))
+	inFlags := true
+	for _, token := range tokens.Arguments {
+		if token.Type == lexer.TOKEN_WHITESPACE {
+			continue
+		}
+		if inFlags && strings.HasPrefix(token.Value, "--") {
+			kv := strings.SplitN(strings.TrimPrefix(token.Value, "--"), "=", 2)
+			value := ""
+			if len(kv) > 1 {
+				value = kv[1]
+			}
+			if existing, ok := instruction.Flags[kv[0]]; ok {
+				value = existing + " " + value
+			}
+			instruction.Flags[kv[0]] = value
+			continue
+		}
+		inFlags = false
+		command = append(command, token.Value)
+	}
+	if len(command) == 0 {
+		return &DockerfileError{
+			Code:     CodeInstructionError,
+			Message:  "RUN instruction requires a command after its flags",
+			Position: instruction.Range.Start,
+		}
+	}
+	args = strings.Join(command, " ")
+
 	// Check for heredoc
 	for _, token := range tokens.Raw {
 		if token.Type == lexer.TOKEN_HEREDOC_START {
@@ -370,6 +402,10 @@ func (p *InstructionParser) parseAddCopyInstruction(tokens *lexer.InstructionTok
 				instruction.Dependencies = append(instruction.Dependencies, fromValue)
 			} else if strings.HasPrefix(token.Value, "--chmod=") {
 				instruction.Flags["chmod"] = strings.TrimPrefix(token.Value, "--chmod=")
+			} else {
+				// Keep other flags such as --checksum, --link and --keep-git-dir
+				name, value, _ := strings.Cut(strings.TrimPrefix(token.Value, "--"), "=")
+				instruction.Flags[name] = value
 			}
 		} else if token.Type != lexer.TOKEN_WHITESPACE {
 			args = append(args, token.Value)
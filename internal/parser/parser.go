@@ -0,0 +1,454 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/yourusername/dockerfile-parser/internal/lexer"
+)
+
+// Default limits used by DefaultParseOptions for untrusted input
+const (
+	DefaultMaxFileSize       = 1 << 20 // 1 MiB
+	DefaultMaxLineLength     = 64 << 10
+	DefaultMaxHeredocSize    = 512 << 10
+	DefaultMaxInstructions   = 5000
+	DefaultMaxStages         = 64
+	DefaultMaxExpansionDepth = 16
+)
+
+// DefaultParseOptions returns options with resource limits suitable for
+// parsing Dockerfiles from untrusted sources
+func DefaultParseOptions() ParseOptions {
+	return ParseOptions{
+		ValidateInstructions: true,
+		MaxFileSize:          DefaultMaxFileSize,
+		MaxLineLength:        DefaultMaxLineLength,
+		MaxHeredocSize:       DefaultMaxHeredocSize,
+		MaxInstructions:      DefaultMaxInstructions,
+		MaxStages:            DefaultMaxStages,
+		MaxExpansionDepth:    DefaultMaxExpansionDepth,
+	}
+}
+
+// DockerfileParser is the default Parser implementation
+type DockerfileParser struct {
+	options           ParseOptions
+	instructionParser *InstructionParser
+	lastErrors        []error
+}
+
+// NewParser creates a parser with zero-value options (no limits)
+func NewParser() *DockerfileParser {
+	return NewParserWithOptions(ParseOptions{})
+}
+
+// NewParserWithOptions creates a parser that uses opts for every call
+// that does not take explicit options
+func NewParserWithOptions(opts ParseOptions) *DockerfileParser {
+	return &DockerfileParser{
+		options:           opts,
+		instructionParser: NewInstructionParser(),
+	}
+}
+
+// Parse parses Dockerfile content using the parser's options
+func (p *DockerfileParser) Parse(content string) (*ParsedDockerfile, error) {
+	return p.ParseContext(context.Background(), content)
+}
+
+// ParseContext parses Dockerfile content, stopping early if ctx is done
+func (p *DockerfileParser) ParseContext(ctx context.Context, content string) (*ParsedDockerfile, error) {
+	return p.ParseWithOptionsContext(ctx, content, p.options)
+}
+
+// ParseWithOptions parses Dockerfile content using opts
+func (p *DockerfileParser) ParseWithOptions(content string, opts ParseOptions) (*ParsedDockerfile, error) {
+	return p.ParseWithOptionsContext(context.Background(), content, opts)
+}
+
+// ParseWithOptionsContext parses Dockerfile content using opts, stopping
+// early if ctx is done
+func (p *DockerfileParser) ParseWithOptionsContext(ctx context.Context, content string, opts ParseOptions) (*ParsedDockerfile, error) {
+	df, err := p.parse(ctx, content, "", opts)
+	if df != nil {
+		p.lastErrors = df.Errors
+	}
+	return df, err
+}
+
+// ParseFile reads and parses the Dockerfile at path
+func (p *DockerfileParser) ParseFile(path string) (*ParsedDockerfile, error) {
+	return p.ParseFileContext(context.Background(), path)
+}
+
+// ParseFileContext reads and parses the Dockerfile at path, refusing files
+// larger than MaxFileSize before reading them
+func (p *DockerfileParser) ParseFileContext(ctx context.Context, path string) (*ParsedDockerfile, error) {
+	content, err := ReadFileContext(ctx, path, p.options.MaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseNamedContext(ctx, path, content)
+}
+
+// ParseNamedContext parses content that was read from filename, so that
+// positions and metadata refer to it
+func (p *DockerfileParser) ParseNamedContext(ctx context.Context, filename string, content string) (*ParsedDockerfile, error) {
+	df, err := p.parse(ctx, content, filename, p.options)
+	if df != nil {
+		p.lastErrors = df.Errors
+	}
+	return df, err
+}
+
+// Validate returns the non-fatal errors collected by the last parse
+func (p *DockerfileParser) Validate() []error {
+	return p.lastErrors
+}
+
+// ReadFileContext reads a Dockerfile while honouring maxSize and ctx.
+// A maxSize of zero disables the size check.
+func ReadFileContext(ctx context.Context, path string, maxSize int64) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", &DockerfileError{
+			Code:     CodeIOError,
+			Message:  "Cannot open Dockerfile",
+			Position: Position{FilePath: path},
+			Cause:    err,
+		}
+	}
+	defer f.Close()
+
+	var r io.Reader = &contextReader{ctx: ctx, r: f}
+	if maxSize > 0 {
+		if info, err := f.Stat(); err == nil && info.Size() > maxSize {
+			return "", NewLimitError(Position{FilePath: path}, "MaxFileSize", maxSize)
+		}
+		// The file may grow between Stat and Read
+		r = io.LimitReader(r, maxSize+1)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		return "", &DockerfileError{
+			Code:     CodeIOError,
+			Message:  "Cannot read Dockerfile",
+			Position: Position{FilePath: path},
+			Cause:    err,
+		}
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return "", NewLimitError(Position{FilePath: path}, "MaxFileSize", maxSize)
+	}
+
+	return string(data), nil
+}
+
+// contextReader fails reads once its context is done, so that long lexing
+// runs observe cancellation
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
+
+// parse builds a ParsedDockerfile from content
+func (p *DockerfileParser) parse(ctx context.Context, content string, filename string, opts ParseOptions) (*ParsedDockerfile, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrEmptyDockerfile
+	}
+	if opts.MaxFileSize > 0 && int64(len(content)) > opts.MaxFileSize {
+		return nil, NewLimitError(Position{FilePath: filename}, "MaxFileSize", opts.MaxFileSize)
+	}
+
+	df := &ParsedDockerfile{
+		Stages:       make([]*Stage, 0),
+		GlobalArgs:   make(map[string]Variable),
+		GlobalEnv:    make(map[string]Variable),
+		Raw:          content,
+		EscapeChar:   detectEscapeChar(content),
+		ParseOptions: opts,
+		Metadata: Metadata{
+			ParseTime: time.Now(),
+			Filename:  filename,
+			Size:      int64(len(content)),
+		},
+	}
+
+	lx := lexer.NewLexerWithLimits(&contextReader{ctx: ctx, r: strings.NewReader(content)}, lexer.Limits{
+		MaxLineLength:   opts.MaxLineLength,
+		MaxHeredocSize:  opts.MaxHeredocSize,
+		MaxInstructions: opts.MaxInstructions,
+	})
+	instructions, lexErrors := lx.ProcessAllInstructions()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, err := range lexErrors {
+		if IsLimitError(err) {
+			return nil, withFilePath(err, filename)
+		}
+		df.Errors = append(df.Errors, err)
+	}
+
+	var current *Stage
+	for _, tokens := range instructions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		tokens.JSONForm = lx.IsJSONForm(tokens.Raw)
+		inst, err := p.instructionParser.ParseInstruction(tokens, current)
+		if err != nil {
+			df.Errors = append(df.Errors, withFilePath(err, filename))
+			continue
+		}
+		inst.Range.Start.FilePath = filename
+		inst.Range.End.FilePath = filename
+
+		if inst.Command == "FROM" {
+			if opts.MaxStages > 0 && len(df.Stages) >= opts.MaxStages {
+				return nil, NewLimitError(inst.Range.Start, "MaxStages", int64(opts.MaxStages))
+			}
+			if current != nil {
+				current.Range.End = lastInstructionEnd(current)
+			}
+			current, err = newStage(df, inst, len(df.Stages), opts)
+			if err != nil {
+				return nil, err
+			}
+			df.Stages = append(df.Stages, current)
+			continue
+		}
+
+		if current == nil {
+			// Only ARG may precede the first FROM
+			if inst.Command != "ARG" {
+				df.Errors = append(df.Errors, NewInstructionError(inst.Range.Start, inst.Command, "must follow a FROM instruction"))
+				continue
+			}
+			v, err := declareVariable(df, inst, nil, opts)
+			if err != nil {
+				return nil, err
+			}
+			df.GlobalArgs[v.Name] = v
+			continue
+		}
+
+		current.AddInstruction(*inst)
+		if inst.Command == "ARG" || inst.Command == "ENV" {
+			if err := declareStageVariables(df, current, inst, opts); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if current != nil {
+		current.Range.End = lastInstructionEnd(current)
+	}
+
+	df.Metadata.StageCount = len(df.Stages)
+	for _, stage := range df.Stages {
+		if stage.BaseStage == nil {
+			df.Metadata.BaseImages = append(df.Metadata.BaseImages, stage.BaseImage)
+		}
+	}
+
+	if opts.ValidateInstructions && len(df.Errors) > 0 {
+		return df, df.Errors[0]
+	}
+
+	return df, nil
+}
+
+// newStage creates the stage started by a FROM instruction
+func newStage(df *ParsedDockerfile, from *Instruction, index int, opts ParseOptions) (*Stage, error) {
+	stage := &Stage{
+		Name:      from.Flags["stage"],
+		Index:     index,
+		Range:     Range{Start: from.Range.Start, End: from.Range.End},
+		Variables: make(map[string]Variable),
+		Platform:  opts.DefaultPlatform,
+	}
+	if platform, ok := from.Flags["platform"]; ok {
+		stage.Platform = platform
+	}
+
+	if len(from.Args) > 0 {
+		stage.BaseImage = from.Args[0]
+	}
+	if opts.AllowEnvVarExpansion && strings.Contains(stage.BaseImage, "$") {
+		expanded, err := ExpandVariables(stage.BaseImage, globalLookup(df), opts.MaxExpansionDepth, from.Range.Start)
+		if err != nil {
+			return nil, err
+		}
+		stage.BaseImage = expanded
+	}
+
+	// FROM <earlier stage> makes that stage the parent and inherits its ENV
+	for _, prev := range df.Stages {
+		if prev.Name != "" && strings.EqualFold(prev.Name, stage.BaseImage) {
+			stage.BaseStage = prev
+			for name, v := range prev.Variables {
+				if v.Type == EnvType {
+					stage.Variables[name] = v
+				}
+			}
+			break
+		}
+	}
+
+	stage.AddInstruction(*from)
+	return stage, nil
+}
+
+// declareStageVariables records ARG and ENV declarations on a stage
+func declareStageVariables(df *ParsedDockerfile, stage *Stage, inst *Instruction, opts ParseOptions) error {
+	if inst.Command == "ARG" {
+		v, err := declareVariable(df, inst, stage, opts)
+		if err != nil {
+			return err
+		}
+		stage.Variables[v.Name] = v
+		return nil
+	}
+
+	for _, pair := range inst.Args {
+		kv := strings.SplitN(pair, "=", 2)
+		v := Variable{
+			Name:     kv[0],
+			Position: inst.Range.Start,
+			Stage:    stage,
+			Type:     EnvType,
+			Scope:    StageScope,
+		}
+		if len(kv) > 1 {
+			v.Value = kv[1]
+		}
+		if opts.AllowEnvVarExpansion && strings.Contains(v.Value, "$") {
+			expanded, err := ExpandVariables(v.Value, stageLookup(df, stage), opts.MaxExpansionDepth, inst.Range.Start)
+			if err != nil {
+				return err
+			}
+			v.Value = expanded
+		}
+		stage.Variables[v.Name] = v
+	}
+	return nil
+}
+
+// declareVariable builds the Variable for an ARG instruction
+func declareVariable(df *ParsedDockerfile, inst *Instruction, stage *Stage, opts ParseOptions) (Variable, error) {
+	v := Variable{
+		Position: inst.Range.Start,
+		Stage:    stage,
+		Type:     ArgType,
+		Scope:    GlobalScope,
+	}
+	if len(inst.Args) > 0 {
+		v.Name = inst.Args[0]
+	}
+	if stage != nil {
+		v.Scope = StageScope
+	}
+	v.Default = inst.Flags["default"]
+	v.Value = v.Default
+
+	if opts.AllowEnvVarExpansion && strings.Contains(v.Value, "$") {
+		lookup := globalLookup(df)
+		if stage != nil {
+			lookup = stageLookup(df, stage)
+		}
+		expanded, err := ExpandVariables(v.Value, lookup, opts.MaxExpansionDepth, inst.Range.Start)
+		if err != nil {
+			return v, err
+		}
+		v.Value = expanded
+	}
+
+	// A stage ARG without a default inherits the global value
+	if stage != nil && v.Default == "" {
+		if global, ok := df.GlobalArgs[v.Name]; ok {
+			v.Value = global.Value
+		}
+	}
+
+	// Build arguments override declared defaults
+	if value, ok := opts.BuildArgs[v.Name]; ok {
+		v.Value = value
+	}
+	return v, nil
+}
+
+// globalLookup resolves variables declared before the first FROM
+func globalLookup(df *ParsedDockerfile) VariableLookup {
+	return func(name string) (string, bool) {
+		v, ok := df.GlobalArgs[name]
+		return v.Value, ok
+	}
+}
+
+// stageLookup resolves variables visible inside a stage
+func stageLookup(df *ParsedDockerfile, stage *Stage) VariableLookup {
+	return func(name string) (string, bool) {
+		v, ok := stage.Variables[name]
+		return v.Value, ok
+	}
+}
+
+// lastInstructionEnd returns the end position of the stage's last instruction
+func lastInstructionEnd(stage *Stage) Position {
+	if last := stage.LastInstruction(); last != nil {
+		return last.Range.End
+	}
+	return stage.Range.End
+}
+
+// detectEscapeChar reads the escape parser directive, defaulting to backslash
+func detectEscapeChar(content string) rune {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		directive := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		if strings.HasPrefix(strings.ToLower(directive), "escape=") {
+			value := strings.TrimSpace(directive[len("escape="):])
+			if value == "`" {
+				return '`'
+			}
+		}
+	}
+	return '\\'
+}
+
+// withFilePath attaches the file path to DockerfileError positions
+func withFilePath(err error, filename string) error {
+	var dockerfileErr *DockerfileError
+	if filename != "" && errors.As(err, &dockerfileErr) && dockerfileErr.Position.FilePath == "" {
+		dockerfileErr.Position.FilePath = filename
+	}
+	return err
+}
@@ -1,6 +1,9 @@
 package parser
 
 import (
+    "context"
+    "fmt"
+    "strings"
     "time"
     "github.com/docker/docker/builder/dockerfile/parser"
 )
@@ -38,7 +41,7 @@ type Instruction struct {
     Raw         string            // Raw instruction text
     Comment     string            // Associated comments
     JSONForm    bool             // Whether instruction uses JSON form
-    Stage       *Stage           // Parent build stage
+    Stage       *Stage           `json:"-"` // Parent build stage, restored by LinkStages
     Heredoc     *Heredoc         // Heredoc content if present
     Dependencies []string        // Files/resources this instruction depends on
 }
@@ -48,7 +51,7 @@ type Stage struct {
     Name         string
     Index        int
     BaseImage    string
-    BaseStage    *Stage          // Reference to base stage if using FROM
+    BaseStage    *Stage          `json:"-"` // Reference to base stage if using FROM
     Instructions []Instruction
     Range        Range
     Aliases      []string        // Other names for this stage
@@ -71,7 +74,7 @@ type Variable struct {
     Value     string
     Default   string
     Position  Position
-    Stage     *Stage
+    Stage     *Stage `json:"-"`
     Type      VariableType
     Scope     VariableScope
 }
@@ -99,6 +102,31 @@ type Warning struct {
     Message  string
     Position Position
     Context  string
+    RuleID   string    // Rule that produced the warning, empty for parser warnings
+    Fix      *Fix      // Optional automatic correction
+    Alternatives []Fix // Other valid corrections, never applied automatically
+    Related  []RelatedLocation // Other locations involved in the finding
+}
+
+// RelatedLocation points at a secondary location of a warning, such as
+// the instruction that overrides the reported one
+type RelatedLocation struct {
+    Position Position
+    Message  string
+}
+
+// Fix is an automatic correction for a warning
+type Fix struct {
+    Description string
+    Edits       []TextEdit
+}
+
+// TextEdit replaces the whole source lines Range.Start.Line through
+// Range.End.Line with NewText; an empty NewText deletes them. An edit whose
+// End.Line is before Start.Line inserts NewText ahead of Start.Line.
+type TextEdit struct {
+    Range   Range
+    NewText string
 }
 
 // WarnLevel indicates warning severity
@@ -110,15 +138,38 @@ const (
     WarnHigh
 )
 
+var warnLevelNames = map[WarnLevel]string{
+    WarnLow:    "low",
+    WarnMedium: "medium",
+    WarnHigh:   "high",
+}
+
+func (l WarnLevel) String() string {
+    if name, ok := warnLevelNames[l]; ok {
+        return name
+    }
+    return fmt.Sprintf("WarnLevel(%d)", int(l))
+}
+
+// ParseWarnLevel converts a severity name such as "medium" to a WarnLevel
+func ParseWarnLevel(name string) (WarnLevel, error) {
+    for level, n := range warnLevelNames {
+        if strings.EqualFold(n, strings.TrimSpace(name)) {
+            return level, nil
+        }
+    }
+    return WarnLow, fmt.Errorf("unknown severity %q (want low, medium or high)", name)
+}
+
 // ParsedDockerfile represents the final parsed Dockerfile
 type ParsedDockerfile struct {
     Stages       []*Stage
     GlobalArgs   map[string]Variable
     GlobalEnv    map[string]Variable
     Raw          string
-    AST          *parser.Node
+    AST          *parser.Node    `json:"-"`
     Metadata     Metadata
-    Errors       []error
+    Errors       []error         `json:"-"`
     Warnings     []Warning
     EscapeChar   rune            // \ or ` as escape character
     ParseOptions ParseOptions
@@ -133,6 +184,15 @@ type ParseOptions struct {
     DefaultPlatform    string
     BuildContext      string
     TargetStage       string
+    BuildArgs         map[string]string // --build-arg overrides for ARG defaults
+
+    // Resource limits for untrusted input; zero disables a limit
+    MaxFileSize       int64 // Maximum Dockerfile size in bytes
+    MaxLineLength     int   // Maximum length of a physical line
+    MaxHeredocSize    int   // Maximum size of a single heredoc body
+    MaxInstructions   int   // Maximum number of instructions
+    MaxStages         int   // Maximum number of build stages
+    MaxExpansionDepth int   // Maximum nesting of variable expansion
 }
 
 // Parser defines the interface for Dockerfile parsing
@@ -140,6 +200,8 @@ type Parser interface {
     Parse(content string) (*ParsedDockerfile, error)
     ParseFile(filepath string) (*ParsedDockerfile, error)
     ParseWithOptions(content string, opts ParseOptions) (*ParsedDockerfile, error)
+    ParseContext(ctx context.Context, content string) (*ParsedDockerfile, error)
+    ParseFileContext(ctx context.Context, filepath string) (*ParsedDockerfile, error)
     Validate() []error
 }
 
@@ -149,6 +211,19 @@ type InstructionVisitor interface {
     VisitStage(stage *Stage) error
 }
 
+// Analyzer inspects a parsed Dockerfile and reports findings as warnings.
+// Implementations must be safe for concurrent use.
+type Analyzer interface {
+    Analyze(ctx context.Context, df *ParsedDockerfile) ([]Warning, error)
+}
+
+// AnalyzerFunc adapts a function to the Analyzer interface
+type AnalyzerFunc func(ctx context.Context, df *ParsedDockerfile) ([]Warning, error)
+
+func (f AnalyzerFunc) Analyze(ctx context.Context, df *ParsedDockerfile) ([]Warning, error) {
+    return f(ctx, df)
+}
+
 // Helpers and utility methods
 
 func (i *Instruction) HasFlag(name string) bool {
@@ -176,3 +251,33 @@ func (s *Stage) AddInstruction(inst Instruction) {
     s.Instructions = append(s.Instructions, inst)
 }
 
+// LinkStages restores the Stage back-references that are not serialized:
+// Instruction.Stage, Variable.Stage and Stage.BaseStage
+func (df *ParsedDockerfile) LinkStages() {
+    for i, stage := range df.Stages {
+        stage.BaseStage = nil
+        for _, prev := range df.Stages[:i] {
+            if prev.Name != "" && strings.EqualFold(prev.Name, stage.BaseImage) {
+                stage.BaseStage = prev
+                break
+            }
+        }
+
+        for j := range stage.Instructions {
+            stage.Instructions[j].Stage = stage
+        }
+
+        for name, v := range stage.Variables {
+            // Inherited ENV belongs to the ancestor that declared it
+            v.Stage = stage
+            for owner := stage; owner != nil; owner = owner.BaseStage {
+                if v.Position.Line >= owner.Range.Start.Line && v.Position.Line <= owner.Range.End.Line {
+                    v.Stage = owner
+                    break
+                }
+            }
+            stage.Variables[name] = v
+        }
+    }
+}
+
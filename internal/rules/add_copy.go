@@ -0,0 +1,156 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(addCopyRule{})
+}
+
+// addCopyRule separates ADD uses that need ADD's extra behaviour from those
+// that should be COPY
+type addCopyRule struct{}
+
+func (addCopyRule) ID() string { return "add-vs-copy" }
+
+func (addCopyRule) Description() string {
+	return "ADD should only be used for archives to extract, git sources and verified downloads"
+}
+
+func (addCopyRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+// Extensions of archives that ADD extracts when copied from the context
+var tarExtensions = []string{".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tbz", ".tar.xz", ".txz", ".tar.zst"}
+
+// addSourceKind classifies an ADD source
+type addSourceKind int
+
+const (
+	addLocalFile addSourceKind = iota
+	addLocalArchive
+	addRemoteURL
+	addGitSource
+)
+
+func classifyAddSource(src string) addSourceKind {
+	lower := strings.ToLower(src)
+	switch {
+	case strings.HasPrefix(lower, "git@") || strings.HasPrefix(lower, "git://") ||
+		strings.HasSuffix(strings.SplitN(lower, "#", 2)[0], ".git"):
+		return addGitSource
+	case strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://"):
+		return addRemoteURL
+	}
+	for _, ext := range tarExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return addLocalArchive
+		}
+	}
+	return addLocalFile
+}
+
+func (r addCopyRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+
+	for _, stage := range df.Stages {
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			if inst.Command != "ADD" || len(inst.Args) < 2 {
+				continue
+			}
+
+			sources := inst.Args[:len(inst.Args)-1]
+			local := make([]string, 0)
+			archives := make([]string, 0)
+			for _, src := range sources {
+				src = strings.Trim(src, `[]",`)
+				switch classifyAddSource(src) {
+				case addRemoteURL:
+					if _, ok := inst.Flags["checksum"]; !ok {
+						w := newWarning(r, inst, fmt.Sprintf("ADD downloads %s without --checksum, so the content is not verified", src))
+						w.Level = parser.WarnMedium
+						warnings = append(warnings, w)
+					}
+				case addLocalArchive:
+					archives = append(archives, src)
+				case addLocalFile:
+					local = append(local, src)
+				}
+			}
+
+			// ADD only earns its keep when some source needs it
+			if len(local) == len(sources) && !usesAddOnlyFlags(inst) {
+				w := newWarning(r, inst, fmt.Sprintf("ADD copies local %s; use COPY, which never extracts archives or fetches URLs", strings.Join(local, ", ")))
+				w.Fix = addToCopyFix(lines, inst)
+				warnings = append(warnings, w)
+				continue
+			}
+
+			for _, archive := range archives {
+				if run, ok := extractsAgain(df, stage, i, archive); ok {
+					w := newWarning(r, inst, fmt.Sprintf(
+						"ADD already extracts %s, but the RUN at line %d extracts it again; use COPY to keep the archive as a file",
+						archive, run.Range.Start.Line))
+					w.Level = parser.WarnMedium
+					if len(archives) == len(sources) && !usesAddOnlyFlags(inst) {
+						w.Fix = addToCopyFix(lines, inst)
+					}
+					warnings = append(warnings, w)
+				}
+			}
+		}
+	}
+
+	return warnings
+}
+
+// usesAddOnlyFlags reports whether the instruction has flags COPY rejects
+func usesAddOnlyFlags(inst *parser.Instruction) bool {
+	for _, flag := range []string{"checksum", "keep-git-dir", "unpack"} {
+		if _, ok := inst.Flags[flag]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// extractsAgain finds a later RUN in the stage that unpacks the archive
+func extractsAgain(df *parser.ParsedDockerfile, stage *parser.Stage, addIdx int, archive string) (*parser.Instruction, bool) {
+	name := path.Base(archive)
+	for i := addIdx + 1; i < len(stage.Instructions); i++ {
+		inst := &stage.Instructions[i]
+		if inst.Command != "RUN" {
+			continue
+		}
+		for _, c := range instructionCommands(df, inst) {
+			if c.name != "tar" || !c.mentions(name) {
+				continue
+			}
+			if c.hasShortFlag('x') || c.hasFlag("--extract", "--get") || strings.HasPrefix(c.subcommand(), "x") {
+				return inst, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// addToCopyFix replaces the ADD keyword with COPY
+func addToCopyFix(lines []string, inst *parser.Instruction) *parser.Fix {
+	n := inst.Range.Start.Line
+	line := lineText(lines, n)
+	trimmed := strings.TrimLeft(line, " \t")
+	if len(trimmed) < 3 || !strings.EqualFold(trimmed[:3], "ADD") {
+		return nil
+	}
+	indent := line[:len(line)-len(trimmed)]
+	return &parser.Fix{
+		Description: "Replace ADD with COPY",
+		Edits:       []parser.TextEdit{replaceLines(n, n, indent+"COPY"+trimmed[3:])},
+	}
+}
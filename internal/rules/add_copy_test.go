@@ -0,0 +1,55 @@
+package rules
+
+import "testing"
+
+func TestAddVsCopy(t *testing.T) {
+	runRuleTests(t, "add-vs-copy", []ruleTest{
+		{
+			name: "local files",
+			dockerfile: `FROM alpine
+ADD app.py config.yaml /app/`,
+			want: []string{"ADD copies local app.py, config.yaml; use COPY"},
+			fixed: `FROM alpine
+COPY app.py config.yaml /app/`,
+		},
+		{
+			name: "indentation and lowercase keyword are kept",
+			dockerfile: `FROM alpine
+  add app.py /app/`,
+			want: []string{"ADD copies local app.py"},
+			fixed: `FROM alpine
+  COPY app.py /app/`,
+		},
+		{
+			name: "download without a checksum",
+			dockerfile: `FROM alpine
+ADD https://example.com/tool.tar.gz /opt/`,
+			want: []string{"ADD downloads https://example.com/tool.tar.gz without --checksum"},
+		},
+		{
+			name: "download with a checksum",
+			dockerfile: `FROM alpine
+ADD --checksum=sha256:24454f830cdb571e2c4ad15481119c43b3cafd48dd869a9b2945d1036d1dc68d https://example.com/tool.tar.gz /opt/`,
+		},
+		{
+			name: "archive extracted again",
+			dockerfile: `FROM alpine
+ADD dist.tar.gz /tmp/
+RUN tar -xzf /tmp/dist.tar.gz -C /opt`,
+			want: []string{"ADD already extracts dist.tar.gz, but the RUN at line 3 extracts it again"},
+			fixed: `FROM alpine
+COPY dist.tar.gz /tmp/
+RUN tar -xzf /tmp/dist.tar.gz -C /opt`,
+		},
+		{
+			name: "archive ADD extracts",
+			dockerfile: `FROM alpine
+ADD rootfs.tar.xz /`,
+		},
+		{
+			name: "git source",
+			dockerfile: `FROM alpine
+ADD https://github.com/moby/buildkit.git#v0.12.0 /src`,
+		},
+	})
+}
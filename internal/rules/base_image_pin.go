@@ -0,0 +1,179 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/image"
+	"github.com/yourusername/dockerfile-parser/internal/lockfile"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(baseImagePinRule{})
+}
+
+// baseImagePinRule flags base images that are not pinned to a digest. With
+// a lockfile in the environment it offers to pin them.
+type baseImagePinRule struct{}
+
+func (baseImagePinRule) ID() string { return "unpinned-base-image" }
+
+func (baseImagePinRule) Description() string {
+	return "Base images should use a specific tag pinned to a digest"
+}
+
+func (baseImagePinRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+func (r baseImagePinRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	return r.CheckEnv(df, Env{})
+}
+
+func (r baseImagePinRule) CheckEnv(df *parser.ParsedDockerfile, env Env) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+
+	for _, base := range baseImages(df, lines) {
+		if base.ref.Pinned() {
+			continue
+		}
+
+		var w parser.Warning
+		switch {
+		case base.ref.Tag == "":
+			w = newWarning(r, base.from, fmt.Sprintf("Base image %s has no tag and resolves to %s:latest", base.ref, base.ref.Name))
+		case base.ref.Tag == image.DefaultTag:
+			w = newWarning(r, base.from, fmt.Sprintf("Base image %s uses the mutable latest tag", base.ref))
+		default:
+			w = newWarning(r, base.from, fmt.Sprintf("Base image %s is not pinned to a digest", base.ref))
+			w.Level = parser.WarnLow
+		}
+
+		if env.Lockfile != nil {
+			w.Fix = base.pinFix(lines, env.Lockfile)
+		}
+		warnings = append(warnings, w)
+	}
+
+	return warnings
+}
+
+// PinBaseImages returns a fix for every FROM line whose base image has a
+// lockfile digest it does not already use, along with the tagged
+// references the lockfile has no digest for
+func PinBaseImages(df *parser.ParsedDockerfile, lock *lockfile.Lockfile) ([]parser.Warning, []string) {
+	warnings := make([]parser.Warning, 0)
+	missing := make([]string, 0)
+	lines := sourceLines(df)
+	rule := baseImagePinRule{}
+
+	for _, base := range baseImages(df, lines) {
+		digest, ok := lock.Lookup(base.ref.String())
+		if !ok {
+			if !containsString(missing, base.ref.Tagged()) {
+				missing = append(missing, base.ref.Tagged())
+			}
+			continue
+		}
+		if digest == base.ref.Digest {
+			continue
+		}
+
+		w := newWarning(rule, base.from, fmt.Sprintf("Base image %s pinned to %s from the lockfile", base.ref, digest))
+		w.RuleID = rule.ID()
+		w.Fix = base.pinFix(lines, lock)
+		warnings = append(warnings, w)
+	}
+
+	return warnings, missing
+}
+
+// BaseImageRefs returns the registry images used by FROM lines, skipping
+// scratch, earlier stages and references built from variables
+func BaseImageRefs(df *parser.ParsedDockerfile) []image.Reference {
+	refs := make([]image.Reference, 0)
+	for _, base := range baseImages(df, sourceLines(df)) {
+		refs = append(refs, base.ref)
+	}
+	return refs
+}
+
+// baseImage is a FROM line that names a registry image
+type baseImage struct {
+	stage *parser.Stage
+	from  *parser.Instruction
+	ref   image.Reference
+	start int // Byte offset of the reference in the FROM line
+	end   int
+}
+
+// baseImages finds the registry image of every stage
+func baseImages(df *parser.ParsedDockerfile, lines []string) []baseImage {
+	result := make([]baseImage, 0)
+	for _, stage := range df.Stages {
+		if stage.BaseStage != nil || len(stage.Instructions) == 0 || stage.Instructions[0].Command != "FROM" {
+			continue
+		}
+		from := &stage.Instructions[0]
+
+		line := lineText(lines, from.Range.Start.Line)
+		start, end := fromImageSpan(line)
+		if start == end {
+			continue
+		}
+		written := line[start:end]
+		if strings.Contains(written, "$") || strings.EqualFold(written, "scratch") {
+			continue
+		}
+
+		ref, err := image.ParseReference(written)
+		if err != nil {
+			continue
+		}
+		result = append(result, baseImage{stage: stage, from: from, ref: ref, start: start, end: end})
+	}
+	return result
+}
+
+// pinFix rewrites the FROM line to name:tag@digest using the lockfile
+func (b baseImage) pinFix(lines []string, lock *lockfile.Lockfile) *parser.Fix {
+	digest, ok := lock.Lookup(b.ref.String())
+	if !ok {
+		return nil
+	}
+
+	pinned := image.Reference{Name: b.ref.Name, Tag: b.ref.TagOrDefault(), Digest: digest}
+	n := b.from.Range.Start.Line
+	line := lineText(lines, n)
+	return &parser.Fix{
+		Description: "Pin base image to " + pinned.String(),
+		Edits:       []parser.TextEdit{replaceLines(n, n, line[:b.start]+pinned.String()+line[b.end:])},
+	}
+}
+
+// fromImageSpan locates the image reference in a FROM line, after the
+// keyword and any --platform flag
+func fromImageSpan(line string) (int, int) {
+	i := 0
+	skipSpace := func() {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+	}
+	skipWord := func() {
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' && line[i] != '\r' {
+			i++
+		}
+	}
+
+	skipSpace()
+	skipWord() // FROM
+	skipSpace()
+	for strings.HasPrefix(line[i:], "--") {
+		skipWord()
+		skipSpace()
+	}
+	start := i
+	skipWord()
+	return start, i
+}
@@ -0,0 +1,60 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/dockerfile-parser/internal/lockfile"
+)
+
+func TestUnpinnedBaseImage(t *testing.T) {
+	const digest = "sha256:24454f830cdb571e2c4ad15481119c43b3cafd48dd869a9b2945d1036d1dc68d"
+	lock := lockfile.New()
+	require.NoError(t, lock.Set("node:20", digest))
+	require.NoError(t, lock.Set("alpine:latest", digest))
+
+	runRuleTests(t, "unpinned-base-image", []ruleTest{
+		{
+			name:       "no tag",
+			dockerfile: "FROM alpine",
+			want:       []string{"Base image alpine has no tag and resolves to alpine:latest"},
+		},
+		{
+			name:       "latest tag",
+			dockerfile: "FROM ubuntu:latest",
+			want:       []string{"Base image ubuntu:latest uses the mutable latest tag"},
+		},
+		{
+			name:       "tag without a digest",
+			dockerfile: "FROM python:3.12-slim",
+			want:       []string{"Base image python:3.12-slim is not pinned to a digest"},
+		},
+		{
+			name:       "pinned",
+			dockerfile: "FROM node:20@" + digest,
+		},
+		{
+			name: "stages, scratch and variables are skipped",
+			dockerfile: `ARG BASE=debian
+FROM ${BASE} AS base
+FROM base
+FROM scratch`,
+		},
+		{
+			name: "digests from the lockfile",
+			dockerfile: `FROM --platform=$BUILDPLATFORM node:20 AS build
+FROM alpine
+FROM python:3.12`,
+			env: Env{Lockfile: lock},
+			want: []string{
+				"Base image node:20 is not pinned to a digest",
+				"Base image alpine has no tag",
+				"Base image python:3.12 is not pinned to a digest",
+			},
+			fixed: `FROM --platform=$BUILDPLATFORM node:20@` + digest + ` AS build
+FROM alpine:latest@` + digest + `
+FROM python:3.12`,
+		},
+	})
+}
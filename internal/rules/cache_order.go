@@ -0,0 +1,553 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+	"github.com/yourusername/dockerfile-parser/internal/shell"
+)
+
+func init() {
+	Register(cacheOrderRule{})
+}
+
+// cacheOrderRule finds dependency installs that run after the whole build
+// context is copied, so that any source change reinstalls every dependency
+type cacheOrderRule struct{}
+
+func (cacheOrderRule) ID() string { return "cache-order" }
+
+func (cacheOrderRule) Description() string {
+	return "Dependency manifests should be copied and installed before the rest of the source"
+}
+
+func (cacheOrderRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+// dependencyInstall recognises an install command that only needs the
+// ecosystem's manifests and lockfiles
+type dependencyInstall struct {
+	ecosystem string
+	names     []string
+	manifests func(c command) []string // nil when the command needs more than manifests
+}
+
+var dependencyInstalls = []dependencyInstall{
+	{
+		ecosystem: "npm",
+		names:     []string{"npm"},
+		manifests: func(c command) []string {
+			if !containsString([]string{"ci", "install", "i"}, c.subcommand()) || len(c.operands()) > 0 {
+				return nil
+			}
+			return []string{"package*.json"}
+		},
+	},
+	{
+		ecosystem: "Yarn",
+		names:     []string{"yarn"},
+		manifests: func(c command) []string {
+			if c.subcommand() != "" && c.subcommand() != "install" {
+				return nil
+			}
+			return []string{"package.json", "yarn.lock"}
+		},
+	},
+	{
+		ecosystem: "pnpm",
+		names:     []string{"pnpm"},
+		manifests: func(c command) []string {
+			if !containsString([]string{"install", "i"}, c.subcommand()) || len(c.operands()) > 0 {
+				return nil
+			}
+			return []string{"package.json", "pnpm-lock.yaml"}
+		},
+	},
+	{
+		ecosystem: "pip",
+		names:     []string{"pip", "pip3"},
+		manifests: requirementFiles,
+	},
+	{
+		ecosystem: "Poetry",
+		names:     []string{"poetry"},
+		manifests: func(c command) []string {
+			// Without --no-root Poetry also installs the project itself
+			if c.subcommand() != "install" || !c.hasFlag("--no-root") {
+				return nil
+			}
+			return []string{"pyproject.toml", "poetry.lock"}
+		},
+	},
+	{
+		ecosystem: "Pipenv",
+		names:     []string{"pipenv"},
+		manifests: func(c command) []string {
+			if c.subcommand() != "install" || len(c.operands()) > 0 {
+				return nil
+			}
+			return []string{"Pipfile", "Pipfile.lock"}
+		},
+	},
+	{
+		ecosystem: "Go",
+		names:     []string{"go"},
+		manifests: func(c command) []string {
+			if c.subcommand() != "mod" || !containsString(c.operands(), "download") {
+				return nil
+			}
+			return []string{"go.mod", "go.sum"}
+		},
+	},
+	{
+		ecosystem: "Maven",
+		names:     []string{"mvn", "mvnw", "./mvnw"},
+		manifests: func(c command) []string {
+			for _, arg := range c.args {
+				if strings.HasPrefix(arg, "dependency:go-offline") || strings.HasPrefix(arg, "dependency:resolve") {
+					return []string{"pom.xml"}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ecosystem: "Bundler",
+		names:     []string{"bundle", "bundler"},
+		manifests: func(c command) []string {
+			if c.subcommand() != "" && c.subcommand() != "install" {
+				return nil
+			}
+			return []string{"Gemfile", "Gemfile.lock"}
+		},
+	},
+	{
+		ecosystem: "Cargo",
+		names:     []string{"cargo"},
+		manifests: func(c command) []string {
+			if c.subcommand() != "fetch" {
+				return nil
+			}
+			return []string{"Cargo.toml", "Cargo.lock"}
+		},
+	},
+}
+
+// requirementFiles returns the -r and -c files of a pip install that
+// installs nothing from the source tree
+func requirementFiles(c command) []string {
+	if c.subcommand() != "install" {
+		return nil
+	}
+
+	files := make([]string, 0)
+	for i := 0; i < len(c.args); i++ {
+		arg := c.args[i]
+		switch {
+		case arg == "-r" || arg == "--requirement" || arg == "-c" || arg == "--constraint":
+			if i+1 < len(c.args) {
+				files = append(files, c.args[i+1])
+				i++
+			}
+		case strings.HasPrefix(arg, "--requirement=") || strings.HasPrefix(arg, "--constraint="):
+			files = append(files, arg[strings.IndexByte(arg, '=')+1:])
+		case strings.HasPrefix(arg, "-r") || strings.HasPrefix(arg, "-c"):
+			files = append(files, arg[2:])
+		case arg == "-e" || arg == "--editable" || arg == "." || strings.HasPrefix(arg, "./"):
+			return nil
+		}
+	}
+
+	for _, file := range files {
+		if path.IsAbs(file) || strings.Contains(file, "..") || strings.Contains(file, "$") {
+			return nil
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	return files
+}
+
+// Commands that can run before the source is copied, in the part of a RUN
+// that moves ahead of the source copy
+var contextFreeCommands = map[string]bool{
+	"apt-get": true, "apt": true, "apk": true, "yum": true, "dnf": true, "microdnf": true,
+	"npm": true, "yarn": true, "pnpm": true, "corepack": true,
+	"pip": true, "pip3": true, "poetry": true, "pipenv": true,
+	"go": true, "mvn": true, "mvnw": true, "bundle": true, "bundler": true, "gem": true, "cargo": true,
+	"rm": true, "mkdir": true, "true": true,
+}
+
+// Instructions a moved install can be hoisted across without changing what
+// it sees
+var cacheOrderNeutral = map[string]bool{
+	"LABEL":       true,
+	"EXPOSE":      true,
+	"MAINTAINER":  true,
+	"STOPSIGNAL":  true,
+	"HEALTHCHECK": true,
+	"CMD":         true,
+	"ENTRYPOINT":  true,
+	"VOLUME":      true,
+}
+
+// installStep is one command of a RUN, split at && and ;
+type installStep struct {
+	stmt     *shell.Stmt
+	joiner   string // Operator before the step
+	commands []command
+}
+
+func (r cacheOrderRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+
+	for _, stage := range df.Stages {
+		contextCopy := -1
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			if contextCopy < 0 && copiesWholeContext(inst) {
+				contextCopy = i
+				continue
+			}
+			if contextCopy < 0 || inst.Command != "RUN" {
+				continue
+			}
+
+			if w, ok := r.checkInstall(df, lines, stage, contextCopy, i); ok {
+				warnings = append(warnings, w)
+			}
+		}
+	}
+
+	return warnings
+}
+
+// checkInstall reports the RUN at index run when it installs dependencies
+// after the context copy at index copyIdx
+func (r cacheOrderRule) checkInstall(df *parser.ParsedDockerfile, lines []string, stage *parser.Stage, copyIdx, run int) (parser.Warning, bool) {
+	inst := &stage.Instructions[run]
+	copyInst := &stage.Instructions[copyIdx]
+
+	commands := make([]command, 0)
+	for _, c := range instructionCommands(df, inst) {
+		if !c.heredoc {
+			commands = append(commands, c)
+		}
+	}
+
+	ecosystems := make([]string, 0)
+	manifests := make([]string, 0)
+	var lastInstall command
+	for _, c := range commands {
+		for _, dep := range dependencyInstalls {
+			if !containsString(dep.names, c.name) {
+				continue
+			}
+			files := dep.manifests(c)
+			if files == nil {
+				continue
+			}
+			if !containsString(ecosystems, dep.ecosystem) {
+				ecosystems = append(ecosystems, dep.ecosystem)
+			}
+			for _, f := range files {
+				if !containsString(manifests, f) {
+					manifests = append(manifests, f)
+				}
+			}
+			lastInstall = c
+		}
+	}
+	if len(ecosystems) == 0 {
+		return parser.Warning{}, false
+	}
+
+	advice := "run the install before it, since the manifests are already copied"
+	if groups := manifestCopies(df, stage, copyIdx, manifests); len(groups) > 0 {
+		missing := make([]string, 0)
+		for _, group := range groups {
+			missing = append(missing, group.files...)
+		}
+		advice = "copy " + strings.Join(missing, ", ") + " first"
+	}
+	w := newWarning(r, inst, fmt.Sprintf(
+		"%s dependencies are installed after %s copies the whole build context (line %d), so any source change reinstalls them; %s",
+		strings.Join(ecosystems, " and "), copyInst.Command, copyInst.Range.Start.Line, advice))
+	w.Fix = r.reorderFix(df, lines, stage, copyIdx, run, manifests, lastInstall)
+	return w, true
+}
+
+// reorderFix copies the missing manifests just above the context copy and
+// moves the install there. A RUN that goes on to use the source is split
+// after the install.
+func (r cacheOrderRule) reorderFix(df *parser.ParsedDockerfile, lines []string, stage *parser.Stage, copyIdx, run int, manifests []string, lastInstall command) *parser.Fix {
+	inst := &stage.Instructions[run]
+	copyInst := &stage.Instructions[copyIdx]
+	if inst.Heredoc != nil || copyInst.JSONForm {
+		return nil
+	}
+	for _, between := range stage.Instructions[copyIdx+1 : run] {
+		if !cacheOrderNeutral[between.Command] {
+			return nil
+		}
+	}
+
+	steps, ok := installSteps(lastInstall.script)
+	if !ok {
+		return nil
+	}
+	split := -1
+	for i, step := range steps {
+		if lastInstall.call.Pos() >= step.stmt.Pos() && lastInstall.call.End() <= step.stmt.End() {
+			split = i
+		}
+	}
+	if split < 0 {
+		return nil
+	}
+	// Cache cleanup right after the install belongs in the same layer
+	for split+1 < len(steps) && isCleanupStep(steps[split+1]) {
+		split++
+	}
+	for _, step := range steps[:split+1] {
+		for _, c := range step.commands {
+			if !contextFreeCommands[c.name] {
+				return nil
+			}
+		}
+	}
+
+	// Manifest paths are relative to the install's working directory, which
+	// must be where the context lands
+	dest := copyInst.Args[len(copyInst.Args)-1]
+	if dest != "." && dest != "./" && path.Clean(dest) != path.Clean(workdirAt(stage, copyIdx)) {
+		return nil
+	}
+	if !strings.HasSuffix(dest, "/") {
+		dest += "/"
+	}
+
+	block := make([]string, 0)
+	for _, group := range manifestCopies(df, stage, copyIdx, manifests) {
+		src := strings.Join(group.files, " ")
+		block = append(block, fmt.Sprintf("COPY%s %s %s", ownershipFlags(copyInst), src, dest+group.dir))
+	}
+
+	firstLine := lineText(lines, inst.Range.Start.Line)
+	header := strings.TrimRight(firstLine[:runHeaderLength(firstLine)], " \t")
+	start := commentBlockStart(lines, inst.Range.Start.Line)
+	end := instructionEndLine(inst)
+
+	edits := make([]parser.TextEdit, 0, 2)
+	if split == len(steps)-1 {
+		for n := start; n <= end; n++ {
+			block = append(block, lineText(lines, n))
+		}
+		edits = append(edits, deleteLines(start, end))
+	} else {
+		script := lastInstall.script
+		block = append(block, header+" "+stepsText(script, steps[:split+1]))
+		rest := stepsText(script, steps[split+1:])
+		edits = append(edits, replaceLines(inst.Range.Start.Line, end, header+" "+rest))
+	}
+
+	insertAt := commentBlockStart(lines, copyInst.Range.Start.Line)
+	edits = append([]parser.TextEdit{insertBefore(insertAt, strings.Join(block, "\n"))}, edits...)
+
+	return &parser.Fix{
+		Description: "Copy dependency manifests and install before copying the rest of the source",
+		Edits:       edits,
+	}
+}
+
+// copiesWholeContext reports whether inst copies the root of the build context
+func copiesWholeContext(inst *parser.Instruction) bool {
+	if (inst.Command != "COPY" && inst.Command != "ADD") || inst.Flags["from"] != "" || len(inst.Args) < 2 {
+		return false
+	}
+	for _, src := range inst.Args[:len(inst.Args)-1] {
+		switch strings.Trim(src, `[]",`) {
+		case ".", "./", "*", "./*", "./.":
+			return true
+		}
+	}
+	return false
+}
+
+// workdirAt returns the working directory in effect at instruction idx of
+// the stage, ignoring inherited and variable working directories
+func workdirAt(stage *parser.Stage, idx int) string {
+	dir := "/"
+	for _, inst := range stage.Instructions[:idx] {
+		if inst.Command != "WORKDIR" || len(inst.Args) == 0 {
+			continue
+		}
+		if path.IsAbs(inst.Args[0]) {
+			dir = inst.Args[0]
+		} else {
+			dir = path.Join(dir, inst.Args[0])
+		}
+	}
+	return dir
+}
+
+// manifestGroup is a set of manifests that share a directory
+type manifestGroup struct {
+	dir   string
+	files []string
+}
+
+// Lockfiles an install works without, which a project may not have
+var optionalLockfiles = map[string]bool{
+	"yarn.lock": true, "pnpm-lock.yaml": true, "poetry.lock": true, "Pipfile.lock": true,
+	"go.sum": true, "Gemfile.lock": true, "Cargo.lock": true,
+}
+
+// manifestCopies groups the manifests not already copied earlier in the
+// stage by directory, keeping their order. COPY fails on a missing source
+// that is not a pattern, so an optional lockfile is dropped when the build
+// context lacks it, and copied as a pattern when there is no context to look
+// at.
+func manifestCopies(df *parser.ParsedDockerfile, stage *parser.Stage, copyIdx int, manifests []string) []manifestGroup {
+	groups := make([]manifestGroup, 0)
+	for _, file := range manifests {
+		if alreadyCopied(stage.Instructions[:copyIdx], file) {
+			continue
+		}
+		if optionalLockfiles[path.Base(file)] {
+			if ctx := df.ParseOptions.BuildContext; ctx != "" {
+				if _, err := os.Stat(filepath.Join(ctx, filepath.FromSlash(file))); err != nil {
+					continue
+				}
+			} else {
+				file += "*"
+			}
+		}
+		dir := path.Dir(file)
+		if dir == "." {
+			dir = ""
+		} else {
+			dir += "/"
+		}
+
+		found := false
+		for i := range groups {
+			if groups[i].dir == dir {
+				groups[i].files = append(groups[i].files, file)
+				found = true
+			}
+		}
+		if !found {
+			groups = append(groups, manifestGroup{dir: dir, files: []string{file}})
+		}
+	}
+	return groups
+}
+
+// alreadyCopied reports whether one of the instructions copies file from the
+// build context. A manifest pattern such as package*.json only counts as
+// copied when an earlier source is that same pattern, since a source like
+// package.json leaves the lockfile it also stands for behind.
+func alreadyCopied(instructions []parser.Instruction, file string) bool {
+	literal := !strings.ContainsAny(file, "*?[")
+	for _, inst := range instructions {
+		if (inst.Command != "COPY" && inst.Command != "ADD") || inst.Flags["from"] != "" || len(inst.Args) < 2 {
+			continue
+		}
+		for _, src := range inst.Args[:len(inst.Args)-1] {
+			src = strings.TrimPrefix(src, "./")
+			if src == file {
+				return true
+			}
+			if !literal {
+				continue
+			}
+			if src == path.Base(file) {
+				return true
+			}
+			if ok, _ := path.Match(src, file); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ownershipFlags repeats the --chown and --chmod flags of a COPY
+func ownershipFlags(inst *parser.Instruction) string {
+	flags := ""
+	for _, name := range []string{"chown", "chmod"} {
+		if value, ok := inst.Flags[name]; ok {
+			flags += " --" + name + "=" + value
+		}
+	}
+	return flags
+}
+
+// installSteps splits a script into the commands joined by && and ;
+func installSteps(script *shell.Script) ([]installStep, bool) {
+	steps := make([]installStep, 0)
+	var flatten func(stmt *shell.Stmt, joiner string) bool
+	flatten = func(stmt *shell.Stmt, joiner string) bool {
+		if stmt.Background {
+			return false
+		}
+		if bin, ok := stmt.Cmd.(*shell.BinaryCmd); ok && bin.Op == shell.AndOp && !stmt.Negated && len(stmt.Redirs) == 0 {
+			return flatten(bin.X, joiner) && flatten(bin.Y, "&&")
+		}
+		step := installStep{stmt: stmt, joiner: joiner}
+		for _, call := range shell.Calls(stmt) {
+			if c, ok := newCommand(call, script, false); ok {
+				step.commands = append(step.commands, c)
+			}
+		}
+		steps = append(steps, step)
+		return true
+	}
+
+	for i, stmt := range script.Stmts {
+		joiner := ";"
+		if i == 0 {
+			joiner = ""
+		}
+		if !flatten(stmt, joiner) {
+			return nil, false
+		}
+	}
+	return steps, true
+}
+
+// isCleanupStep reports whether a step only removes files or caches
+func isCleanupStep(step installStep) bool {
+	if len(step.commands) == 0 {
+		return false
+	}
+	for _, c := range step.commands {
+		sub := c.subcommand()
+		if c.name != "rm" && sub != "cache" && sub != "clean" {
+			return false
+		}
+	}
+	return true
+}
+
+// stepsText renders steps on one line with their original operators
+func stepsText(script *shell.Script, steps []installStep) string {
+	var b strings.Builder
+	for i, step := range steps {
+		if i > 0 {
+			if step.joiner == ";" {
+				b.WriteString("; ")
+			} else {
+				b.WriteString(" && ")
+			}
+		}
+		b.WriteString(strings.TrimSpace(script.Text(step.stmt)))
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package rules
+
+import "testing"
+
+func TestCacheOrder(t *testing.T) {
+	runRuleTests(t, "cache-order", []ruleTest{
+		{
+			name: "npm install after the context copy",
+			dockerfile: `FROM node:20
+WORKDIR /app
+COPY . .
+RUN npm ci
+CMD ["node", "server.js"]`,
+			want: []string{"npm dependencies are installed after COPY copies the whole build context (line 3)"},
+			fixed: `FROM node:20
+WORKDIR /app
+COPY package*.json ./
+RUN npm ci
+COPY . .
+CMD ["node", "server.js"]`,
+		},
+		{
+			name: "install followed by a build is split",
+			dockerfile: `FROM node:20
+WORKDIR /app
+COPY . .
+RUN npm ci && npm run build`,
+			want: []string{"copy package*.json first"},
+			fixed: `FROM node:20
+WORKDIR /app
+COPY package*.json ./
+RUN npm ci
+COPY . .
+RUN npm run build`,
+		},
+		{
+			name: "package.json alone does not stand for package*.json",
+			dockerfile: `FROM node:20
+WORKDIR /app
+COPY package.json ./
+COPY . .
+RUN npm ci`,
+			want: []string{"copy package*.json first"},
+			fixed: `FROM node:20
+WORKDIR /app
+COPY package.json ./
+COPY package*.json ./
+RUN npm ci
+COPY . .`,
+		},
+		{
+			name: "manifests copied verbatim earlier",
+			dockerfile: `FROM node:20
+WORKDIR /app
+COPY package*.json ./
+COPY . .
+RUN npm ci`,
+			want: []string{"run the install before it, since the manifests are already copied"},
+			fixed: `FROM node:20
+WORKDIR /app
+COPY package*.json ./
+RUN npm ci
+COPY . .`,
+		},
+		{
+			name: "optional lockfile without a build context is a pattern",
+			dockerfile: `FROM golang:1.22
+WORKDIR /src
+COPY . .
+RUN go mod download`,
+			want: []string{"copy go.mod, go.sum* first"},
+			fixed: `FROM golang:1.22
+WORKDIR /src
+COPY go.mod go.sum* ./
+RUN go mod download
+COPY . .`,
+		},
+		{
+			name: "optional lockfile missing from the build context",
+			dockerfile: `FROM ruby:3.3
+WORKDIR /app
+COPY . .
+RUN bundle install`,
+			context: map[string]string{"Gemfile": "source 'https://rubygems.org'\n"},
+			want:    []string{"copy Gemfile first"},
+			fixed: `FROM ruby:3.3
+WORKDIR /app
+COPY Gemfile ./
+RUN bundle install
+COPY . .`,
+		},
+		{
+			name: "lockfile present in the build context",
+			dockerfile: `FROM node:20
+WORKDIR /app
+COPY . .
+RUN yarn install --frozen-lockfile`,
+			context: map[string]string{"package.json": "{}", "yarn.lock": ""},
+			want:    []string{"copy package.json, yarn.lock first"},
+			fixed: `FROM node:20
+WORKDIR /app
+COPY package.json yarn.lock ./
+RUN yarn install --frozen-lockfile
+COPY . .`,
+		},
+		{
+			name: "pip requirements keep their directory",
+			dockerfile: `FROM python:3.12
+WORKDIR /app
+COPY . .
+RUN pip install -r requirements/base.txt`,
+			want: []string{"pip dependencies"},
+			fixed: `FROM python:3.12
+WORKDIR /app
+COPY requirements/base.txt ./requirements/
+RUN pip install -r requirements/base.txt
+COPY . .`,
+		},
+		{
+			name: "installing the project itself needs the source",
+			dockerfile: `FROM python:3.12
+WORKDIR /app
+COPY . .
+RUN pip install .`,
+		},
+		{
+			name: "install before the context copy",
+			dockerfile: `FROM node:20
+WORKDIR /app
+COPY package*.json ./
+RUN npm ci
+COPY . .`,
+		},
+	})
+}
@@ -0,0 +1,391 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(chownLayerRule{})
+}
+
+// Modes COPY --chmod accepts
+var octalMode = regexp.MustCompile(`^[0-7]{3,4}$`)
+
+// chownLayerRule flags RUN chown and chmod over files an earlier COPY or
+// ADD of the stage put in place. Changing metadata copies every file into
+// a new layer, so the image carries them twice.
+type chownLayerRule struct{}
+
+func (chownLayerRule) ID() string { return "chown-after-copy" }
+
+func (chownLayerRule) Description() string {
+	return "Set ownership and permissions with COPY --chown/--chmod instead of a later RUN"
+}
+
+func (chownLayerRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+// copyTarget is a COPY or ADD and the absolute path it writes to
+type copyTarget struct {
+	inst  *parser.Instruction
+	index int
+	dest  string
+	dir   bool // Whether dest is a directory holding the sources
+}
+
+// metadataChange is a chown or chmod over copied files
+type metadataChange struct {
+	cmd       command
+	flag      string // COPY flag with the same effect, chown or chmod
+	value     string // Owner or mode
+	recursive bool
+	paths     []string // Absolute operands
+}
+
+func (r chownLayerRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+
+	for _, stage := range df.Stages {
+		copies := make([]copyTarget, 0)
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			switch inst.Command {
+			case "COPY", "ADD":
+				if target, ok := newCopyTarget(stage, i); ok {
+					copies = append(copies, target)
+				}
+			case "RUN":
+				if w, ok := r.checkRun(df, lines, stage, i, copies); ok {
+					warnings = append(warnings, w)
+				}
+			}
+		}
+	}
+
+	return warnings
+}
+
+// checkRun reports the chown and chmod commands of RUN idx that touch files
+// of earlier copies
+func (r chownLayerRule) checkRun(df *parser.ParsedDockerfile, lines []string, stage *parser.Stage, idx int, copies []copyTarget) (parser.Warning, bool) {
+	inst := &stage.Instructions[idx]
+	commands := instructionCommands(df, inst)
+	workdir := workdirAt(stage, idx)
+
+	changes := make([]metadataChange, 0)
+	touched := make(map[*parser.Instruction]copyTarget)
+	covered := make(map[*parser.Instruction]bool) // Copies whose every file is rewritten
+	for _, c := range commands {
+		change, ok := newMetadataChange(c, workdir)
+		if !ok {
+			continue
+		}
+		hit := false
+		for _, target := range copies {
+			for _, p := range change.paths {
+				// Without -R only the named path changes, which for a
+				// directory copy is not a copied file
+				switch {
+				case !change.recursive:
+					if p == target.dest && !target.dir {
+						covered[target.inst] = true
+						touched[target.inst], hit = target, true
+					}
+				case p == target.dest || isUnder(target.dest, p):
+					covered[target.inst] = true
+					touched[target.inst], hit = target, true
+				case isUnder(p, target.dest) && target.dir:
+					touched[target.inst], hit = target, true
+				}
+			}
+		}
+		if hit {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) == 0 {
+		return parser.Warning{}, false
+	}
+
+	targets := make([]copyTarget, 0, len(touched))
+	for _, target := range touched {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].index < targets[j].index })
+
+	names := make([]string, 0, len(changes))
+	for _, change := range changes {
+		if !containsString(names, change.cmd.name) {
+			names = append(names, change.cmd.name)
+		}
+	}
+	copyLines := make([]string, 0, len(targets))
+	for _, target := range targets {
+		copyLines = append(copyLines, fmt.Sprintf("%d", target.inst.Range.Start.Line))
+	}
+
+	where := "line " + copyLines[0]
+	if len(copyLines) > 1 {
+		where = "lines " + strings.Join(copyLines, ", ")
+	}
+	msg := fmt.Sprintf("RUN %s duplicates the files copied at %s into a new layer",
+		strings.Join(names, " and "), where)
+	if bytes, ok := duplicatedBytes(df, targets, covered); ok {
+		msg += fmt.Sprintf(", about %s", formatBytes(bytes))
+	}
+	msg += "; use COPY --" + strings.Join(names, " and --") + " instead"
+
+	w := newWarning(r, inst, msg)
+	w.Position = changes[0].cmd.position()
+	for _, target := range targets {
+		w.Related = append(w.Related, parser.RelatedLocation{
+			Position: target.inst.Range.Start,
+			Message:  target.inst.Command + " that put the files in place",
+		})
+	}
+	if len(changes) == len(commands) && inst.Heredoc == nil {
+		w.Fix = foldMetadataFix(df, lines, stage, idx, changes, copies)
+	}
+	return w, true
+}
+
+// newCopyTarget resolves the destination of COPY or ADD idx
+func newCopyTarget(stage *parser.Stage, idx int) (copyTarget, bool) {
+	inst := &stage.Instructions[idx]
+	if inst.Heredoc != nil || len(inst.Args) < 2 {
+		return copyTarget{}, false
+	}
+	dest := strings.Trim(inst.Args[len(inst.Args)-1], `[]",`)
+	if dest == "" || strings.Contains(dest, "$") {
+		return copyTarget{}, false
+	}
+	src := strings.Trim(inst.Args[0], `[]",`)
+	dir := strings.HasSuffix(dest, "/") || len(inst.Args) > 2 || dest == "." || !looksLikeFile(src)
+	if !path.IsAbs(dest) {
+		dest = path.Join(workdirAt(stage, idx), dest)
+	}
+	return copyTarget{inst: inst, index: idx, dest: path.Clean(dest), dir: dir}, true
+}
+
+// looksLikeFile reports whether a copy source names a single file; sources
+// without an extension are most likely directories
+func looksLikeFile(src string) bool {
+	base := path.Base(src)
+	return !strings.HasSuffix(src, "/") && !strings.ContainsAny(src, "*?[") &&
+		base != "." && base != "/" && path.Ext(base) != ""
+}
+
+// newMetadataChange reads a chown or chmod command. Options other than
+// the recursive flag make it unsuitable for folding and are rejected.
+func newMetadataChange(c command, workdir string) (metadataChange, bool) {
+	if c.name != "chown" && c.name != "chmod" {
+		return metadataChange{}, false
+	}
+	change := metadataChange{cmd: c, flag: c.name, value: c.subcommand()}
+	for _, arg := range c.args {
+		switch {
+		case arg == "-R" || arg == "--recursive":
+			change.recursive = true
+		case strings.HasPrefix(arg, "-"):
+			if strings.HasPrefix(arg, "--reference") || strings.HasPrefix(arg, "--from") {
+				return metadataChange{}, false
+			}
+		}
+	}
+	if change.value == "" || strings.Contains(change.value, "$") {
+		return metadataChange{}, false
+	}
+	for _, operand := range c.operands() {
+		if strings.ContainsAny(operand, "$*?") {
+			return metadataChange{}, false
+		}
+		if !path.IsAbs(operand) {
+			operand = path.Join(workdir, operand)
+		}
+		change.paths = append(change.paths, path.Clean(operand))
+	}
+	return change, len(change.paths) > 0
+}
+
+// foldable reports whether the change means the same as the COPY flag
+func (m metadataChange) foldable() bool {
+	if m.flag == "chmod" {
+		return octalMode.MatchString(m.value)
+	}
+	// user.group is a deprecated chown spelling COPY does not accept
+	return !strings.Contains(m.value, ".") || strings.Contains(m.value, ":")
+}
+
+// foldMetadataFix moves the chown and chmod of RUN idx onto the copies that
+// created the files and deletes the RUN. It applies only when every path is
+// exactly a copy destination, the change covers all copied files, and no
+// other instruction wrote there: a directory that exists before the COPY
+// keeps its owner and mode, as do files other instructions put in it.
+func foldMetadataFix(df *parser.ParsedDockerfile, lines []string, stage *parser.Stage, idx int, changes []metadataChange, copies []copyTarget) *parser.Fix {
+	flags := make(map[*parser.Instruction]map[string]string)
+	order := make([]copyTarget, 0)
+	for _, change := range changes {
+		if !change.foldable() {
+			return nil
+		}
+		for _, p := range change.paths {
+			target, ok := lastCopyTo(copies, p)
+			if !ok || (target.dir && !change.recursive) || runsBetween(stage, target.index, idx) ||
+				writtenElsewhere(df, stage, target.index, idx, p) {
+				return nil
+			}
+			if flags[target.inst] == nil {
+				flags[target.inst] = make(map[string]string)
+				order = append(order, target)
+			}
+			if prev, ok := flags[target.inst][change.flag]; ok && prev != change.value {
+				return nil
+			}
+			flags[target.inst][change.flag] = change.value
+		}
+	}
+
+	fix := &parser.Fix{Description: "Set ownership and mode in COPY and remove the RUN"}
+	for _, target := range order {
+		first := target.inst.Range.Start.Line
+		text := lineText(lines, first)
+		keyword := strings.Index(strings.ToUpper(text), target.inst.Command)
+		if keyword < 0 {
+			return nil
+		}
+		added := ""
+		for _, name := range []string{"chown", "chmod"} {
+			value, ok := flags[target.inst][name]
+			if !ok {
+				continue
+			}
+			if existing, set := target.inst.Flags[name]; set {
+				if existing != value {
+					return nil
+				}
+				continue
+			}
+			added += " --" + name + "=" + value
+		}
+		at := keyword + len(target.inst.Command)
+		fix.Edits = append(fix.Edits, replaceLines(first, first, text[:at]+added+text[at:]))
+	}
+	run := &stage.Instructions[idx]
+	fix.Edits = append(fix.Edits, deleteLines(run.Range.Start.Line, instructionEndLine(run)))
+	return fix
+}
+
+// lastCopyTo returns the latest copy whose destination is p
+func lastCopyTo(copies []copyTarget, p string) (copyTarget, bool) {
+	for i := len(copies) - 1; i >= 0; i-- {
+		if copies[i].dest == p {
+			return copies[i], true
+		}
+	}
+	return copyTarget{}, false
+}
+
+// writtenElsewhere reports whether an instruction other than the copy at
+// copyIdx, before the RUN at runIdx or in the stages the stage is built
+// FROM, may have created p or written under it: a WORKDIR, another copy,
+// or a RUN that names the path or works inside it
+func writtenElsewhere(df *parser.ParsedDockerfile, stage *parser.Stage, copyIdx, runIdx int, p string) bool {
+	inside := func(dir string) bool { return dir == p || isUnder(dir, p) }
+	for s, limit := stage, runIdx; s != nil; s = s.BaseStage {
+		for i := 0; i < limit; i++ {
+			inst := &s.Instructions[i]
+			if s == stage && i == copyIdx {
+				continue
+			}
+			switch inst.Command {
+			case "WORKDIR":
+				if inside(workdirAt(s, i+1)) {
+					return true
+				}
+			case "COPY", "ADD":
+				if target, ok := newCopyTarget(s, i); !ok || inside(target.dest) {
+					return true
+				}
+			case "RUN":
+				if inside(workdirAt(s, i)) {
+					return true
+				}
+				for _, c := range instructionCommands(df, inst) {
+					if c.mentions(p) {
+						return true
+					}
+				}
+			}
+		}
+		if s.BaseStage != nil {
+			limit = len(s.BaseStage.Instructions)
+		}
+	}
+	return false
+}
+
+// runsBetween reports whether a RUN lies between instructions from and to
+func runsBetween(stage *parser.Stage, from, to int) bool {
+	for i := from + 1; i < to; i++ {
+		if stage.Instructions[i].Command == "RUN" {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicatedBytes measures the copies whose every file is rewritten, when
+// the build context is available
+func duplicatedBytes(df *parser.ParsedDockerfile, targets []copyTarget, covered map[*parser.Instruction]bool) (int64, bool) {
+	ctx := df.ParseOptions.BuildContext
+	if ctx == "" {
+		return 0, false
+	}
+	var total int64
+	measured := false
+	for _, target := range targets {
+		inst := target.inst
+		if !covered[inst] || inst.Flags["from"] != "" {
+			continue
+		}
+		sources := make([]string, 0, len(inst.Args)-1)
+		for _, src := range inst.Args[:len(inst.Args)-1] {
+			src = strings.Trim(src, `[]",`)
+			if kind := classifyAddSource(src); inst.Command == "ADD" && (kind == addRemoteURL || kind == addGitSource) {
+				continue
+			}
+			sources = append(sources, src)
+		}
+		bytes, err := contextBytes(ctx, sources)
+		if err != nil {
+			return 0, false
+		}
+		total += bytes
+		measured = true
+	}
+	return total, measured
+}
+
+// isUnder reports whether p lies inside dir
+func isUnder(p, dir string) bool {
+	return (dir == "/" && p != "/") || strings.HasPrefix(p, dir+"/")
+}
+
+// formatBytes renders a size for messages
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
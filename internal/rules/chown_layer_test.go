@@ -0,0 +1,59 @@
+package rules
+
+import "testing"
+
+func TestChownAfterCopy(t *testing.T) {
+	runRuleTests(t, "chown-after-copy", []ruleTest{
+		{
+			name: "recursive chown of a copied directory",
+			dockerfile: `FROM node:20
+COPY . /app
+RUN chown -R node:node /app`,
+			want: []string{"RUN chown duplicates the files copied at line 2 into a new layer; use COPY --chown instead"},
+			fixed: `FROM node:20
+COPY --chown=node:node . /app`,
+		},
+		{
+			name: "chmod of a copied file",
+			dockerfile: `FROM alpine
+COPY entrypoint.sh /entrypoint.sh
+RUN chmod 755 /entrypoint.sh`,
+			want: []string{"RUN chmod duplicates the files copied at line 2"},
+			fixed: `FROM alpine
+COPY --chmod=755 entrypoint.sh /entrypoint.sh`,
+		},
+		{
+			name: "chown and chmod together",
+			dockerfile: `FROM alpine
+COPY app /srv/app
+RUN chown -R app:app /srv/app && chmod -R 750 /srv/app`,
+			want: []string{"RUN chown and chmod duplicate"},
+			fixed: `FROM alpine
+COPY --chown=app:app --chmod=750 app /srv/app`,
+		},
+		{
+			name: "non-recursive change of the copy's target directory",
+			dockerfile: `FROM alpine AS build
+RUN mkdir /out
+FROM alpine
+COPY --from=build /out /app
+RUN chmod 755 /app
+COPY start.sh /opt/app/
+RUN chown app /opt/app`,
+		},
+		{
+			name: "path written by another instruction as well",
+			dockerfile: `FROM alpine
+COPY config /etc/app
+RUN echo extra > /etc/app/extra.conf
+RUN chown -R app /etc/app`,
+			want: []string{"RUN chown duplicates the files copied at line 2"},
+		},
+		{
+			name: "chown of files the stage did not copy",
+			dockerfile: `FROM alpine
+COPY app /srv/app
+RUN chown -R app /var/log`,
+		},
+	})
+}
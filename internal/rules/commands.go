@@ -0,0 +1,167 @@
+package rules
+
+import (
+	"path"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+	"github.com/yourusername/dockerfile-parser/internal/shell"
+)
+
+// command is a simple command from an instruction's shell code, with its
+// words reduced to text
+type command struct {
+	call    *shell.CallExpr
+	script  *shell.Script
+	name    string   // Program name without directory or sudo/env wrappers
+	args    []string // Words after the program name
+	heredoc bool     // Whether the command comes from a heredoc body
+}
+
+// Wrappers that run the command given in their arguments
+var commandWrappers = map[string]bool{
+	"sudo":      true,
+	"env":       true,
+	"nice":      true,
+	"nohup":     true,
+	"time":      true,
+	"command":   true,
+	"exec":      true,
+	"tini":      true,
+	"dumb-init": true,
+}
+
+// instructionCommands returns the simple commands run by inst, in source order
+func instructionCommands(df *parser.ParsedDockerfile, inst *parser.Instruction) []command {
+	commands := make([]command, 0)
+	for i, script := range shell.Scripts(df, inst) {
+		// Scripts puts the heredoc body after the command
+		heredoc := i > 0
+		for _, call := range shell.Calls(script) {
+			if c, ok := newCommand(call, script, heredoc); ok {
+				commands = append(commands, c)
+			}
+		}
+	}
+	return commands
+}
+
+// newCommand resolves wrappers such as "sudo -E apt-get" and "python -m pip"
+func newCommand(call *shell.CallExpr, script *shell.Script, heredoc bool) (command, bool) {
+	words := make([]string, 0, len(call.Args))
+	for _, arg := range call.Args {
+		words = append(words, arg.Text())
+	}
+	c, ok := commandFromWords(words)
+	if !ok {
+		return command{}, false
+	}
+	c.call, c.script, c.heredoc = call, script, heredoc
+	return c, true
+}
+
+// commandFromWords resolves the wrappers of a command given as words, such
+// as the elements of an exec-form CMD. The command has no source position.
+func commandFromWords(words []string) (command, bool) {
+	for len(words) > 0 && commandWrappers[path.Base(words[0])] {
+		words = words[1:]
+		// Skip wrapper options and env assignments
+		for len(words) > 0 && (strings.HasPrefix(words[0], "-") || strings.Contains(words[0], "=")) {
+			words = words[1:]
+		}
+	}
+	if len(words) == 0 {
+		return command{}, false
+	}
+
+	name := path.Base(words[0])
+	args := words[1:]
+	if strings.HasPrefix(name, "python") && len(args) >= 2 && args[0] == "-m" {
+		name = args[1]
+		args = args[2:]
+	}
+
+	return command{name: name, args: args}, true
+}
+
+// hasFlag reports whether any of the flags appears among the arguments,
+// either alone or as --flag=value
+func (c command) hasFlag(flags ...string) bool {
+	for _, arg := range c.args {
+		for _, flag := range flags {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasShortFlag reports whether a single-letter flag appears, including in
+// combined form such as -qy
+func (c command) hasShortFlag(letter byte) bool {
+	for _, arg := range c.args {
+		if len(arg) > 1 && arg[0] == '-' && arg[1] != '-' && strings.IndexByte(arg[1:], letter) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// subcommand returns the first argument that is not an option
+func (c command) subcommand() string {
+	for _, arg := range c.args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
+
+// operands returns the arguments after the subcommand that are not options
+func (c command) operands() []string {
+	operands := make([]string, 0)
+	seenSub := false
+	for _, arg := range c.args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if !seenSub {
+			seenSub = true
+			continue
+		}
+		operands = append(operands, arg)
+	}
+	return operands
+}
+
+// mentions reports whether any argument contains substr
+func (c command) mentions(substr string) bool {
+	for _, arg := range c.args {
+		if strings.Contains(arg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// position returns the Dockerfile position of the command
+func (c command) position() parser.Position {
+	return c.script.Position(c.call.Pos())
+}
+
+// wordEnd returns the Dockerfile position just after word i of the call,
+// wrappers included
+func (c command) wordEnd(i int) parser.Position {
+	return c.script.Position(c.call.Args[i].End())
+}
+
+// argIndex returns the index in call.Args of the first word equal to value
+func (c command) argIndex(value string) int {
+	for i, arg := range c.call.Args {
+		if arg.Text() == value {
+			return i
+		}
+	}
+	return -1
+}
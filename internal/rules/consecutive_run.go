@@ -0,0 +1,346 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+	"github.com/yourusername/dockerfile-parser/internal/shell"
+)
+
+func init() {
+	Register(consecutiveRunRule{})
+}
+
+// consecutiveRunRule finds RUN instructions in a stage that could share one
+// layer and offers to join them with &&
+type consecutiveRunRule struct{}
+
+func (consecutiveRunRule) ID() string { return "consecutive-run" }
+
+func (consecutiveRunRule) Description() string {
+	return "Consecutive RUN instructions each create a layer and can be merged"
+}
+
+func (consecutiveRunRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+// Instructions that only set image metadata; RUNs on either side of them can
+// be merged without changing what the commands see
+var runNeutralInstructions = map[string]bool{
+	"LABEL":       true,
+	"EXPOSE":      true,
+	"MAINTAINER":  true,
+	"STOPSIGNAL":  true,
+	"HEALTHCHECK": true,
+	"CMD":         true,
+	"ENTRYPOINT":  true,
+}
+
+// Shell builtins whose effect would leak into later commands once merged
+var shellStateCommands = map[string]bool{
+	"cd":     true,
+	"pushd":  true,
+	"popd":   true,
+	"export": true,
+	"unset":  true,
+	"set":    true,
+	"shopt":  true,
+	"source": true,
+	".":      true,
+	"umask":  true,
+	"ulimit": true,
+	"alias":  true,
+	"exit":   true,
+	"exec":   true,
+}
+
+func (r consecutiveRunRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+
+	for _, stage := range df.Stages {
+		group := make([]*parser.Instruction, 0)
+		flush := func() {
+			if len(group) >= 2 {
+				warnings = append(warnings, r.warn(df, lines, group))
+			}
+			group = group[:0]
+		}
+
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			switch {
+			case inst.Command == "RUN":
+				if !mergeableRun(lines, inst) {
+					flush()
+					continue
+				}
+				if len(group) > 0 {
+					prev := group[len(group)-1]
+					if !sameRunFlags(group[0], inst) || !runLeavesNoState(lines, prev) || !runGroupable(df, lines, inst) {
+						flush()
+					}
+				}
+				group = append(group, inst)
+			case runNeutralInstructions[inst.Command]:
+				// Metadata does not affect the RUNs around it
+			default:
+				flush()
+			}
+		}
+		flush()
+	}
+
+	return warnings
+}
+
+func (r consecutiveRunRule) warn(df *parser.ParsedDockerfile, lines []string, group []*parser.Instruction) parser.Warning {
+	lineNumbers := make([]string, 0, len(group))
+	for _, inst := range group {
+		lineNumbers = append(lineNumbers, fmt.Sprint(inst.Range.Start.Line))
+	}
+
+	w := newWarning(r, group[0], fmt.Sprintf(
+		"%d consecutive RUN instructions create %d layers; merge them into a single RUN",
+		len(group), len(group)))
+	w.Fix = &parser.Fix{
+		Description: "Merge RUN instructions at lines " + strings.Join(lineNumbers, ", ") + " with &&",
+		Edits:       mergeRunEdits(df, lines, group),
+	}
+	return w
+}
+
+// mergeableRun reports whether inst is a shell-form RUN whose source can be
+// spliced into a command list
+func mergeableRun(lines []string, inst *parser.Instruction) bool {
+	if inst.JSONForm || inst.Heredoc != nil || len(inst.Args) == 0 {
+		return false
+	}
+	return strings.TrimSpace(runBodyLines(lines, inst)[0].text) != ""
+}
+
+// sameRunFlags compares --mount, --network and --security settings
+func sameRunFlags(a, b *parser.Instruction) bool {
+	if len(a.Flags) != len(b.Flags) {
+		return false
+	}
+	for k, v := range a.Flags {
+		if other, ok := b.Flags[k]; !ok || other != v {
+			return false
+		}
+	}
+	return true
+}
+
+// runLeavesNoState reports whether later commands can safely follow inst in
+// the same shell: no directory or environment changes, no early exit, no
+// trailing background job and no shell comment that would swallow them
+func runLeavesNoState(lines []string, inst *parser.Instruction) bool {
+	command := strings.TrimSpace(inst.Args[0])
+	if strings.HasSuffix(command, "&") && !strings.HasSuffix(command, "&&") {
+		return false
+	}
+	for _, word := range commandNames(command) {
+		if shellStateCommands[word] {
+			return false
+		}
+	}
+	for _, line := range runBodyLines(lines, inst) {
+		if !line.comment && strings.Contains(line.text, " #") {
+			return false
+		}
+	}
+	return true
+}
+
+// runNeedsGroup reports whether the commands of inst must be wrapped in
+// { ...; } to follow && as a unit. A top-level ||, ; or & would otherwise
+// bind to the commands merged before it and let their failures pass.
+func runNeedsGroup(df *parser.ParsedDockerfile, inst *parser.Instruction) bool {
+	script, err := shell.ParseInstruction(df, inst)
+	if err != nil || len(script.Stmts) != 1 {
+		return true
+	}
+	return script.Stmts[0].Background || listHasOr(script.Stmts[0])
+}
+
+// listHasOr reports whether a && and || list contains ||, outside subshells
+// and braces
+func listHasOr(stmt *shell.Stmt) bool {
+	bin, ok := stmt.Cmd.(*shell.BinaryCmd)
+	if !ok {
+		return false
+	}
+	switch bin.Op {
+	case shell.OrOp:
+		return true
+	case shell.AndOp:
+		return listHasOr(bin.X) || listHasOr(bin.Y)
+	}
+	return false
+}
+
+// runGroupable reports whether inst can join a merged RUN: either it needs
+// no braces, or braces can close after its last line, which a trailing
+// comment or background job prevents
+func runGroupable(df *parser.ParsedDockerfile, lines []string, inst *parser.Instruction) bool {
+	if !runNeedsGroup(df, inst) {
+		return true
+	}
+	return runLeavesNoState(lines, inst)
+}
+
+// commandNames returns the first word of every simple command in a shell
+// command string. It is a lexical approximation that ignores quoting.
+func commandNames(command string) []string {
+	for _, sep := range []string{"&&", "||", ";", "|", "(", ")", "{", "}"} {
+		command = strings.ReplaceAll(command, sep, "\n")
+	}
+
+	names := make([]string, 0)
+	for _, part := range strings.Split(command, "\n") {
+		for _, word := range strings.Fields(part) {
+			// Skip leading VAR=value assignments
+			if strings.Contains(word, "=") && !strings.HasPrefix(word, "=") {
+				continue
+			}
+			names = append(names, word)
+			break
+		}
+	}
+	return names
+}
+
+// runLine is one source line of a RUN command body
+type runLine struct {
+	text    string // Line text without the continuation marker
+	comment bool   // Dockerfile comment inside the continuation
+}
+
+// runBodyLines returns the command body of a RUN instruction: the first line
+// stripped of the RUN keyword and its flags, continuation markers removed and
+// blank continuation lines dropped. The result always has at least one line.
+func runBodyLines(lines []string, inst *parser.Instruction) []runLine {
+	body := make([]runLine, 0)
+	end := instructionEndLine(inst)
+
+	for n := inst.Range.Start.Line; n <= end; n++ {
+		text := lineText(lines, n)
+		if n == inst.Range.Start.Line {
+			text = text[runHeaderLength(text):]
+		}
+		if isCommentLine(text) && n != inst.Range.Start.Line {
+			body = append(body, runLine{text: strings.TrimSpace(text), comment: true})
+			continue
+		}
+
+		text = stripContinuation(text)
+		if strings.TrimSpace(text) == "" && n != inst.Range.Start.Line {
+			continue
+		}
+		body = append(body, runLine{text: text})
+	}
+
+	if len(body) == 0 {
+		body = append(body, runLine{})
+	}
+	// The first line may hold only flags, leaving the command on the next line
+	if strings.TrimSpace(body[0].text) == "" && len(body) > 1 {
+		body = body[1:]
+	}
+	return body
+}
+
+// runHeaderLength returns the length of the "RUN --flag=..." prefix of line
+func runHeaderLength(line string) int {
+	i := 0
+	skipSpace := func() {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+	}
+
+	skipSpace()
+	if len(line)-i >= 3 && strings.EqualFold(line[i:i+3], "RUN") {
+		i += 3
+	}
+	for {
+		skipSpace()
+		if !strings.HasPrefix(line[i:], "--") {
+			break
+		}
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+	}
+	return i
+}
+
+// stripContinuation removes a trailing \ or ` continuation marker
+func stripContinuation(line string) string {
+	trimmed := strings.TrimRight(line, " \t")
+	if strings.HasSuffix(trimmed, "\\") || strings.HasSuffix(trimmed, "`") {
+		return strings.TrimRight(trimmed[:len(trimmed)-1], " \t")
+	}
+	return line
+}
+
+// mergeRunEdits rewrites the first RUN of the group to contain every command
+// and deletes the others together with the comments directly above them,
+// which move into the merged instruction
+func mergeRunEdits(df *parser.ParsedDockerfile, lines []string, group []*parser.Instruction) []parser.TextEdit {
+	first := group[0]
+	header := strings.TrimRight(lineText(lines, first.Range.Start.Line)[:runHeaderLength(lineText(lines, first.Range.Start.Line))], " \t")
+
+	merged := make([]runLine, 0)
+	for i, inst := range group {
+		body := runBodyLines(lines, inst)
+		if i == 0 {
+			body[0].text = header + " " + strings.TrimSpace(body[0].text)
+			merged = append(merged, body...)
+			continue
+		}
+
+		above := commentBlockStart(lines, inst.Range.Start.Line)
+		for n := above; n < inst.Range.Start.Line; n++ {
+			merged = append(merged, runLine{text: "    " + strings.TrimSpace(lineText(lines, n)), comment: true})
+		}
+		if runNeedsGroup(df, inst) {
+			body[0].text = "    && { " + strings.TrimSpace(body[0].text)
+			for j := len(body) - 1; j >= 0; j-- {
+				if !body[j].comment {
+					body[j].text = strings.TrimRight(strings.TrimRight(body[j].text, " \t"), ";") + "; }"
+					break
+				}
+			}
+		} else {
+			body[0].text = "    && " + strings.TrimSpace(body[0].text)
+		}
+		merged = append(merged, body...)
+	}
+
+	lastCommand := -1
+	for i, line := range merged {
+		if !line.comment {
+			lastCommand = i
+		}
+	}
+
+	suffix := continuationSuffix(df)
+	out := make([]string, 0, len(merged))
+	for i, line := range merged {
+		text := line.text
+		if !line.comment && i != lastCommand {
+			text += suffix
+		}
+		out = append(out, text)
+	}
+
+	edits := []parser.TextEdit{
+		replaceLines(first.Range.Start.Line, instructionEndLine(first), strings.Join(out, "\n")),
+	}
+	for _, inst := range group[1:] {
+		edits = append(edits, deleteLines(commentBlockStart(lines, inst.Range.Start.Line), instructionEndLine(inst)))
+	}
+	return edits
+}
@@ -0,0 +1,42 @@
+package rules
+
+import "testing"
+
+func TestConsecutiveRun(t *testing.T) {
+	runRuleTests(t, "consecutive-run", []ruleTest{
+		{
+			name: "three RUNs",
+			dockerfile: `FROM debian
+RUN apt-get update
+RUN apt-get install -y curl
+RUN rm -rf /var/lib/apt/lists/*`,
+			want: []string{"3 consecutive RUN instructions create 3 layers"},
+			fixed: `FROM debian
+RUN apt-get update \
+    && apt-get install -y curl \
+    && rm -rf /var/lib/apt/lists/*`,
+		},
+		{
+			name: "an or-list is grouped so && keeps its meaning",
+			dockerfile: `FROM debian
+RUN make
+RUN make test || true`,
+			want: []string{"2 consecutive RUN instructions"},
+			fixed: `FROM debian
+RUN make \
+    && { make test || true; }`,
+		},
+		{
+			name: "a single RUN",
+			dockerfile: `FROM debian
+RUN make`,
+		},
+		{
+			name: "RUNs separated by another instruction",
+			dockerfile: `FROM debian
+RUN make
+WORKDIR /out
+RUN make install`,
+		},
+	})
+}
@@ -0,0 +1,194 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(deadStageRule{})
+}
+
+// deadStageRule reports stages that no built target needs, directly or
+// through FROM, COPY --from and RUN --mount=from
+type deadStageRule struct{}
+
+func (deadStageRule) ID() string { return "unused-stage" }
+
+func (deadStageRule) Description() string {
+	return "Stages that no target depends on should be removed"
+}
+
+func (deadStageRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+func (r deadStageRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	return r.CheckEnv(df, Env{})
+}
+
+func (r deadStageRule) CheckEnv(df *parser.ParsedDockerfile, env Env) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	if len(df.Stages) < 2 {
+		return warnings
+	}
+
+	targets := []*parser.Stage{df.Stages[len(df.Stages)-1]}
+	for _, name := range append([]string{df.ParseOptions.TargetStage}, env.Targets...) {
+		if stage := stageByRef(df, name); stage != nil {
+			targets = append(targets, stage)
+		}
+	}
+	live := reachableStages(df, targets)
+
+	lines := sourceLines(df)
+	numericRefs := usesNumericStageRefs(df)
+	for _, stage := range df.Stages {
+		if live[stage] || len(stage.Instructions) == 0 {
+			continue
+		}
+
+		from := &stage.Instructions[0]
+		msg := fmt.Sprintf("Stage %s is not used by the final stage or any target", stageName(stage))
+		if refs := stageReferences(df, stage); len(refs) > 0 {
+			msg = fmt.Sprintf("Stage %s is only used by unused stages (%s)", stageName(stage), strings.Join(refs, ", "))
+		}
+		w := newWarning(r, from, msg)
+
+		// Removing a stage renumbers the ones after it
+		if !numericRefs {
+			start, end := deadStageSpan(df, lines, stage)
+			w.Fix = &parser.Fix{
+				Description: "Remove stage " + stageName(stage),
+				Edits:       []parser.TextEdit{deleteLines(start, end)},
+			}
+		}
+		warnings = append(warnings, w)
+	}
+
+	return warnings
+}
+
+// reachableStages returns the stages the targets depend on, including the
+// targets themselves
+func reachableStages(df *parser.ParsedDockerfile, targets []*parser.Stage) map[*parser.Stage]bool {
+	live := make(map[*parser.Stage]bool)
+	var visit func(stage *parser.Stage)
+	visit = func(stage *parser.Stage) {
+		if stage == nil || live[stage] {
+			return
+		}
+		live[stage] = true
+		for _, dep := range stageDependencies(df, stage) {
+			visit(dep)
+		}
+	}
+	for _, target := range targets {
+		visit(target)
+	}
+	return live
+}
+
+// stageDependencies returns the stages a stage builds on or reads from
+func stageDependencies(df *parser.ParsedDockerfile, stage *parser.Stage) []*parser.Stage {
+	deps := make([]*parser.Stage, 0)
+	if stage.BaseStage != nil {
+		deps = append(deps, stage.BaseStage)
+	}
+	for i := range stage.Instructions {
+		for _, ref := range stageRefs(&stage.Instructions[i]) {
+			if dep := stageByRef(df, ref); dep != nil {
+				deps = append(deps, dep)
+			}
+		}
+	}
+	return deps
+}
+
+// stageRefs returns the --from values of a COPY, ADD or RUN --mount
+func stageRefs(inst *parser.Instruction) []string {
+	refs := make([]string, 0)
+	switch inst.Command {
+	case "COPY", "ADD":
+		if from, ok := inst.Flags["from"]; ok {
+			refs = append(refs, from)
+		}
+	case "RUN":
+		for _, mount := range strings.Fields(inst.Flags["mount"]) {
+			for _, opt := range strings.Split(mount, ",") {
+				if key, value, ok := strings.Cut(opt, "="); ok && strings.EqualFold(key, "from") {
+					refs = append(refs, value)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// stageByRef resolves a stage name or index; image references and empty
+// names resolve to nil
+func stageByRef(df *parser.ParsedDockerfile, ref string) *parser.Stage {
+	if ref == "" {
+		return nil
+	}
+	for _, stage := range df.Stages {
+		if stage.Name != "" && strings.EqualFold(stage.Name, ref) {
+			return stage
+		}
+	}
+	if n, err := strconv.Atoi(ref); err == nil && n >= 0 && n < len(df.Stages) {
+		return df.Stages[n]
+	}
+	return nil
+}
+
+// stageReferences describes the instructions that refer to stage
+func stageReferences(df *parser.ParsedDockerfile, stage *parser.Stage) []string {
+	refs := make([]string, 0)
+	for _, other := range df.Stages {
+		if other.BaseStage == stage && len(other.Instructions) > 0 {
+			refs = append(refs, fmt.Sprintf("FROM of %s", stageName(other)))
+		}
+		for i := range other.Instructions {
+			inst := &other.Instructions[i]
+			for _, ref := range stageRefs(inst) {
+				if stageByRef(df, ref) == stage {
+					refs = append(refs, fmt.Sprintf("%s --from in %s at line %d", inst.Command, stageName(other), inst.Range.Start.Line))
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// usesNumericStageRefs reports whether any --from names a stage by index
+func usesNumericStageRefs(df *parser.ParsedDockerfile) bool {
+	for _, inst := range allInstructions(df) {
+		for _, ref := range stageRefs(inst) {
+			if _, err := strconv.Atoi(ref); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deadStageSpan returns the lines of a stage, from the comments above its
+// FROM to just before the comments above the next FROM
+func deadStageSpan(df *parser.ParsedDockerfile, lines []string, stage *parser.Stage) (int, int) {
+	start := commentBlockStart(lines, stage.Instructions[0].Range.Start.Line)
+	end := len(lines)
+	if stage.Index+1 < len(df.Stages) && len(df.Stages[stage.Index+1].Instructions) > 0 {
+		end = commentBlockStart(lines, df.Stages[stage.Index+1].Instructions[0].Range.Start.Line) - 1
+	}
+	return start, end
+}
+
+// stageName names a stage in messages
+func stageName(stage *parser.Stage) string {
+	if stage.Name != "" {
+		return stage.Name
+	}
+	return fmt.Sprintf("#%d", stage.Index)
+}
@@ -0,0 +1,53 @@
+package rules
+
+import "testing"
+
+func TestUnusedStage(t *testing.T) {
+	runRuleTests(t, "unused-stage", []ruleTest{
+		{
+			name: "stage nothing uses",
+			dockerfile: `FROM golang:1.22 AS build
+RUN go build -o /app
+FROM alpine AS debug
+RUN apk add --no-cache curl
+FROM alpine
+COPY --from=build /app /app`,
+			want: []string{"Stage debug is not used by the final stage or any target"},
+			fixed: `FROM golang:1.22 AS build
+RUN go build -o /app
+FROM alpine
+COPY --from=build /app /app`,
+		},
+		{
+			name: "stage used only by an unused stage",
+			dockerfile: `FROM alpine AS deps
+RUN apk add --no-cache git
+FROM deps AS tools
+RUN git --version
+FROM alpine
+CMD ["true"]`,
+			want: []string{
+				"Stage deps is only used by unused stages (FROM of tools)",
+				"Stage tools is not used by the final stage or any target",
+			},
+			fixed: `FROM alpine
+CMD ["true"]`,
+		},
+		{
+			name: "stage built as the target",
+			dockerfile: `FROM alpine AS test
+RUN echo test
+FROM alpine
+CMD ["true"]`,
+			target: "test",
+		},
+		{
+			name: "stage named in the environment's targets",
+			dockerfile: `FROM alpine AS test
+RUN echo test
+FROM alpine
+CMD ["true"]`,
+			env: Env{Targets: []string{"test"}},
+		},
+	})
+}
@@ -0,0 +1,347 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(deletedFilesRule{})
+}
+
+// deletedFilesRule flags rm commands that delete files an earlier layer of
+// the stage added. The files stay in that layer, so the image does not
+// shrink.
+type deletedFilesRule struct{}
+
+func (deletedFilesRule) ID() string { return "deleted-in-later-layer" }
+
+func (deletedFilesRule) Description() string {
+	return "Files deleted in a later layer still take up space in the layer that added them"
+}
+
+func (deletedFilesRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+// Build tools whose output makes a directory created in the same RUN worth
+// tracking
+var buildTools = map[string]bool{
+	"make": true, "cmake": true, "ninja": true, "cargo": true, "mvn": true, "gradle": true,
+	"go": true, "npm": true, "yarn": true, "pnpm": true, "dotnet": true, "configure": true,
+}
+
+// artifact is a path an instruction added to the stage's filesystem
+type artifact struct {
+	path    string
+	inst    *parser.Instruction
+	kind    string   // copy, download, archive, clone, build or cache
+	dir     bool     // Whether the path is a directory
+	sources []string // Build context sources of a copy
+}
+
+func (r deletedFilesRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	for _, stage := range df.Stages {
+		artifacts := make([]artifact, 0)
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			switch inst.Command {
+			case "COPY", "ADD":
+				artifacts = append(artifacts, copyArtifacts(stage, i)...)
+			case "RUN":
+				var found []parser.Warning
+				artifacts, found = r.checkRun(df, stage, i, artifacts)
+				warnings = append(warnings, found...)
+			}
+		}
+	}
+
+	return warnings
+}
+
+// checkRun walks the commands of RUN idx in order: rm commands delete
+// tracked artifacts, reporting those from earlier layers, and recognised
+// commands add new ones
+func (r deletedFilesRule) checkRun(df *parser.ParsedDockerfile, stage *parser.Stage, idx int, artifacts []artifact) ([]artifact, []parser.Warning) {
+	inst := &stage.Instructions[idx]
+	warnings := make([]parser.Warning, 0)
+	workdir := workdirAt(stage, idx)
+	commands := instructionCommands(df, inst)
+	reported := make(map[*parser.Instruction]bool)
+
+	for i, c := range commands {
+		if c.name != "rm" {
+			artifacts = append(artifacts, runArtifacts(stage, inst, workdir, c, commands, i)...)
+			workdir = afterCd(c, workdir)
+			continue
+		}
+
+		kept := artifacts[:0]
+		for _, a := range artifacts {
+			removed, whole, target := removedBy(c, workdir, a)
+			if !removed {
+				kept = append(kept, a)
+				continue
+			}
+			// Part of a copied directory may have been created by a later
+			// RUN, so only report files known to come from the context
+			if !whole && a.kind == "copy" && !inContext(df, a, target) {
+				kept = append(kept, a)
+				continue
+			}
+			// Likewise a later RUN working in a cloned, extracted or build
+			// directory may have created what is deleted from it
+			if !whole && a.kind != "copy" && a.kind != "cache" && writtenSince(df, stage, idx, i, a) {
+				kept = append(kept, a)
+				continue
+			}
+			if a.inst != inst && !reported[a.inst] {
+				reported[a.inst] = true
+				warnings = append(warnings, r.wasted(df, inst, c, a, whole, target))
+			}
+			if !whole {
+				kept = append(kept, a)
+			}
+		}
+		artifacts = kept
+	}
+	return artifacts, warnings
+}
+
+// wasted describes one artifact deleted after the layer that added it
+func (r deletedFilesRule) wasted(df *parser.ParsedDockerfile, inst *parser.Instruction, rm command, a artifact, whole bool, target string) parser.Warning {
+	line := a.inst.Range.Start.Line
+	what := a.path
+	if whole && len(a.sources) > 0 && df.ParseOptions.BuildContext != "" {
+		if bytes, err := contextBytes(df.ParseOptions.BuildContext, a.sources); err == nil && bytes > 0 {
+			what += fmt.Sprintf(" (%s)", formatBytes(bytes))
+		}
+	}
+	msg := fmt.Sprintf("rm deletes %s, which %s at line %d added; the files stay in that layer", what, a.inst.Command, line)
+	if !whole {
+		msg = fmt.Sprintf("rm deletes %s, part of %s that %s at line %d added; the files stay in that layer",
+			target, a.path, a.inst.Command, line)
+	}
+
+	switch a.kind {
+	case "copy":
+		if a.dir || len(a.sources) != 1 {
+			msg += "; exclude the files with .dockerignore or copy only what the image needs"
+		} else {
+			msg += fmt.Sprintf("; read it through RUN --mount=type=bind,source=%s,target=%s instead of copying it", a.sources[0], a.path)
+		}
+	case "cache":
+		msg += fmt.Sprintf("; clean the cache in the RUN at line %d or use a cache mount", line)
+	default:
+		msg += fmt.Sprintf("; delete it in the RUN at line %d, or do that work in a builder stage and COPY --from only the result", line)
+	}
+
+	w := newWarning(r, inst, msg)
+	w.Position = rm.position()
+	w.Related = []parser.RelatedLocation{{Position: a.inst.Range.Start, Message: "added here"}}
+	return w
+}
+
+// copyArtifacts returns the paths COPY or ADD idx writes. A named file
+// copied into a directory is tracked as that file, so that removing the
+// directory's other contents is not mistaken for it.
+func copyArtifacts(stage *parser.Stage, idx int) []artifact {
+	target, ok := newCopyTarget(stage, idx)
+	if !ok {
+		return nil
+	}
+	inst := target.inst
+	fromContext := inst.Flags["from"] == ""
+	result := make([]artifact, 0)
+	for _, arg := range inst.Args[:len(inst.Args)-1] {
+		src := strings.Trim(arg, `[]",`)
+		a := artifact{path: target.dest, inst: inst, kind: "copy", dir: target.dir}
+		kind := classifyAddSource(src)
+		switch {
+		case inst.Command == "ADD" && (kind == addRemoteURL || kind == addGitSource):
+			a.kind = "download"
+		case inst.Command == "ADD" && kind == addLocalArchive:
+			a.kind, a.dir = "archive", true
+		case fromContext:
+			a.sources = []string{src}
+		}
+
+		if target.dir && looksLikeFile(src) && a.kind != "archive" {
+			name := path.Base(src)
+			if a.kind == "download" {
+				name = path.Base(strings.SplitN(src, "?", 2)[0])
+			}
+			a.path, a.dir = path.Join(target.dest, name), false
+		}
+		result = append(result, a)
+	}
+	return result
+}
+
+// runArtifacts returns the paths command i of a RUN adds: downloads,
+// extracted archives, cloned repositories, build directories and package
+// manager caches that the RUN does not clean up
+func runArtifacts(stage *parser.Stage, inst *parser.Instruction, workdir string, c command, commands []command, i int) []artifact {
+	result := make([]artifact, 0)
+	add := func(p, kind string, dir bool) {
+		if p != "" && !strings.Contains(p, "$") {
+			result = append(result, artifact{path: resolvePath(workdir, p), inst: inst, kind: kind, dir: dir})
+		}
+	}
+
+	switch c.name {
+	case "curl", "wget":
+		if out := downloadOutput(c); out != "" {
+			add(out, "download", false)
+		} else if url := downloadURL(c); url != "" && (c.name == "wget" || c.hasShortFlag('O') || c.hasFlag("--remote-name")) {
+			if dir := flagValue(c.args, "-P"); c.name == "wget" && dir != "" {
+				add(path.Join(dir, path.Base(url)), "download", false)
+			} else if c.name == "curl" || !c.hasFlag("-qO-", "-O-") {
+				add(path.Base(url), "download", false)
+			}
+		}
+
+	case "tar":
+		if sub := c.subcommand(); c.hasShortFlag('x') || c.hasFlag("--extract") || strings.HasPrefix(sub, "x") {
+			dir := flagValue(c.args, "-C")
+			if dir == "" {
+				dir = flagValue(c.args, "--directory")
+			}
+			add(dir, "archive", true)
+		}
+
+	case "unzip":
+		add(flagValue(c.args, "-d"), "archive", true)
+
+	case "git":
+		if c.subcommand() == "clone" {
+			operands := c.operands()
+			switch len(operands) {
+			case 1:
+				add(strings.TrimSuffix(path.Base(operands[0]), ".git"), "clone", true)
+			case 2:
+				add(operands[1], "clone", true)
+			}
+		}
+
+	case "mkdir":
+		for _, later := range commands[i+1:] {
+			if !buildTools[later.name] {
+				continue
+			}
+			for _, dir := range append([]string{c.subcommand()}, c.operands()...) {
+				add(dir, "build", true)
+			}
+			break
+		}
+
+	default:
+		pm := managerFor(c)
+		if pm == nil || pm.cleaned(c, commands[i+1:]) || cacheMounted(inst, pm) || envDisablesCache(stage, inst, pm) {
+			break
+		}
+		for _, dir := range pm.cacheDirs {
+			result = append(result, artifact{path: dir, inst: inst, kind: "cache", dir: true})
+		}
+	}
+	return result
+}
+
+// removedBy reports whether rm command c deletes a, whether it deletes all
+// of it rather than some of the files inside a directory, and the path
+// the rm names
+func removedBy(c command, workdir string, a artifact) (bool, bool, string) {
+	for _, operand := range c.args {
+		if strings.HasPrefix(operand, "-") || strings.Contains(operand, "$") {
+			continue
+		}
+		target := resolvePath(workdir, operand)
+		contents := strings.HasSuffix(operand, "/*")
+		if contents {
+			target = path.Dir(target)
+		}
+
+		switch {
+		case strings.ContainsAny(target, "*?["):
+			if ok, _ := path.Match(target, a.path); ok {
+				return true, true, target
+			}
+		case a.path == target:
+			return true, !contents || a.dir, target
+		case isUnder(a.path, target):
+			return true, true, target
+		case a.dir && isUnder(target, a.path):
+			return true, false, target
+		}
+	}
+	return false, false, ""
+}
+
+// writtenSince reports whether a RUN after the one that added directory
+// artifact a, or a command before command n of RUN idx, worked inside the
+// directory or named it
+func writtenSince(df *parser.ParsedDockerfile, stage *parser.Stage, idx, n int, a artifact) bool {
+	inside := func(c command, workdir string) bool {
+		return workdir == a.path || isUnder(workdir, a.path) || c.mentions(a.path)
+	}
+	for i := instructionIndex(stage, a.inst) + 1; i <= idx; i++ {
+		inst := &stage.Instructions[i]
+		if inst.Command != "RUN" {
+			continue
+		}
+		workdir := workdirAt(stage, i)
+		for j, c := range instructionCommands(df, inst) {
+			if i == idx && j >= n {
+				break
+			}
+			if inside(c, workdir) {
+				return true
+			}
+			workdir = afterCd(c, workdir)
+		}
+	}
+	return false
+}
+
+// afterCd returns the working directory after command c
+func afterCd(c command, workdir string) string {
+	if c.name == "cd" && len(c.args) > 0 && !strings.Contains(c.args[0], "$") {
+		return resolvePath(workdir, c.args[0])
+	}
+	return workdir
+}
+
+// inContext reports whether the path an rm names inside a copied directory
+// exists in the build context, which is only known when it is available
+func inContext(df *parser.ParsedDockerfile, a artifact, target string) bool {
+	ctx := df.ParseOptions.BuildContext
+	if ctx == "" || len(a.sources) != 1 {
+		return false
+	}
+	rel := strings.TrimPrefix(target, a.path+"/")
+	_, err := os.Stat(filepath.Join(ctx, filepath.FromSlash(path.Join(path.Clean("/"+a.sources[0]), rel))))
+	return err == nil
+}
+
+// downloadURL returns the first URL argument of a download command
+func downloadURL(c command) string {
+	for _, arg := range c.args {
+		if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") || strings.HasPrefix(arg, "ftp://") {
+			return strings.SplitN(arg, "?", 2)[0]
+		}
+	}
+	return ""
+}
+
+// resolvePath makes p absolute against dir
+func resolvePath(dir, p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(dir, p)
+}
@@ -0,0 +1,29 @@
+package rules
+
+import "testing"
+
+func TestDeletedInLaterLayer(t *testing.T) {
+	runRuleTests(t, "deleted-in-later-layer", []ruleTest{
+		{
+			name: "copied archive removed later",
+			dockerfile: `FROM alpine
+COPY big.tar /tmp/big.tar
+RUN tar -xf /tmp/big.tar -C /opt
+RUN rm /tmp/big.tar`,
+			want: []string{"rm deletes /tmp/big.tar, which COPY at line 2 added; the files stay in that layer"},
+		},
+		{
+			name: "download removed in a later RUN",
+			dockerfile: `FROM debian
+RUN curl -fsSL -o /tmp/tool.tgz https://example.com/tool.tgz
+RUN tar -xzf /tmp/tool.tgz -C /opt
+RUN rm -f /tmp/tool.tgz`,
+			want: []string{"/tmp/tool.tgz"},
+		},
+		{
+			name: "removed in the same RUN",
+			dockerfile: `FROM debian
+RUN curl -fsSL -o /tmp/tool.tgz https://example.com/tool.tgz && tar -xzf /tmp/tool.tgz -C /opt && rm /tmp/tool.tgz`,
+		},
+	})
+}
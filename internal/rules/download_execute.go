@@ -0,0 +1,210 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+	"github.com/yourusername/dockerfile-parser/internal/shell"
+)
+
+func init() {
+	Register(downloadExecuteRule{})
+}
+
+// Programs that fetch URLs
+var downloaders = map[string]bool{"curl": true, "wget": true, "fetch": true}
+
+// Programs that execute a script given on stdin, as a file or with -c
+var scriptInterpreters = map[string]bool{
+	"sh": true, "bash": true, "ash": true, "dash": true, "zsh": true, "ksh": true,
+	"python": true, "python2": true, "python3": true, "perl": true, "ruby": true,
+	"node": true, "php": true,
+}
+
+// Programs that verify a download against a checksum or signature
+var downloadVerifiers = map[string]bool{
+	"sha256sum": true, "sha512sum": true, "sha384sum": true, "sha1sum": true, "shasum": true,
+	"gpg": true, "gpgv": true, "cosign": true, "minisign": true, "signify": true,
+}
+
+// downloadExecuteRule flags RUN instructions that run scripts fetched from
+// the network without checking them first, as in curl URL | sh
+type downloadExecuteRule struct{}
+
+func (downloadExecuteRule) ID() string { return "download-execute" }
+
+func (downloadExecuteRule) Description() string {
+	return "Downloaded scripts should be verified against a checksum before they run"
+}
+
+func (downloadExecuteRule) DefaultSeverity() parser.WarnLevel { return parser.WarnHigh }
+
+func (r downloadExecuteRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	for _, inst := range allInstructions(df) {
+		if inst.Command != "RUN" {
+			continue
+		}
+		for _, script := range shell.Scripts(df, inst) {
+			warnings = append(warnings, r.checkScript(inst, script)...)
+		}
+	}
+
+	return warnings
+}
+
+// checkScript looks for downloads piped or substituted into an interpreter,
+// and downloaded files run before any verification
+func (r downloadExecuteRule) checkScript(inst *parser.Instruction, script *shell.Script) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	for _, pipe := range shell.Pipelines(script) {
+		var fetcher string
+		for _, stmt := range shell.PipelineStmts(pipe) {
+			c, ok := stmtCommand(stmt, script)
+			if !ok {
+				continue
+			}
+			if downloaders[c.name] && fetcher == "" {
+				fetcher = c.name
+				continue
+			}
+			if fetcher != "" && scriptInterpreters[c.name] {
+				w := newWarning(r, inst, fmt.Sprintf(
+					"%s output is piped into %s without verification; download to a file, check it with sha256sum -c, then run it",
+					fetcher, c.name))
+				w.Position = script.Position(pipe.Pos())
+				warnings = append(warnings, w)
+				break
+			}
+		}
+	}
+
+	calls := shell.Calls(script)
+	downloaded := make(map[string]string) // Output file to downloader
+	verified := false
+	for _, call := range calls {
+		c, ok := newCommand(call, script, false)
+		if !ok {
+			continue
+		}
+		switch {
+		case downloadVerifiers[c.name] || (c.name == "openssl" && c.subcommand() == "dgst"):
+			verified = true
+
+		case downloaders[c.name]:
+			if out := downloadOutput(c); out != "" {
+				downloaded[out] = c.name
+			}
+
+		case scriptInterpreters[c.name]:
+			if substituted := substitutedDownload(call); substituted != "" {
+				w := newWarning(r, inst, fmt.Sprintf(
+					"%s runs a script fetched by %s without verification; download to a file, check it with sha256sum -c, then run it",
+					c.name, substituted))
+				w.Position = c.position()
+				warnings = append(warnings, w)
+				continue
+			}
+			if verified {
+				continue
+			}
+			if file := firstOperand(c.args); file != "" {
+				if fetcher, ok := downloaded[path.Clean(file)]; ok {
+					warnings = append(warnings, r.unverifiedFile(inst, c, fetcher, file))
+				}
+			}
+
+		default:
+			// ./install.sh after chmod +x
+			if verified || len(call.Args) == 0 {
+				continue
+			}
+			word := call.Args[0].Text()
+			if fetcher, ok := downloaded[path.Clean(word)]; ok && strings.Contains(word, "/") {
+				warnings = append(warnings, r.unverifiedFile(inst, c, fetcher, word))
+			}
+		}
+	}
+
+	return warnings
+}
+
+func (r downloadExecuteRule) unverifiedFile(inst *parser.Instruction, c command, fetcher, file string) parser.Warning {
+	w := newWarning(r, inst, fmt.Sprintf(
+		"%s is downloaded with %s and run without a checksum or signature check; verify it with sha256sum -c first",
+		file, fetcher))
+	w.Position = c.position()
+	w.Level = parser.WarnMedium
+	return w
+}
+
+// stmtCommand returns the simple command of a pipeline element
+func stmtCommand(stmt *shell.Stmt, script *shell.Script) (command, bool) {
+	call, ok := stmt.Cmd.(*shell.CallExpr)
+	if !ok {
+		return command{}, false
+	}
+	return newCommand(call, script, false)
+}
+
+// downloadOutput returns the file a curl or wget command writes, or ""
+// when it writes to stdout or names the file after the URL
+func downloadOutput(c command) string {
+	var out string
+	switch c.name {
+	case "curl":
+		out = flagValue(c.args, "-o")
+		if out == "" {
+			out = flagValue(c.args, "--output")
+		}
+	case "wget":
+		out = flagValue(c.args, "-O")
+		if out == "" {
+			out = flagValue(c.args, "--output-document")
+		}
+	case "fetch":
+		out = flagValue(c.args, "-o")
+	}
+	if out == "-" {
+		return ""
+	}
+	if out != "" {
+		return path.Clean(out)
+	}
+	return ""
+}
+
+// substitutedDownload returns the downloader whose output an interpreter
+// runs through command substitution, as in sh -c "$(curl URL)"
+func substitutedDownload(call *shell.CallExpr) string {
+	fetcher := ""
+	for _, arg := range call.Args[1:] {
+		shell.Walk(arg, func(n shell.Node) bool {
+			subst, ok := n.(*shell.CmdSubst)
+			if !ok || fetcher != "" {
+				return fetcher == ""
+			}
+			for _, inner := range shell.Calls(subst) {
+				if downloaders[path.Base(inner.Name())] {
+					fetcher = path.Base(inner.Name())
+				}
+			}
+			return false
+		})
+	}
+	return fetcher
+}
+
+// firstOperand returns the first argument that is not an option
+func firstOperand(args []string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
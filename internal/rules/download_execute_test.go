@@ -0,0 +1,32 @@
+package rules
+
+import "testing"
+
+func TestDownloadExecute(t *testing.T) {
+	runRuleTests(t, "download-execute", []ruleTest{
+		{
+			name: "curl piped into sh",
+			dockerfile: `FROM debian
+RUN curl -fsSL https://example.com/install.sh | sh`,
+			want: []string{"curl output is piped into sh without verification"},
+		},
+		{
+			name: "wget piped into bash",
+			dockerfile: `FROM debian
+RUN wget -qO- https://example.com/install.sh | bash -s -- --yes`,
+			want: []string{"piped into bash"},
+		},
+		{
+			name: "download verified before running",
+			dockerfile: `FROM debian
+RUN curl -fsSLo /tmp/install.sh https://example.com/install.sh \
+    && echo "24454f830cdb571e2c4ad15481119c43b3cafd48dd869a9b2945d1036d1dc68d  /tmp/install.sh" | sha256sum -c - \
+    && sh /tmp/install.sh`,
+		},
+		{
+			name: "download piped into tar",
+			dockerfile: `FROM debian
+RUN curl -fsSL https://example.com/tool.tar.gz | tar -xz -C /opt`,
+		},
+	})
+}
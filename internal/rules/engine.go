@@ -0,0 +1,179 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// RuleConfig overrides the defaults of a single rule
+type RuleConfig struct {
+	Enabled  *bool  `yaml:"enabled,omitempty" json:"enabled,omitempty"`   // nil keeps the rule's default
+	Severity string `yaml:"severity,omitempty" json:"severity,omitempty"` // low, medium or high; empty keeps the default
+}
+
+// Config selects rules and remaps their severities
+type Config struct {
+	Rules map[string]RuleConfig `yaml:"rules" json:"rules"`
+}
+
+// LoadConfig reads a YAML rule configuration such as:
+//
+//	rules:
+//	  RULE_ID:
+//	    enabled: false
+//	  OTHER_RULE:
+//	    severity: high
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, errors.Wrap(err, "read rule config")
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, errors.Wrap(err, "parse rule config")
+	}
+	return cfg, nil
+}
+
+// Fingerprint returns a stable string describing the configuration, suitable
+// as extra cache key input
+func (c Config) Fingerprint() string {
+	// Encoding a map sorts its keys, so equal configs encode identically
+	data, _ := json.Marshal(c)
+	return string(data)
+}
+
+// Engine runs the enabled rules of a registry over Dockerfiles
+type Engine struct {
+	registry *Registry
+	config   Config
+	env      Env
+}
+
+// NewEngine creates an engine, validating rule IDs and severities in config
+func NewEngine(registry *Registry, config Config) (*Engine, error) {
+	for id, rc := range config.Rules {
+		if _, ok := registry.Get(id); !ok {
+			return nil, fmt.Errorf("config refers to unknown rule %s", id)
+		}
+		if rc.Severity != "" {
+			if _, err := parser.ParseWarnLevel(rc.Severity); err != nil {
+				return nil, errors.Wrapf(err, "rule %s", id)
+			}
+		}
+	}
+	return &Engine{registry: registry, config: config}, nil
+}
+
+// SetEnv provides the inputs used by rules that look beyond the Dockerfile.
+// Include anything that affects results, such as the lockfile fingerprint,
+// in cache keys.
+func (e *Engine) SetEnv(env Env) {
+	e.env = env
+}
+
+// Enabled reports whether the rule runs under the engine's configuration
+func (e *Engine) Enabled(rule Rule) bool {
+	if rc, ok := e.config.Rules[rule.ID()]; ok && rc.Enabled != nil {
+		return *rc.Enabled
+	}
+	if optIn, ok := rule.(OptInRule); ok && optIn.OptIn() {
+		return false
+	}
+	return true
+}
+
+// Run checks df with every enabled rule, drops suppressed warnings and
+// stores the result in df.Warnings
+func (e *Engine) Run(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings, _ := e.Analyze(context.Background(), df)
+	return warnings
+}
+
+// Analyze implements parser.Analyzer. A rule that panics is reported as an
+// error after the remaining rules have run.
+func (e *Engine) Analyze(ctx context.Context, df *parser.ParsedDockerfile) ([]parser.Warning, error) {
+	suppressions := collectSuppressions(df)
+	warnings := make([]parser.Warning, 0)
+	var failures []error
+
+	for _, rule := range e.registry.Rules() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !e.Enabled(rule) {
+			continue
+		}
+
+		found, err := runRule(rule, df, e.env)
+		if err != nil {
+			failures = append(failures, err)
+			continue
+		}
+
+		for _, w := range found {
+			w.RuleID = rule.ID()
+			if level, ok := e.severity(rule.ID()); ok {
+				w.Level = level
+			}
+			if suppressions.suppressed(w) {
+				continue
+			}
+			warnings = append(warnings, w)
+		}
+	}
+
+	sortWarnings(warnings)
+	df.Warnings = warnings
+
+	if len(failures) > 0 {
+		return warnings, failures[0]
+	}
+	return warnings, nil
+}
+
+// severity returns the configured severity override for a rule
+func (e *Engine) severity(id string) (parser.WarnLevel, bool) {
+	rc, ok := e.config.Rules[id]
+	if !ok || rc.Severity == "" {
+		return parser.WarnLow, false
+	}
+	level, err := parser.ParseWarnLevel(rc.Severity)
+	return level, err == nil
+}
+
+// runRule isolates a rule so that a bug in one rule cannot abort analysis
+func runRule(rule Rule, df *parser.ParsedDockerfile, env Env) (warnings []parser.Warning, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			warnings = nil
+			err = fmt.Errorf("rule %s failed: %v", rule.ID(), r)
+		}
+	}()
+	if envRule, ok := rule.(EnvRule); ok {
+		return envRule.CheckEnv(df, env), nil
+	}
+	return rule.Check(df), nil
+}
+
+// sortWarnings orders warnings by position, then rule ID
+func sortWarnings(warnings []parser.Warning) {
+	sort.SliceStable(warnings, func(i, j int) bool {
+		a, b := warnings[i].Position, warnings[j].Position
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return warnings[i].RuleID < warnings[j].RuleID
+	})
+}
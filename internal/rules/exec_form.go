@@ -0,0 +1,467 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/image"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+	"github.com/yourusername/dockerfile-parser/internal/shell"
+)
+
+func init() {
+	Register(shellFormEntrypointRule{})
+	Register(entrypointCmdRule{})
+	Register(stopSignalRule{})
+}
+
+// gracefulStop is the signal a process shuts down cleanly on, and the
+// official images that already set it as STOPSIGNAL
+type gracefulStop struct {
+	signal string
+	images []string
+}
+
+// Processes that shut down gracefully on a signal other than SIGTERM
+var gracefulSignals = map[string]gracefulStop{
+	"nginx":     {"SIGQUIT", []string{"nginx", "nginxinc/nginx-unprivileged"}},
+	"php-fpm":   {"SIGQUIT", []string{"php"}},
+	"httpd":     {"SIGWINCH", []string{"httpd"}},
+	"apache2":   {"SIGWINCH", nil},
+	"apachectl": {"SIGWINCH", nil},
+	"unicorn":   {"SIGQUIT", nil},
+	"haproxy":   {"SIGUSR1", []string{"haproxy"}},
+}
+
+// Signal names accepted by STOPSIGNAL, without the SIG prefix
+var signalNames = map[string]bool{
+	"HUP": true, "INT": true, "QUIT": true, "ILL": true, "TRAP": true, "ABRT": true,
+	"IOT": true, "BUS": true, "FPE": true, "KILL": true, "USR1": true, "SEGV": true,
+	"USR2": true, "PIPE": true, "ALRM": true, "TERM": true, "STKFLT": true, "CHLD": true,
+	"CONT": true, "STOP": true, "TSTP": true, "TTIN": true, "TTOU": true, "URG": true,
+	"XCPU": true, "XFSZ": true, "VTALRM": true, "PROF": true, "WINCH": true, "IO": true,
+	"POLL": true, "PWR": true, "SYS": true,
+}
+
+// imageProcess is what the final image runs: the ENTRYPOINT and CMD in
+// effect, and the STOPSIGNAL the container is stopped with
+type imageProcess struct {
+	entrypoint *parser.Instruction
+	cmd        *parser.Instruction
+	lostCmd    *parser.Instruction // CMD of a base stage cleared by ENTRYPOINT
+	stopSignal *parser.Instruction
+	base       string // Base image of the first shipped stage
+}
+
+// finalProcess follows the final stage and the stages it is built FROM,
+// applying CMD, ENTRYPOINT and STOPSIGNAL the way the builder does. An
+// ENTRYPOINT clears the CMD inherited from the base, but not a CMD set
+// earlier in its own stage.
+func finalProcess(df *parser.ParsedDockerfile) imageProcess {
+	var p imageProcess
+	chain := shippedChain(df)
+	if len(chain) == 0 {
+		return p
+	}
+	p.base = chain[0].BaseImage
+
+	for _, stage := range chain {
+		cmdSet := false
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			switch inst.Command {
+			case "CMD":
+				p.cmd, p.lostCmd, cmdSet = inst, nil, true
+			case "ENTRYPOINT":
+				p.entrypoint = inst
+				if !cmdSet && p.cmd != nil {
+					p.cmd, p.lostCmd = nil, p.cmd
+				}
+			case "STOPSIGNAL":
+				p.stopSignal = inst
+			}
+		}
+	}
+	return p
+}
+
+// shippedChain returns the final stage and the stages it is built FROM,
+// starting with the one whose FROM names an image
+func shippedChain(df *parser.ParsedDockerfile) []*parser.Stage {
+	chain := make([]*parser.Stage, 0)
+	if len(df.Stages) == 0 {
+		return chain
+	}
+	for stage := df.Stages[len(df.Stages)-1]; stage != nil; stage = stage.BaseStage {
+		chain = append([]*parser.Stage{stage}, chain...)
+		if len(chain) > len(df.Stages) {
+			break
+		}
+	}
+	return chain
+}
+
+// shellWrapped reports whether inst runs its command through /bin/sh -c
+// without handing the process over with exec
+func shellWrapped(df *parser.ParsedDockerfile, inst *parser.Instruction) bool {
+	if inst == nil || inst.JSONForm || len(inst.Args) == 0 {
+		return false
+	}
+	script, err := shell.ParseInstruction(df, inst)
+	if err != nil || len(script.Stmts) == 0 {
+		return true
+	}
+	call, ok := script.Stmts[0].Cmd.(*shell.CallExpr)
+	return !ok || call.Name() != "exec"
+}
+
+// shellFormEntrypointRule flags ENTRYPOINT and CMD in shell form, where
+// /bin/sh runs as PID 1 and does not forward SIGTERM to the application
+type shellFormEntrypointRule struct{}
+
+func (shellFormEntrypointRule) ID() string { return "shell-form-entrypoint" }
+
+func (shellFormEntrypointRule) Description() string {
+	return "ENTRYPOINT and CMD should use exec form so the process receives stop signals"
+}
+
+func (shellFormEntrypointRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+func (r shellFormEntrypointRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	p := finalProcess(df)
+
+	// A shell-form CMD after an exec-form ENTRYPOINT is a composition
+	// problem, reported by entrypoint-cmd
+	targets := []*parser.Instruction{p.entrypoint}
+	if p.entrypoint == nil {
+		targets = append(targets, p.cmd)
+	}
+
+	lines := sourceLines(df)
+	for _, inst := range targets {
+		if !shellWrapped(df, inst) {
+			continue
+		}
+		w := newWarning(r, inst, fmt.Sprintf(
+			"%s in shell form runs under /bin/sh -c, which does not pass SIGTERM on; "+
+				"the container is killed after the stop timeout. Use exec form", inst.Command))
+		execForm, ok := execFormFix(df, lines, inst)
+		switch {
+		case ok && inst == p.entrypoint && p.cmd != nil:
+			// The shell ignores CMD, but an exec-form ENTRYPOINT appends it,
+			// so the rewrite changes the command line and is only offered
+			execForm.Description += fmt.Sprintf("; the CMD at line %d then becomes its arguments", p.cmd.Range.Start.Line)
+			w.Alternatives = append(w.Alternatives, *execForm)
+			if fix, ok := execPrefixFix(df, lines, inst); ok {
+				w.Fix = fix
+			}
+		case ok:
+			w.Fix = execForm
+		default:
+			if fix, ok := execPrefixFix(df, lines, inst); ok {
+				w.Fix = fix
+			}
+		}
+		warnings = append(warnings, w)
+	}
+
+	return warnings
+}
+
+// entrypointCmdRule flags ENTRYPOINT and CMD pairs that do not combine into
+// the intended command line
+type entrypointCmdRule struct{}
+
+func (entrypointCmdRule) ID() string { return "entrypoint-cmd" }
+
+func (entrypointCmdRule) Description() string {
+	return "ENTRYPOINT and CMD should both use exec form to compose"
+}
+
+func (entrypointCmdRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+func (r entrypointCmdRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	p := finalProcess(df)
+	if p.entrypoint == nil {
+		return warnings
+	}
+
+	switch {
+	case p.cmd != nil && !p.entrypoint.JSONForm:
+		w := newWarning(r, p.cmd, fmt.Sprintf(
+			"CMD is ignored because the ENTRYPOINT at line %d uses shell form", p.entrypoint.Range.Start.Line))
+		w.Related = []parser.RelatedLocation{{Position: p.entrypoint.Range.Start, Message: "shell-form ENTRYPOINT"}}
+		warnings = append(warnings, w)
+
+	case p.cmd != nil && !p.cmd.JSONForm && len(p.cmd.Args) > 0:
+		w := newWarning(r, p.cmd, fmt.Sprintf(
+			"Shell-form CMD is passed to the ENTRYPOINT at line %d as the arguments /bin/sh -c %q",
+			p.entrypoint.Range.Start.Line, strings.Join(p.cmd.Args, " ")))
+		w.Related = []parser.RelatedLocation{{Position: p.entrypoint.Range.Start, Message: "receives CMD as arguments"}}
+		if fix, ok := execFormFix(df, sourceLines(df), p.cmd); ok {
+			w.Fix = fix
+		}
+		warnings = append(warnings, w)
+
+	case p.lostCmd != nil:
+		w := newWarning(r, p.entrypoint, fmt.Sprintf(
+			"ENTRYPOINT clears the CMD inherited from line %d; repeat CMD after it if the default arguments are still wanted",
+			p.lostCmd.Range.Start.Line))
+		w.Level = parser.WarnLow
+		w.Related = []parser.RelatedLocation{{Position: p.lostCmd.Range.Start, Message: "CMD no longer in effect"}}
+		warnings = append(warnings, w)
+	}
+
+	return warnings
+}
+
+// stopSignalRule flags STOPSIGNAL values that are invalid, cannot reach the
+// process, or differ from the signal the process shuts down gracefully on
+type stopSignalRule struct{}
+
+func (stopSignalRule) ID() string { return "stop-signal" }
+
+func (stopSignalRule) Description() string {
+	return "STOPSIGNAL should be valid and match the signal the process handles"
+}
+
+func (stopSignalRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+func (r stopSignalRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	for _, inst := range allInstructions(df) {
+		if inst.Command != "STOPSIGNAL" || len(inst.Args) == 0 || strings.Contains(inst.Args[0], "$") {
+			continue
+		}
+		signal, ok := normalizeSignal(inst.Args[0])
+		switch {
+		case !ok:
+			w := newWarning(r, inst, fmt.Sprintf("STOPSIGNAL %s is not a valid signal", inst.Args[0]))
+			w.Level = parser.WarnMedium
+			warnings = append(warnings, w)
+		case signal == "SIGKILL" || signal == "SIGSTOP":
+			warnings = append(warnings, newWarning(r, inst, fmt.Sprintf(
+				"STOPSIGNAL %s cannot be handled, so the process never shuts down gracefully", inst.Args[0])))
+		}
+	}
+
+	p := finalProcess(df)
+	process := p.entrypoint
+	if process == nil {
+		process = p.cmd
+	}
+	if process == nil {
+		return warnings
+	}
+
+	if p.stopSignal != nil && shellWrapped(df, process) {
+		w := newWarning(r, p.stopSignal, fmt.Sprintf(
+			"STOPSIGNAL is delivered to /bin/sh, not the process started by the shell-form %s at line %d",
+			process.Command, process.Range.Start.Line))
+		w.Related = []parser.RelatedLocation{{Position: process.Range.Start, Message: "shell-form " + process.Command}}
+		return append(warnings, w)
+	}
+
+	name, expected, ok := gracefulProcess(p)
+	if !ok {
+		return warnings
+	}
+	current := "SIGTERM"
+	if p.stopSignal != nil && len(p.stopSignal.Args) > 0 {
+		if signal, valid := normalizeSignal(p.stopSignal.Args[0]); valid {
+			current = signal
+		} else {
+			return warnings
+		}
+	} else if officialImageFor(p.base, expected.images) {
+		return warnings
+	}
+	if current == expected.signal || current == "SIGKILL" {
+		return warnings
+	}
+
+	line := fmt.Sprintf("STOPSIGNAL %s", expected.signal)
+	if p.stopSignal != nil {
+		w := newWarning(r, p.stopSignal, fmt.Sprintf(
+			"%s shuts down gracefully on %s; %s makes it stop immediately", name, expected.signal, current))
+		w.Fix = &parser.Fix{
+			Description: "Use " + line,
+			Edits:       []parser.TextEdit{replaceLines(p.stopSignal.Range.Start.Line, instructionEndLine(p.stopSignal), line)},
+		}
+		return append(warnings, w)
+	}
+
+	w := newWarning(r, process, fmt.Sprintf(
+		"%s shuts down gracefully on %s, but the container is stopped with SIGTERM; add %s",
+		name, expected.signal, line))
+	w.Fix = &parser.Fix{
+		Description: "Add " + line,
+		Edits:       []parser.TextEdit{insertBefore(process.Range.Start.Line, line)},
+	}
+	return append(warnings, w)
+}
+
+// gracefulProcess finds a process with a preferred stop signal among the
+// executables named by ENTRYPOINT and CMD, so that wrappers such as
+// docker-entrypoint.sh still reveal the server they start
+func gracefulProcess(p imageProcess) (string, gracefulStop, bool) {
+	for _, inst := range []*parser.Instruction{p.entrypoint, p.cmd} {
+		if inst == nil || !inst.JSONForm {
+			continue
+		}
+		for _, arg := range inst.Args {
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			name := path.Base(arg)
+			if expected, ok := gracefulSignals[name]; ok {
+				return name, expected, true
+			}
+			break
+		}
+	}
+	return "", gracefulStop{}, false
+}
+
+// officialImageFor reports whether base is one of the images that already
+// set the process's preferred STOPSIGNAL
+func officialImageFor(base string, images []string) bool {
+	ref, err := image.ParseReference(base)
+	if err != nil {
+		return false
+	}
+	repo := strings.TrimPrefix(ref.Repository(), "library/")
+	for _, name := range images {
+		if repo == name {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeSignal turns TERM, SIGTERM or 15 into SIGTERM style names;
+// numbers without a common name and SIGRTMIN+n are returned as written
+func normalizeSignal(value string) (string, bool) {
+	upper := strings.ToUpper(strings.TrimSpace(value))
+	if n, err := strconv.Atoi(upper); err == nil {
+		names := map[int]string{1: "SIGHUP", 2: "SIGINT", 3: "SIGQUIT", 9: "SIGKILL", 10: "SIGUSR1",
+			12: "SIGUSR2", 15: "SIGTERM", 19: "SIGSTOP", 28: "SIGWINCH"}
+		if name, ok := names[n]; ok {
+			return name, true
+		}
+		return upper, n >= 1 && n <= 64
+	}
+	upper = strings.TrimPrefix(upper, "SIG")
+	if strings.HasPrefix(upper, "RTMIN") || strings.HasPrefix(upper, "RTMAX") {
+		return "SIG" + upper, true
+	}
+	return "SIG" + upper, signalNames[upper]
+}
+
+// execFormFix rewrites a shell-form CMD or ENTRYPOINT as a JSON array. It
+// only applies when the command is a single simple command without
+// expansions, globs, redirections or operators, which /bin/sh would run
+// exactly as the array does.
+func execFormFix(df *parser.ParsedDockerfile, lines []string, inst *parser.Instruction) (*parser.Fix, bool) {
+	if inst.JSONForm || inst.Heredoc != nil {
+		return nil, false
+	}
+	script, err := shell.ParseInstruction(df, inst)
+	if err != nil || len(script.Stmts) != 1 {
+		return nil, false
+	}
+	stmt := script.Stmts[0]
+	call, ok := stmt.Cmd.(*shell.CallExpr)
+	if !ok || len(stmt.Redirs) > 0 || stmt.Negated || stmt.Background || len(call.Assigns) > 0 {
+		return nil, false
+	}
+
+	words := make([]string, 0, len(call.Args))
+	for _, arg := range call.Args {
+		value, literal := arg.Lit()
+		if !literal || hasUnquotedGlob(arg) {
+			return nil, false
+		}
+		words = append(words, value)
+	}
+	// exec is only needed in shell form
+	if len(words) > 0 && words[0] == "exec" {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return nil, false
+	}
+
+	array, err := jsonArray(words)
+	if err != nil {
+		return nil, false
+	}
+	start := inst.Range.Start.Line
+	first := lineText(lines, start)
+	indent := first[:len(first)-len(strings.TrimLeft(first, " \t"))]
+	return &parser.Fix{
+		Description: fmt.Sprintf("Rewrite %s in exec form", inst.Command),
+		Edits:       []parser.TextEdit{replaceLines(start, instructionEndLine(inst), indent+inst.Command+" "+array)},
+	}, true
+}
+
+// execPrefixFix prefixes a single shell command with exec, so the shell
+// hands PID 1 to the process after expanding variables
+func execPrefixFix(df *parser.ParsedDockerfile, lines []string, inst *parser.Instruction) (*parser.Fix, bool) {
+	script, err := shell.ParseInstruction(df, inst)
+	if err != nil || len(script.Stmts) != 1 || script.Stmts[0].Background {
+		return nil, false
+	}
+	call, ok := script.Stmts[0].Cmd.(*shell.CallExpr)
+	if !ok || len(call.Args) == 0 || len(call.Assigns) > 0 {
+		return nil, false
+	}
+
+	pos := script.Position(call.Args[0].Pos())
+	line := lineText(lines, pos.Line)
+	if pos.Column < 1 || pos.Column > len(line)+1 {
+		return nil, false
+	}
+	return &parser.Fix{
+		Description: fmt.Sprintf("Start the %s command with exec", inst.Command),
+		Edits:       []parser.TextEdit{replaceLines(pos.Line, pos.Line, line[:pos.Column-1]+"exec "+line[pos.Column-1:])},
+	}, true
+}
+
+// hasUnquotedGlob reports whether a word has *, ? or [ outside quotes, or
+// starts with ~, which the shell would expand
+func hasUnquotedGlob(word *shell.Word) bool {
+	for i, part := range word.Parts {
+		lit, ok := part.(*shell.Lit)
+		if !ok {
+			continue
+		}
+		if strings.ContainsAny(lit.Value, "*?[") || (i == 0 && strings.HasPrefix(lit.Value, "~")) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonArray renders words as a JSON array in the spacing Dockerfiles use,
+// without escaping &, < and > as JSON for HTML would
+func jsonArray(words []string) (string, error) {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(word); err != nil {
+			return "", err
+		}
+		quoted = append(quoted, strings.TrimSuffix(buf.String(), "\n"))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]", nil
+}
@@ -0,0 +1,115 @@
+package rules
+
+import "testing"
+
+func TestShellFormEntrypoint(t *testing.T) {
+	runRuleTests(t, "shell-form-entrypoint", []ruleTest{
+		{
+			name: "shell-form CMD",
+			dockerfile: `FROM node:20
+CMD node server.js`,
+			want: []string{"CMD in shell form runs under /bin/sh -c, which does not pass SIGTERM on"},
+			fixed: `FROM node:20
+CMD ["node", "server.js"]`,
+		},
+		{
+			name: "shell-form ENTRYPOINT",
+			dockerfile: `FROM node:20
+ENTRYPOINT node server.js`,
+			want: []string{"ENTRYPOINT in shell form"},
+			fixed: `FROM node:20
+ENTRYPOINT ["node", "server.js"]`,
+		},
+		{
+			name: "ENTRYPOINT with a CMD in effect keeps the CMD out of its arguments",
+			dockerfile: `FROM node:20
+ENTRYPOINT node server.js
+CMD ["--port", "80"]`,
+			want: []string{"ENTRYPOINT in shell form"},
+			fixed: `FROM node:20
+ENTRYPOINT exec node server.js
+CMD ["--port", "80"]`,
+			alternate: `FROM node:20
+ENTRYPOINT ["node", "server.js"]
+CMD ["--port", "80"]`,
+		},
+		{
+			name: "exec form",
+			dockerfile: `FROM node:20
+CMD ["node", "server.js"]`,
+		},
+	})
+}
+
+func TestEntrypointCmd(t *testing.T) {
+	runRuleTests(t, "entrypoint-cmd", []ruleTest{
+		{
+			name: "shell-form CMD as ENTRYPOINT arguments",
+			dockerfile: `FROM node:20
+ENTRYPOINT ["node"]
+CMD server.js`,
+			want: []string{`Shell-form CMD is passed to the ENTRYPOINT at line 2 as the arguments /bin/sh -c "server.js"`},
+			fixed: `FROM node:20
+ENTRYPOINT ["node"]
+CMD ["server.js"]`,
+		},
+		{
+			name: "exec-form CMD",
+			dockerfile: `FROM node:20
+ENTRYPOINT ["node"]
+CMD ["server.js"]`,
+		},
+	})
+}
+
+func TestStopSignal(t *testing.T) {
+	runRuleTests(t, "stop-signal", []ruleTest{
+		{
+			name: "invalid signal",
+			dockerfile: `FROM alpine
+STOPSIGNAL SIGFOO
+CMD ["sleep", "infinity"]`,
+			want: []string{"STOPSIGNAL SIGFOO is not a valid signal"},
+		},
+		{
+			name: "signal that cannot be handled",
+			dockerfile: `FROM alpine
+STOPSIGNAL SIGKILL
+CMD ["sleep", "infinity"]`,
+			want: []string{"STOPSIGNAL SIGKILL cannot be handled"},
+		},
+		{
+			name: "nginx on a custom base needs SIGQUIT",
+			dockerfile: `FROM debian:bookworm-slim
+RUN apt-get update && apt-get install -y nginx
+CMD ["nginx", "-g", "daemon off;"]`,
+			want: []string{"nginx shuts down gracefully on SIGQUIT, but the container is stopped with SIGTERM"},
+			fixed: `FROM debian:bookworm-slim
+RUN apt-get update && apt-get install -y nginx
+STOPSIGNAL SIGQUIT
+CMD ["nginx", "-g", "daemon off;"]`,
+		},
+		{
+			name: "wrong STOPSIGNAL is replaced",
+			dockerfile: `FROM debian:bookworm-slim
+STOPSIGNAL SIGTERM
+CMD ["nginx", "-g", "daemon off;"]`,
+			want: []string{"nginx shuts down gracefully on SIGQUIT; SIGTERM makes it stop immediately"},
+			fixed: `FROM debian:bookworm-slim
+STOPSIGNAL SIGQUIT
+CMD ["nginx", "-g", "daemon off;"]`,
+		},
+		{
+			name: "official image already sets the signal",
+			dockerfile: `FROM nginx:1.25
+CMD ["nginx", "-g", "daemon off;"]`,
+		},
+		{
+			name: "signal sent to the shell",
+			dockerfile: `FROM debian:bookworm-slim
+STOPSIGNAL SIGQUIT
+CMD nginx -g "daemon off;"`,
+			want: []string{"STOPSIGNAL is delivered to /bin/sh, not the process started by the shell-form CMD at line 3"},
+		},
+	})
+}
@@ -0,0 +1,127 @@
+package rules
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// ApplyFixes applies the fixes attached to warnings to content. Fixes whose
+// edits overlap an already accepted fix are skipped; the number of applied
+// fixes is returned alongside the rewritten content.
+func ApplyFixes(content string, warnings []parser.Warning) (string, int) {
+	type lineEdit struct {
+		start, end int // Inclusive line span; end < start inserts before start
+		text       string
+		order      int
+	}
+
+	accepted := make([]lineEdit, 0)
+	applied := 0
+
+	overlaps := func(a, b lineEdit) bool {
+		// Two insertions at the same line are ordered, not conflicting
+		if a.end < a.start || b.end < b.start {
+			return a.end >= a.start && b.start > a.start && b.start <= a.end ||
+				b.end >= b.start && a.start > b.start && a.start <= b.end
+		}
+		return a.start <= b.end && b.start <= a.end
+	}
+
+	for _, w := range warnings {
+		if w.Fix == nil || len(w.Fix.Edits) == 0 {
+			continue
+		}
+
+		edits := make([]lineEdit, 0, len(w.Fix.Edits))
+		conflict := false
+		for _, e := range w.Fix.Edits {
+			edit := lineEdit{start: e.Range.Start.Line, end: e.Range.End.Line, text: e.NewText}
+			for _, other := range accepted {
+				if overlaps(edit, other) {
+					conflict = true
+				}
+			}
+			edits = append(edits, edit)
+		}
+		if conflict {
+			continue
+		}
+
+		accepted = append(accepted, edits...)
+		applied++
+	}
+
+	// Apply bottom-up so earlier line numbers stay valid. At the same line a
+	// replacement goes first, and insertions go in reverse so that they end
+	// up in the order they were accepted.
+	for i := range accepted {
+		accepted[i].order = i
+	}
+	sort.Slice(accepted, func(i, j int) bool {
+		a, b := accepted[i], accepted[j]
+		if a.start != b.start {
+			return a.start > b.start
+		}
+		aInsert, bInsert := a.end < a.start, b.end < b.start
+		if aInsert != bInsert {
+			return !aInsert
+		}
+		return a.order > b.order
+	})
+
+	lines := strings.Split(content, "\n")
+	for _, edit := range accepted {
+		start := clampLine(edit.start, len(lines)+1) - 1
+		end := start
+		if edit.end >= edit.start {
+			end = clampLine(edit.end, len(lines))
+		}
+
+		replacement := []string{}
+		if edit.text != "" {
+			replacement = strings.Split(edit.text, "\n")
+		}
+
+		updated := make([]string, 0, len(lines)-(end-start)+len(replacement))
+		updated = append(updated, lines[:start]...)
+		updated = append(updated, replacement...)
+		updated = append(updated, lines[end:]...)
+		lines = updated
+	}
+
+	return strings.Join(lines, "\n"), applied
+}
+
+// clampLine bounds a 1-based line number to [1, max]
+func clampLine(line, max int) int {
+	if line < 1 {
+		return 1
+	}
+	if line > max {
+		return max
+	}
+	return line
+}
+
+// replaceLines builds an edit replacing lines start through end with text
+func replaceLines(start, end int, text string) parser.TextEdit {
+	return parser.TextEdit{
+		Range: parser.Range{
+			Start: parser.Position{Line: start},
+			End:   parser.Position{Line: end},
+		},
+		NewText: text,
+	}
+}
+
+// deleteLines builds an edit removing lines start through end
+func deleteLines(start, end int) parser.TextEdit {
+	return replaceLines(start, end, "")
+}
+
+// insertBefore builds an edit inserting text ahead of line
+func insertBefore(line int, text string) parser.TextEdit {
+	return replaceLines(line, line-1, text)
+}
@@ -0,0 +1,123 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// fixWarning wraps edits in a warning carrying them as its fix
+func fixWarning(edits ...parser.TextEdit) parser.Warning {
+	return parser.Warning{Fix: &parser.Fix{Edits: edits}}
+}
+
+func TestApplyFixes(t *testing.T) {
+	const content = "FROM alpine\nRUN one\nRUN two\nRUN three"
+
+	tests := []struct {
+		name     string
+		warnings []parser.Warning
+		want     string
+		applied  int
+	}{
+		{
+			name:     "no fixes",
+			warnings: []parser.Warning{{Message: "no fix"}, {Fix: &parser.Fix{}}},
+			want:     content,
+		},
+		{
+			name:     "replace a line",
+			warnings: []parser.Warning{fixWarning(replaceLines(2, 2, "RUN 1"))},
+			want:     "FROM alpine\nRUN 1\nRUN two\nRUN three",
+			applied:  1,
+		},
+		{
+			name:     "replace several lines with one",
+			warnings: []parser.Warning{fixWarning(replaceLines(2, 3, "RUN one && two"))},
+			want:     "FROM alpine\nRUN one && two\nRUN three",
+			applied:  1,
+		},
+		{
+			name:     "delete lines",
+			warnings: []parser.Warning{fixWarning(deleteLines(3, 4))},
+			want:     "FROM alpine\nRUN one",
+			applied:  1,
+		},
+		{
+			name:     "insert before a line",
+			warnings: []parser.Warning{fixWarning(insertBefore(2, "WORKDIR /app"))},
+			want:     "FROM alpine\nWORKDIR /app\nRUN one\nRUN two\nRUN three",
+			applied:  1,
+		},
+		{
+			name:     "insert past the end",
+			warnings: []parser.Warning{fixWarning(insertBefore(5, "USER app"))},
+			want:     content + "\nUSER app",
+			applied:  1,
+		},
+		{
+			name: "independent fixes apply bottom-up",
+			warnings: []parser.Warning{
+				fixWarning(replaceLines(2, 2, "RUN 1\nRUN 1b")),
+				fixWarning(replaceLines(4, 4, "RUN 3")),
+			},
+			want:    "FROM alpine\nRUN 1\nRUN 1b\nRUN two\nRUN 3",
+			applied: 2,
+		},
+		{
+			name: "overlapping fix is skipped",
+			warnings: []parser.Warning{
+				fixWarning(replaceLines(2, 3, "RUN one && two")),
+				fixWarning(replaceLines(3, 3, "RUN 2")),
+			},
+			want:    "FROM alpine\nRUN one && two\nRUN three",
+			applied: 1,
+		},
+		{
+			name: "a fix is applied whole or not at all",
+			warnings: []parser.Warning{
+				fixWarning(deleteLines(4, 4)),
+				fixWarning(insertBefore(2, "RUN zero"), replaceLines(4, 4, "RUN 3")),
+			},
+			want:    "FROM alpine\nRUN one\nRUN two",
+			applied: 1,
+		},
+		{
+			name: "insertions at one line keep their order",
+			warnings: []parser.Warning{
+				fixWarning(insertBefore(2, "ARG A")),
+				fixWarning(insertBefore(2, "ARG B")),
+			},
+			want:    "FROM alpine\nARG A\nARG B\nRUN one\nRUN two\nRUN three",
+			applied: 2,
+		},
+		{
+			name: "insertion before a replaced line",
+			warnings: []parser.Warning{
+				fixWarning(replaceLines(2, 2, "RUN 1")),
+				fixWarning(insertBefore(2, "ARG A")),
+			},
+			want:    "FROM alpine\nARG A\nRUN 1\nRUN two\nRUN three",
+			applied: 2,
+		},
+		{
+			name: "insertion inside a replaced span conflicts",
+			warnings: []parser.Warning{
+				fixWarning(replaceLines(2, 3, "RUN one && two")),
+				fixWarning(insertBefore(3, "ARG A")),
+			},
+			want:    "FROM alpine\nRUN one && two\nRUN three",
+			applied: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, applied := ApplyFixes(content, tt.warnings)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.applied, applied)
+		})
+	}
+}
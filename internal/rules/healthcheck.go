@@ -0,0 +1,562 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/dockerfile-parser/internal/catalog"
+	"github.com/yourusername/dockerfile-parser/internal/image"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(missingHealthcheckRule{})
+	Register(healthcheckBinaryRule{})
+	Register(healthcheckTimingRule{})
+}
+
+// Programs that run as long-lived network servers
+var serverProcesses = map[string]bool{
+	"nginx": true, "httpd": true, "apache2": true, "apache2-foreground": true, "caddy": true,
+	"traefik": true, "haproxy": true, "envoy": true, "gunicorn": true, "uvicorn": true,
+	"hypercorn": true, "daphne": true, "waitress-serve": true, "http.server": true, "puma": true,
+	"unicorn": true, "php-fpm": true, "catalina.sh": true, "redis-server": true, "postgres": true,
+	"mysqld": true, "mongod": true,
+}
+
+// Base image repositories whose default process is a server
+var serverImages = map[string]bool{
+	"nginx": true, "httpd": true, "caddy": true, "traefik": true, "haproxy": true, "tomcat": true,
+	"redis": true, "postgres": true, "mysql": true, "mariadb": true, "mongo": true,
+}
+
+// Network clients health checks run, with the packages that provide them
+var healthTools = map[string][]string{
+	"curl": {"curl"},
+	"wget": {"wget", "busybox"},
+	"nc":   {"netcat", "netcat-openbsd", "netcat-traditional", "nmap-ncat", "ncat", "busybox", "busybox-extras"},
+}
+
+// Options HEALTHCHECK accepts
+var healthcheckOptionNames = map[string]bool{
+	"interval": true, "timeout": true, "start-period": true, "start-interval": true, "retries": true,
+}
+
+// Defaults the builder uses for unset HEALTHCHECK options
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthRetries  = 3
+)
+
+// missingHealthcheckRule flags final images that serve on a port or run a
+// known server but never say how to tell whether they are healthy
+type missingHealthcheckRule struct{}
+
+func (missingHealthcheckRule) ID() string { return "missing-healthcheck" }
+
+func (missingHealthcheckRule) Description() string {
+	return "Images that run a server should define a HEALTHCHECK"
+}
+
+func (missingHealthcheckRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+func (r missingHealthcheckRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	chain := shippedChain(df)
+	if len(chain) == 0 {
+		return warnings
+	}
+
+	var exposed *parser.Instruction
+	for _, stage := range chain {
+		for i := range stage.Instructions {
+			switch inst := &stage.Instructions[i]; inst.Command {
+			case "HEALTHCHECK":
+				// HEALTHCHECK NONE is a deliberate choice as well
+				return warnings
+			case "EXPOSE":
+				if exposed == nil {
+					exposed = inst
+				}
+			}
+		}
+	}
+	p := finalProcess(df)
+	server, ok := serverProcess(df, p)
+	if exposed == nil && !ok {
+		return warnings
+	}
+
+	var what string
+	port, hasPort := exposedPort(exposed)
+	switch {
+	case ok && exposed != nil:
+		what = fmt.Sprintf("runs %s and exposes %s", server, exposedPorts(exposed))
+	case ok:
+		what = "runs " + server
+	default:
+		what = "exposes " + exposedPorts(exposed)
+	}
+	msg := fmt.Sprintf("The image %s but defines no HEALTHCHECK, so a hung server still counts as running; "+
+		"add one, or HEALTHCHECK NONE if the platform probes the container itself", what)
+
+	final := chain[len(chain)-1]
+	at := exposed
+	if at == nil {
+		for _, inst := range []*parser.Instruction{p.entrypoint, p.cmd} {
+			if inst != nil && (at == nil || inst.Range.Start.Line < at.Range.Start.Line) {
+				at = inst
+			}
+		}
+	}
+	if at == nil && len(final.Instructions) > 0 {
+		at = &final.Instructions[0]
+	}
+	if at == nil {
+		return warnings
+	}
+	w := newWarning(r, at, msg)
+
+	if hasPort {
+		tools, img := availableTools(df, chain)
+		if img != nil && img.Shell {
+			if fix := healthcheckTemplate(sourceLines(df), final, p, tools, port); fix != nil {
+				w.Alternatives = append(w.Alternatives, *fix)
+			}
+		}
+	}
+	return append(warnings, w)
+}
+
+// serverProcess finds a long-running server among the programs ENTRYPOINT
+// and CMD start, or the base image's own server when neither is set
+func serverProcess(df *parser.ParsedDockerfile, p imageProcess) (string, bool) {
+	if p.entrypoint == nil && p.cmd == nil {
+		ref, err := image.ParseReference(p.base)
+		if err != nil {
+			return "", false
+		}
+		repo := strings.TrimPrefix(ref.Repository(), "library/")
+		return repo, serverImages[repo]
+	}
+	for _, inst := range []*parser.Instruction{p.entrypoint, p.cmd} {
+		for _, c := range processCommands(df, inst) {
+			if name, ok := serverCommand(c); ok {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// processCommands returns the commands a CMD or ENTRYPOINT runs, resolving
+// wrappers such as tini in the exec form too
+func processCommands(df *parser.ParsedDockerfile, inst *parser.Instruction) []command {
+	if inst == nil {
+		return nil
+	}
+	if !inst.JSONForm {
+		return instructionCommands(df, inst)
+	}
+	if c, ok := commandFromWords(inst.Args); ok {
+		return []command{c}
+	}
+	return nil
+}
+
+// serverCommand reports whether c starts a server, and its name
+func serverCommand(c command) (string, bool) {
+	name, args := c.name, c.args
+	if name == "bundle" && len(args) > 1 && args[0] == "exec" {
+		name, args = path.Base(args[1]), args[2:]
+	}
+	switch name {
+	case "rails":
+		return name, len(args) > 0 && (args[0] == "server" || args[0] == "s")
+	case "flask":
+		return name, len(args) > 0 && args[0] == "run"
+	}
+	return name, serverProcesses[name]
+}
+
+// exposedPort returns the first TCP port an EXPOSE names
+func exposedPort(inst *parser.Instruction) (int, bool) {
+	if inst == nil {
+		return 0, false
+	}
+	for _, arg := range inst.Args {
+		number, proto, _ := strings.Cut(arg, "/")
+		if proto != "" && proto != "tcp" {
+			continue
+		}
+		if port, err := strconv.Atoi(number); err == nil && port > 0 {
+			return port, true
+		}
+	}
+	return 0, false
+}
+
+// exposedPorts names the ports of an EXPOSE in messages
+func exposedPorts(inst *parser.Instruction) string {
+	if len(inst.Args) == 1 {
+		return "port " + inst.Args[0]
+	}
+	return "ports " + strings.Join(inst.Args, " ")
+}
+
+// healthcheckTemplate inserts a HEALTHCHECK probing the exposed port with
+// a client the image has, ahead of the process the final stage sets. The
+// path probed is a guess, so the fix is only offered as an alternative.
+func healthcheckTemplate(lines []string, final *parser.Stage, p imageProcess, tools map[string]bool, port int) *parser.Fix {
+	var probe string
+	switch url := fmt.Sprintf("http://localhost:%d/", port); {
+	case tools["curl"]:
+		probe = "curl -fsS " + url
+	case tools["wget"]:
+		probe = "wget -q --spider " + url
+	default:
+		return nil
+	}
+
+	var at *parser.Instruction
+	for i := range final.Instructions {
+		if inst := &final.Instructions[i]; inst == p.entrypoint || inst == p.cmd {
+			at = inst
+			break
+		}
+	}
+	if at == nil {
+		return nil
+	}
+	line := fmt.Sprintf("HEALTHCHECK --interval=30s --timeout=5s --start-period=10s --retries=3 CMD %s || exit 1", probe)
+	return &parser.Fix{
+		Description: "Add " + line,
+		Edits:       []parser.TextEdit{insertBefore(commentBlockStart(lines, at.Range.Start.Line), line)},
+	}
+}
+
+// healthcheckBinaryRule flags HEALTHCHECK commands that run a network
+// client or shell the final image does not have, so the check can never
+// pass
+type healthcheckBinaryRule struct{}
+
+func (healthcheckBinaryRule) ID() string { return "healthcheck-binary" }
+
+func (healthcheckBinaryRule) Description() string {
+	return "HEALTHCHECK should only run programs the image contains"
+}
+
+func (healthcheckBinaryRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+func (r healthcheckBinaryRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	chain := shippedChain(df)
+	if len(chain) == 0 {
+		return warnings
+	}
+	var hc *parser.Instruction
+	for _, stage := range chain {
+		for i := range stage.Instructions {
+			if inst := &stage.Instructions[i]; inst.Command == "HEALTHCHECK" {
+				hc = inst
+			}
+		}
+	}
+	if hc == nil || len(hc.Args) == 0 || hc.Args[0] == "NONE" {
+		return warnings
+	}
+	tools, img := availableTools(df, chain)
+	if img == nil {
+		return warnings
+	}
+	base := chain[0].BaseImage
+
+	commands, viaShell := healthcheckCommands(df, hc)
+	if viaShell && !img.Shell && !tools["sh"] {
+		w := newWarning(r, hc, fmt.Sprintf(
+			"HEALTHCHECK runs through /bin/sh, which %s does not have, so every check fails; use the exec form HEALTHCHECK CMD [\"...\"]",
+			base))
+		return append(warnings, w)
+	}
+
+	reported := make(map[string]bool)
+	for _, c := range commands {
+		if _, ok := healthTools[c.name]; !ok || tools[c.name] || reported[c.name] {
+			continue
+		}
+		reported[c.name] = true
+
+		advice := fmt.Sprintf("install %s in the final stage", c.name)
+		switch {
+		case img.Distro == "distroless" || img.Distro == "none":
+			advice = "probe with a static binary copied from a builder stage, or a health command the application provides"
+		case c.name == "curl" && tools["wget"]:
+			advice = "use wget -q --spider instead, or " + advice
+		case c.name == "wget" && tools["curl"]:
+			advice = "use curl -fsS instead, or " + advice
+		}
+		w := newWarning(r, hc, fmt.Sprintf(
+			"HEALTHCHECK runs %s, which %s does not include, so the container never turns healthy; %s",
+			c.name, base, advice))
+		if c.call != nil {
+			w.Position = c.position()
+		}
+		warnings = append(warnings, w)
+	}
+	return warnings
+}
+
+// availableTools returns the network clients of the final image: those the
+// catalog lists for its base, plus any the shipped stages install or copy
+// in. The catalog image is nil when the base's tools are not known.
+func availableTools(df *parser.ParsedDockerfile, chain []*parser.Stage) (map[string]bool, *catalog.Image) {
+	ref, err := image.ParseReference(chain[0].BaseImage)
+	if err != nil || strings.Contains(ref.Name, "$") {
+		return nil, nil
+	}
+	match, ok := catalog.Default().Lookup(ref)
+	if !ok || match.Image.Tools == nil {
+		return nil, nil
+	}
+
+	tools := make(map[string]bool)
+	for _, tool := range match.Image.Tools {
+		tools[tool] = true
+	}
+	for _, stage := range chain {
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			switch inst.Command {
+			case "RUN":
+				for _, c := range instructionCommands(df, inst) {
+					if _, ok := osPackageManagers[c.name]; !ok || !isPackageInstall(c) {
+						continue
+					}
+					for _, operand := range c.operands() {
+						pkg := strings.FieldsFunc(operand, func(r rune) bool { return strings.ContainsRune("=<>~", r) })
+						for tool, packages := range healthTools {
+							if len(pkg) > 0 && containsString(packages, pkg[0]) {
+								tools[tool] = true
+							}
+						}
+					}
+				}
+			case "COPY", "ADD":
+				// Binaries such as a static curl or busybox copied in
+				for _, arg := range inst.Args {
+					switch name := path.Base(strings.Trim(arg, `[]",`)); name {
+					case "busybox":
+						tools["sh"], tools["wget"], tools["nc"] = true, true, true
+					case "sh", "bash":
+						tools["sh"] = true
+					default:
+						if _, ok := healthTools[name]; ok {
+							tools[name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return tools, match.Image
+}
+
+// healthcheckCommands returns the commands a HEALTHCHECK runs and whether
+// it runs them through a shell. Scripts given to sh -c in the exec form
+// are split on their separators rather than parsed.
+func healthcheckCommands(df *parser.ParsedDockerfile, inst *parser.Instruction) ([]command, bool) {
+	src := strings.TrimSpace(inst.Args[len(inst.Args)-1])
+	if !strings.HasPrefix(src, "[") {
+		return instructionCommands(df, inst), true
+	}
+
+	var words []string
+	if err := json.Unmarshal([]byte(src), &words); err != nil {
+		words = words[:0]
+		for _, field := range strings.Fields(src) {
+			if word := strings.Trim(field, `[]",`); word != "" {
+				words = append(words, word)
+			}
+		}
+	}
+	c, ok := commandFromWords(words)
+	if !ok {
+		return nil, false
+	}
+	if !posixShell([]string{c.name}) || len(c.args) < 2 || c.args[0] != "-c" {
+		return []command{c}, false
+	}
+
+	commands := []command{c}
+	start := true
+	for _, field := range strings.Fields(c.args[1]) {
+		switch field {
+		case "&&", "||", ";", "|":
+			start = true
+			continue
+		}
+		if start {
+			if inner, ok := commandFromWords([]string{strings.TrimSuffix(field, ";")}); ok {
+				commands = append(commands, inner)
+			}
+		}
+		start = strings.HasSuffix(field, ";")
+	}
+	return commands, true
+}
+
+// healthcheckTimingRule flags HEALTHCHECK options that are invalid, or
+// whose timing makes checks either a burden or too slow to notice failures
+type healthcheckTimingRule struct{}
+
+func (healthcheckTimingRule) ID() string { return "healthcheck-timing" }
+
+func (healthcheckTimingRule) Description() string {
+	return "HEALTHCHECK intervals and timeouts should be valid and reasonable"
+}
+
+func (healthcheckTimingRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+func (r healthcheckTimingRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	for _, inst := range allInstructions(df) {
+		if inst.Command != "HEALTHCHECK" || len(inst.Args) == 0 || inst.Args[0] == "NONE" {
+			continue
+		}
+		invalid := func(msg string) {
+			w := newWarning(r, inst, msg)
+			w.Level = parser.WarnMedium
+			warnings = append(warnings, w)
+		}
+
+		options, order, spaced := healthcheckOptions(inst)
+		durations := make(map[string]time.Duration)
+		retries := defaultHealthRetries
+		for _, name := range order {
+			value := options[name]
+			switch {
+			case !healthcheckOptionNames[name]:
+				invalid(fmt.Sprintf("HEALTHCHECK has no --%s option", name))
+			case spaced[name]:
+				invalid(fmt.Sprintf("--%s %s is not accepted; write --%s=%s", name, value, name, value))
+			case name == "retries":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					invalid(fmt.Sprintf("--retries=%s is not a whole number", value))
+				} else if n > 0 {
+					retries = n
+				}
+			default:
+				d, err := time.ParseDuration(value)
+				if err != nil || d < 0 {
+					invalid(fmt.Sprintf("--%s=%s is not a valid duration; use a number with a unit, such as 30s", name, value))
+					continue
+				}
+				durations[name] = d
+			}
+		}
+
+		interval, set := durations["interval"]
+		switch {
+		case !set || interval == 0:
+			interval = defaultHealthInterval
+		case interval < 5*time.Second:
+			warnings = append(warnings, newWarning(r, inst, fmt.Sprintf(
+				"--interval=%s runs the check so often that it loads the service; 10s to 60s is typical",
+				options["interval"])))
+		case interval > 10*time.Minute:
+			warnings = append(warnings, newWarning(r, inst, fmt.Sprintf(
+				"--interval=%s leaves a hung service marked healthy for up to %s; 10s to 60s is typical",
+				options["interval"], formatDuration(interval*time.Duration(retries)))))
+		}
+
+		if timeout, set := durations["timeout"]; set && timeout > 0 {
+			switch {
+			case timeout < time.Second:
+				warnings = append(warnings, newWarning(r, inst, fmt.Sprintf(
+					"--timeout=%s fails the check whenever the service is briefly slow; allow at least 1s",
+					options["timeout"])))
+			case timeout > interval:
+				warnings = append(warnings, newWarning(r, inst, fmt.Sprintf(
+					"--timeout=%s is longer than the %s interval, so a hanging check delays the next one; keep it below the interval",
+					options["timeout"], formatDuration(interval))))
+			case timeout > time.Minute:
+				warnings = append(warnings, newWarning(r, inst, fmt.Sprintf(
+					"--timeout=%s waits too long on a hung check; a few seconds is typical",
+					options["timeout"])))
+			}
+		}
+	}
+
+	return warnings
+}
+
+// formatDuration renders a duration for messages without zero units, as
+// 3h rather than 3h0m0s
+func formatDuration(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
+
+// healthcheckOptions returns the options written before CMD, in source
+// order, and the options written as --name value. Docker only accepts
+// --name=value, so the latter are reported as invalid.
+func healthcheckOptions(inst *parser.Instruction) (map[string]string, []string, map[string]bool) {
+	options := make(map[string]string)
+	order := make([]string, 0)
+	spaced := make(map[string]bool)
+	set := func(name, value string) {
+		if _, ok := options[name]; !ok {
+			order = append(order, name)
+		}
+		options[name] = value
+	}
+
+	fields := strings.Fields(inst.Raw)
+	if len(fields) > 0 && strings.EqualFold(fields[0], inst.Command) {
+		fields = fields[1:]
+	}
+	for i := 0; i < len(fields) && fields[i] != "CMD"; i++ {
+		field := fields[i]
+		if field == "\\" || !strings.HasPrefix(field, "--") {
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimPrefix(field, "--"), "=")
+		if !ok && i+1 < len(fields) && fields[i+1] != "CMD" {
+			i++
+			value = fields[i]
+			spaced[name] = true
+		}
+		set(name, value)
+	}
+	if len(order) > 0 || inst.Raw != "" {
+		return options, order, spaced
+	}
+
+	keys := make([]string, 0, len(inst.Flags))
+	for key := range inst.Flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := inst.Flags[key]
+		if name, inline, ok := strings.Cut(key, "="); ok {
+			set(name, inline)
+		} else {
+			set(key, value)
+		}
+	}
+	return options, order, spaced
+}
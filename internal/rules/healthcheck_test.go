@@ -0,0 +1,103 @@
+package rules
+
+import "testing"
+
+func TestMissingHealthcheck(t *testing.T) {
+	runRuleTests(t, "missing-healthcheck", []ruleTest{
+		{
+			name: "server image without a healthcheck",
+			dockerfile: `FROM nginx:1.25
+COPY site /usr/share/nginx/html`,
+			want: []string{"The image runs nginx but defines no HEALTHCHECK"},
+		},
+		{
+			name: "exposed port gets a template",
+			dockerfile: `FROM node:20
+EXPOSE 3000
+CMD ["node", "server.js"]`,
+			want: []string{"The image exposes port 3000 but defines no HEALTHCHECK"},
+			alternate: `FROM node:20
+EXPOSE 3000
+HEALTHCHECK --interval=30s --timeout=5s --start-period=10s --retries=3 CMD curl -fsS http://localhost:3000/ || exit 1
+CMD ["node", "server.js"]`,
+		},
+		{
+			name: "healthcheck present",
+			dockerfile: `FROM node:20
+EXPOSE 3000
+HEALTHCHECK CMD curl -fsS http://localhost:3000/ || exit 1
+CMD ["node", "server.js"]`,
+		},
+		{
+			name: "batch job",
+			dockerfile: `FROM python:3.12
+CMD ["python", "job.py"]`,
+		},
+	})
+}
+
+func TestHealthcheckBinary(t *testing.T) {
+	runRuleTests(t, "healthcheck-binary", []ruleTest{
+		{
+			name: "curl on alpine suggests wget",
+			dockerfile: `FROM node:20-alpine
+HEALTHCHECK CMD curl -f http://localhost:3000/ || exit 1`,
+			want: []string{"HEALTHCHECK runs curl, which node:20-alpine does not include, so the container never turns healthy; use wget -q --spider instead"},
+		},
+		{
+			name: "curl on a slim image",
+			dockerfile: `FROM debian:bookworm-slim
+HEALTHCHECK CMD curl -f http://localhost/ || exit 1`,
+			want: []string{"install curl in the final stage"},
+		},
+		{
+			name: "curl installed in the stage",
+			dockerfile: `FROM debian:bookworm-slim
+RUN apt-get update && apt-get install -y --no-install-recommends curl && rm -rf /var/lib/apt/lists/*
+HEALTHCHECK CMD curl -f http://localhost/ || exit 1`,
+		},
+	})
+}
+
+func TestHealthcheckTiming(t *testing.T) {
+	runRuleTests(t, "healthcheck-timing", []ruleTest{
+		{
+			name: "checks too often with a short timeout",
+			dockerfile: `FROM nginx
+HEALTHCHECK --interval=1s --timeout=500ms CMD true`,
+			want: []string{
+				"--interval=1s runs the check so often that it loads the service",
+				"--timeout=500ms fails the check whenever the service is briefly slow",
+			},
+		},
+		{
+			name: "timeout longer than the interval",
+			dockerfile: `FROM nginx
+HEALTHCHECK --interval=30s --timeout=60s CMD true`,
+			want: []string{"--timeout=60s is longer than the 30s interval"},
+		},
+		{
+			name: "invalid duration",
+			dockerfile: `FROM nginx
+HEALTHCHECK --interval=30 CMD true`,
+			want: []string{"--interval=30 is not a valid duration"},
+		},
+		{
+			name: "option value after a space",
+			dockerfile: `FROM nginx
+HEALTHCHECK --interval 30s --timeout=5s CMD true`,
+			want: []string{"--interval 30s is not accepted; write --interval=30s"},
+		},
+		{
+			name: "unknown option",
+			dockerfile: `FROM nginx
+HEALTHCHECK --every=30s CMD true`,
+			want: []string{"HEALTHCHECK has no --every option"},
+		},
+		{
+			name: "typical values",
+			dockerfile: `FROM nginx
+HEALTHCHECK --interval=30s --timeout=5s --start-period=10s --retries=3 CMD true`,
+		},
+	})
+}
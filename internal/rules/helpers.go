@@ -0,0 +1,90 @@
+package rules
+
+import (
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// newWarning creates a warning for inst at the rule's default severity
+func newWarning(rule Rule, inst *parser.Instruction, message string) parser.Warning {
+	return parser.Warning{
+		Level:    rule.DefaultSeverity(),
+		Message:  message,
+		Position: inst.Range.Start,
+		Context:  instructionSummary(inst),
+		RuleID:   rule.ID(),
+	}
+}
+
+// instructionSummary renders a short one-line form of inst for Warning.Context
+func instructionSummary(inst *parser.Instruction) string {
+	summary := inst.Command
+	if len(inst.Args) > 0 {
+		summary += " " + strings.Join(inst.Args, " ")
+	}
+	if len(summary) > 120 {
+		summary = summary[:117] + "..."
+	}
+	return summary
+}
+
+// allInstructions returns every instruction in source order
+func allInstructions(df *parser.ParsedDockerfile) []*parser.Instruction {
+	result := make([]*parser.Instruction, 0)
+	for _, stage := range df.Stages {
+		for i := range stage.Instructions {
+			result = append(result, &stage.Instructions[i])
+		}
+	}
+	return result
+}
+
+// instructionEndLine returns the last source line of inst, including any
+// heredoc body
+func instructionEndLine(inst *parser.Instruction) int {
+	end := inst.Range.End.Line
+	if inst.Heredoc != nil && inst.Heredoc.Range.End.Line > end {
+		end = inst.Heredoc.Range.End.Line
+	}
+	if end < inst.Range.Start.Line {
+		end = inst.Range.Start.Line
+	}
+	return end
+}
+
+// sourceLines splits the raw Dockerfile into lines, indexed from zero
+func sourceLines(df *parser.ParsedDockerfile) []string {
+	return strings.Split(df.Raw, "\n")
+}
+
+// lineText returns the 1-based line, or "" when out of range
+func lineText(lines []string, line int) string {
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return lines[line-1]
+}
+
+// isCommentLine reports whether a source line is a Dockerfile comment
+func isCommentLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
+
+// commentBlockStart returns the first line of the comment block directly
+// above line, or line itself when there is none
+func commentBlockStart(lines []string, line int) int {
+	start := line
+	for start > 1 && isCommentLine(lineText(lines, start-1)) {
+		start--
+	}
+	return start
+}
+
+// continuationSuffix returns the line continuation marker for df
+func continuationSuffix(df *parser.ParsedDockerfile) string {
+	if df.EscapeChar == '`' {
+		return " `"
+	}
+	return " \\"
+}
@@ -0,0 +1,352 @@
+package rules
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/cachesim"
+	"github.com/yourusername/dockerfile-parser/internal/catalog"
+	"github.com/yourusername/dockerfile-parser/internal/image"
+	"github.com/yourusername/dockerfile-parser/internal/lexer"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// LayerEstimate is the estimated size contribution of one layer
+type LayerEstimate struct {
+	Instruction *parser.Instruction
+	SizeMB      float64
+	Basis       string // How the size was estimated
+	Measured    bool   // Whether the size was read from the build context
+}
+
+// StageImpact lists the layers a stage adds on top of its base
+type StageImpact struct {
+	Stage     *parser.Stage
+	BaseMB    float64 // Size of the base image or stage
+	BaseKnown bool    // False when the base image is not in the catalog
+	Layers    []LayerEstimate
+}
+
+// LayersMB returns the size added by the stage's own layers
+func (s StageImpact) LayersMB() float64 {
+	total := 0.0
+	for _, l := range s.Layers {
+		total += l.SizeMB
+	}
+	return total
+}
+
+// TotalMB returns the estimated size of the stage's image
+func (s StageImpact) TotalMB() float64 {
+	return s.BaseMB + s.LayersMB()
+}
+
+// Typical installed sizes of packages that dominate image size. Names are
+// matched without version specifiers.
+var knownPackageMB = map[string]float64{
+	"build-essential": 200, "gcc": 100, "g++": 60, "clang": 250, "llvm": 200,
+	"cmake": 40, "make": 2, "git": 30, "curl": 5, "wget": 3, "ca-certificates": 1,
+	"openjdk-17-jdk": 300, "openjdk-21-jdk": 320, "openjdk-11-jdk": 280, "default-jdk": 300,
+	"openjdk17": 300, "openjdk21": 320, "java-17-openjdk-devel": 300,
+	"python3": 30, "python3-dev": 40, "python3-pip": 50, "python3-venv": 5, "python": 30,
+	"nodejs": 60, "npm": 30, "golang": 400, "go": 400, "rustc": 250, "cargo": 100,
+	"texlive": 500, "texlive-full": 4000, "chromium": 300, "chromium-browser": 300,
+	"firefox-esr": 250, "libreoffice": 800, "ffmpeg": 150, "imagemagick": 40,
+	"postgresql-client": 15, "mysql-client": 20, "vim": 35, "openssh-client": 5,
+	"awscli": 80, "google-cloud-sdk": 500,
+	"torch": 1800, "tensorflow": 1200, "numpy": 60, "pandas": 70, "scipy": 100,
+	"opencv-python": 90, "puppeteer": 300, "playwright": 200, "typescript": 20,
+}
+
+// Per-package defaults for packages not in knownPackageMB
+var defaultPackageMB = map[string]float64{
+	"apt-get": 10, "apt": 10, "apk": 3, "yum": 10, "dnf": 10, "microdnf": 10,
+	"pip": 5, "pip3": 5, "npm": 10, "yarn": 10, "gem": 5,
+}
+
+// Sizes of steps that are not package installs
+const (
+	dependencyTreeMB = 150.0 // npm ci, yarn install or pip -r without package names
+	downloadMB       = 20.0  // curl or wget saving a file
+	cloneMB          = 30.0  // git clone
+	buildOutputMB    = 50.0  // Compiler output and intermediate files
+	smallChangeMB    = 0.1   // RUN steps that only touch configuration
+	contextCopyMB    = 50.0  // COPY of the whole context without BuildContext
+	contextFileMB    = 1.0   // COPY of named files without BuildContext
+	stageArtifactMB  = 20.0  // COPY --from another stage or image
+)
+
+// ImpactReport estimates the size of every layer-creating instruction in
+// each stage. COPY and ADD sources are measured when ParseOptions.BuildContext
+// is set; everything else uses heuristics.
+func ImpactReport(df *parser.ParsedDockerfile) []StageImpact {
+	report := make([]StageImpact, 0, len(df.Stages))
+	totals := make(map[*parser.Stage]float64)
+
+	for _, stage := range df.Stages {
+		impact := StageImpact{Stage: stage}
+		switch {
+		case stage.BaseStage != nil:
+			impact.BaseMB, impact.BaseKnown = totals[stage.BaseStage], true
+		default:
+			impact.BaseMB, impact.BaseKnown = baseImageMB(stage.BaseImage)
+		}
+
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			if inst.Command == "FROM" || !lexer.InstructionImpact(inst.Command).LayerCreating {
+				continue
+			}
+			var layer LayerEstimate
+			switch inst.Command {
+			case "RUN":
+				layer = estimateRun(df, stage, inst)
+			case "COPY", "ADD":
+				layer = estimateCopy(df, inst)
+			}
+			layer.Instruction = inst
+			impact.Layers = append(impact.Layers, layer)
+		}
+
+		totals[stage] = impact.TotalMB()
+		report = append(report, impact)
+	}
+	return report
+}
+
+// baseImageMB looks up the size of a registry image in the catalog
+func baseImageMB(name string) (float64, bool) {
+	if strings.EqualFold(name, "scratch") {
+		return 0, true
+	}
+	ref, err := image.ParseReference(name)
+	if err != nil {
+		return 0, false
+	}
+	m, ok := catalog.Default().Lookup(ref)
+	if !ok {
+		return 0, false
+	}
+	return float64(m.Image.SizeMB), true
+}
+
+// estimateRun sums the heuristics for each command of a RUN
+func estimateRun(df *parser.ParsedDockerfile, stage *parser.Stage, inst *parser.Instruction) LayerEstimate {
+	commands := instructionCommands(df, inst)
+	workdir := workdirAt(stage, instructionIndex(stage, inst))
+	total := 0.0
+	basis := make([]string, 0)
+
+	for _, c := range commands {
+		if pm := managerFor(c); pm != nil {
+			mb, what := packageInstallMB(c)
+			total += mb
+			basis = append(basis, what)
+			continue
+		}
+		if ecosystem, ok := installsDependencies(c); ok {
+			total += dependencyTreeMB
+			basis = append(basis, ecosystem+" dependencies")
+			continue
+		}
+		switch {
+		case (c.name == "curl" && (c.hasShortFlag('o') || c.hasShortFlag('O') || c.hasFlag("--output", "--remote-name"))) ||
+			(c.name == "wget" && !c.hasFlag("-qO-", "-O-") && flagValue(c.args, "-O") != "-"):
+			total += downloadMB
+			basis = append(basis, "download")
+		case c.name == "git" && c.subcommand() == "clone":
+			total += cloneMB
+			basis = append(basis, "git clone")
+		default:
+			if step, ok := recognizeBuild(c, workdir, stage); ok {
+				total += buildOutputMB
+				basis = append(basis, step.label+" output")
+			}
+		}
+	}
+
+	for _, f := range packageFindings(stage, inst, commands) {
+		total += float64(f.manager.wasteMB)
+		basis = append(basis, f.manager.leftover)
+	}
+
+	if len(basis) == 0 {
+		return LayerEstimate{SizeMB: smallChangeMB, Basis: "no large files expected"}
+	}
+	return LayerEstimate{SizeMB: total, Basis: strings.Join(basis, ", ")}
+}
+
+// installsDependencies reports whether c installs a project's dependencies
+// from its manifests, such as go mod download or bundle install
+func installsDependencies(c command) (string, bool) {
+	for _, d := range dependencyInstalls {
+		if containsString(d.names, c.name) && d.manifests(c) != nil {
+			return d.ecosystem, true
+		}
+	}
+	return "", false
+}
+
+// packageInstallMB estimates what an install command adds
+func packageInstallMB(c command) (float64, string) {
+	packages := c.operands()
+	if len(packages) == 0 || (strings.HasPrefix(c.name, "pip") && c.hasFlag("-r", "--requirement")) {
+		if c.subcommand() == "upgrade" || c.subcommand() == "dist-upgrade" || c.subcommand() == "update" {
+			return 2 * defaultPackageMB[c.name], c.name + " " + c.subcommand()
+		}
+		return dependencyTreeMB, c.name + " dependencies"
+	}
+
+	total := 0.0
+	largest, largestMB := "", 0.0
+	for _, pkg := range packages {
+		mb, ok := knownPackageMB[packageName(pkg)]
+		if !ok {
+			mb = defaultPackageMB[c.name]
+		}
+		total += mb
+		if mb > largestMB {
+			largest, largestMB = packageName(pkg), mb
+		}
+	}
+
+	noun := "packages"
+	if len(packages) == 1 {
+		noun = "package"
+	}
+	what := fmt.Sprintf("%d %s %s", len(packages), c.name, noun)
+	if largestMB >= 50 {
+		what += fmt.Sprintf(" (%s ~%.0f MB)", largest, largestMB)
+	}
+	return total, what
+}
+
+// packageName strips version specifiers such as =1.2, ==1.2, >=1.2 and
+// @1.2 from a package argument
+func packageName(pkg string) string {
+	name := pkg
+	if i := strings.IndexAny(name, "=<>~!"); i > 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndexByte(name, '@'); i > 0 {
+		name = name[:i]
+	}
+	return strings.ToLower(name)
+}
+
+// estimateCopy measures COPY and ADD sources in the build context, falling
+// back to heuristics when no context is available
+func estimateCopy(df *parser.ParsedDockerfile, inst *parser.Instruction) LayerEstimate {
+	if inst.Heredoc != nil {
+		return LayerEstimate{SizeMB: float64(len(inst.Heredoc.Content)) / (1 << 20), Basis: "heredoc", Measured: true}
+	}
+	if from, ok := inst.Flags["from"]; ok {
+		return LayerEstimate{SizeMB: stageArtifactMB, Basis: "files from " + from}
+	}
+	if len(inst.Args) < 2 {
+		return LayerEstimate{}
+	}
+
+	sources := make([]string, 0, len(inst.Args)-1)
+	for _, src := range inst.Args[:len(inst.Args)-1] {
+		sources = append(sources, strings.Trim(src, `[]",`))
+	}
+
+	remote := 0.0
+	local := make([]string, 0, len(sources))
+	for _, src := range sources {
+		if kind := classifyAddSource(src); inst.Command == "ADD" && (kind == addRemoteURL || kind == addGitSource) {
+			remote += downloadMB
+			continue
+		}
+		local = append(local, src)
+	}
+	if len(local) == 0 {
+		return LayerEstimate{SizeMB: remote, Basis: "remote source"}
+	}
+
+	if ctx := df.ParseOptions.BuildContext; ctx != "" {
+		bytes, err := contextBytes(ctx, local)
+		if err == nil {
+			return LayerEstimate{SizeMB: remote + float64(bytes)/(1<<20), Basis: "build context", Measured: true}
+		}
+	}
+
+	if copiesWholeContext(inst) {
+		return LayerEstimate{SizeMB: remote + contextCopyMB, Basis: "whole build context (estimate)"}
+	}
+	return LayerEstimate{SizeMB: remote + contextFileMB*float64(len(local)), Basis: fmt.Sprintf("%d context sources (estimate)", len(local))}
+}
+
+// contextBytes sums the sizes of the files matched by sources in the build
+// context directory, leaving out what .dockerignore excludes. Sources are
+// resolved relative to the context root. A source that matches nothing is
+// an error, since the build would fail on it too.
+func contextBytes(ctx string, sources []string) (int64, error) {
+	root, err := filepath.Abs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := os.Stat(root); err != nil {
+		return 0, err
+	}
+	ignore, err := cachesim.LoadIgnore(root)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, src := range sources {
+		if strings.Contains(src, "$") {
+			return 0, fmt.Errorf("source %s uses a variable", src)
+		}
+		pattern := filepath.Join(root, filepath.FromSlash(filepath.Clean("/"+src)))
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return 0, err
+		}
+		found := false
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				// Ignored directories are still walked, as ! can re-include
+				// files below them
+				if rel, err := filepath.Rel(root, p); err == nil && rel != "." && ignore.Ignored(filepath.ToSlash(rel)) {
+					return nil
+				}
+				found = true
+				if d.IsDir() {
+					return nil
+				}
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				total += info.Size()
+				return nil
+			})
+			if err != nil {
+				return 0, err
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("source %s matches nothing in the build context", src)
+		}
+	}
+	return total, nil
+}
+
+// instructionIndex returns the index of inst in the stage
+func instructionIndex(stage *parser.Stage, inst *parser.Instruction) int {
+	for i := range stage.Instructions {
+		if &stage.Instructions[i] == inst {
+			return i
+		}
+	}
+	return len(stage.Instructions)
+}
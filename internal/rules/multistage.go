@@ -0,0 +1,374 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/image"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(buildInFinalStageRule{})
+}
+
+// buildInFinalStageRule finds compilers and build steps in the stage that
+// becomes the image, and proposes moving them to a builder stage
+type buildInFinalStageRule struct{}
+
+func (buildInFinalStageRule) ID() string { return "build-in-final-stage" }
+
+func (buildInFinalStageRule) Description() string {
+	return "Build toolchains and build steps in the final stage ship in the runtime image"
+}
+
+func (buildInFinalStageRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+// Packages that only serve to compile software
+var toolchainPackages = map[string]bool{
+	"gcc": true, "g++": true, "make": true, "cmake": true, "clang": true,
+	"build-essential": true, "build-base": true, "musl-dev": true, "libc-dev": true,
+	"libc6-dev": true, "gcc-c++": true, "autoconf": true, "automake": true,
+	"golang": true, "golang-go": true, "maven": true, "gradle": true, "cargo": true,
+	"rustc": true, "openjdk-17-jdk": true, "default-jdk": true,
+}
+
+// Base images that are a language toolchain rather than a runtime
+var toolchainImages = map[string]bool{
+	"golang": true, "maven": true, "gradle": true, "rust": true, "gcc": true,
+}
+
+// buildStep is a RUN in the final stage that produces artifacts
+type buildStep struct {
+	kind      string   // go, node, java or native
+	label     string   // Command as shown in messages
+	index     int      // Instruction index in the stage
+	artifacts []string // Absolute paths of what the build produces
+	cgo       bool     // Go build that may link against libc
+}
+
+func (r buildInFinalStageRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	if len(df.Stages) == 0 {
+		return warnings
+	}
+	stage := df.Stages[len(df.Stages)-1]
+	if len(stage.Instructions) == 0 {
+		return warnings
+	}
+
+	found := installedToolchains(df, stage)
+	steps := buildSteps(df, stage)
+	for _, step := range steps {
+		found = append(found, step.label)
+	}
+	if ref, err := image.ParseReference(stage.BaseImage); err == nil && stage.BaseStage == nil && toolchainImages[ref.Repository()] {
+		found = append([]string{"toolchain base image " + stage.BaseImage}, found...)
+	}
+	if len(found) == 0 {
+		return warnings
+	}
+
+	from := &stage.Instructions[0]
+	if len(steps) == 0 {
+		// Without a recognised build step there are no artifacts to copy,
+		// so there is no stage split to propose
+		return append(warnings, newWarning(r, from, fmt.Sprintf(
+			"Final stage ships a build toolchain (%s); install it in a separate build stage and copy only what the image needs",
+			strings.Join(found, ", "))))
+	}
+
+	w := newWarning(r, from, fmt.Sprintf(
+		"Final stage builds the application and ships its toolchain (%s); build in a separate stage and copy only the artifacts",
+		strings.Join(found, ", ")))
+	if fix := ProposeMultiStage(df); fix != nil {
+		w.Alternatives = append(w.Alternatives, *fix)
+	}
+	return append(warnings, w)
+}
+
+// ProposeMultiStage rewrites the final stage as a builder stage running the
+// original instructions and a runtime stage that copies only the build
+// artifacts with COPY --from. It returns nil when no supported build step
+// is found. The result is a starting point: files other than the artifacts
+// are not carried over.
+func ProposeMultiStage(df *parser.ParsedDockerfile) *parser.Fix {
+	if len(df.Stages) == 0 {
+		return nil
+	}
+	stage := df.Stages[len(df.Stages)-1]
+	steps := buildSteps(df, stage)
+	if len(steps) == 0 || len(stage.Instructions) == 0 || stage.Instructions[0].JSONForm {
+		return nil
+	}
+	last := steps[len(steps)-1]
+	lines := sourceLines(df)
+
+	builder := "builder"
+	for _, other := range df.Stages {
+		if strings.EqualFold(other.Name, builder) {
+			builder = "build"
+		}
+	}
+
+	// Builder: the original stage up to its last build step
+	from := &stage.Instructions[0]
+	fromLine := lineText(lines, from.Range.Start.Line)
+	start, end := fromImageSpan(fromLine)
+	if start == end {
+		return nil
+	}
+	out := []string{fromLine[:end] + " AS " + builder}
+	for i := 1; i <= last.index; i++ {
+		out = append(out, instructionSource(lines, &stage.Instructions[i])...)
+	}
+
+	// Runtime: a slim base with the artifacts and the runtime configuration
+	runtime := "FROM " + runtimeImage(stage, last)
+	if stage.Name != "" {
+		runtime += " AS " + stage.Name
+	}
+	out = append(out, "", runtime)
+	if workdir := workdirAt(stage, last.index+1); workdir != "/" {
+		out = append(out, "WORKDIR "+workdir)
+	}
+	for i := 1; i <= last.index; i++ {
+		if stage.Instructions[i].Command == "ENV" {
+			out = append(out, instructionSource(lines, &stage.Instructions[i])...)
+		}
+	}
+	out = append(out, "# Copy any other files the application needs at runtime from the "+builder+" stage")
+	for _, step := range steps {
+		for _, artifact := range step.artifacts {
+			dest := artifact
+			if strings.ContainsAny(artifact, "*?[") {
+				dest = path.Dir(artifact) + "/"
+			}
+			out = append(out, fmt.Sprintf("COPY --from=%s %s %s", builder, artifact, dest))
+		}
+	}
+	for i := last.index + 1; i < len(stage.Instructions); i++ {
+		out = append(out, instructionSource(lines, &stage.Instructions[i])...)
+	}
+
+	first := from.Range.Start.Line
+	lastLine := instructionEndLine(&stage.Instructions[len(stage.Instructions)-1])
+	return &parser.Fix{
+		Description: "Split the final stage into a " + builder + " stage and a slim runtime stage",
+		Edits:       []parser.TextEdit{replaceLines(first, lastLine, strings.Join(out, "\n"))},
+	}
+}
+
+// instructionSource returns the source lines of inst with the comments
+// directly above it
+func instructionSource(lines []string, inst *parser.Instruction) []string {
+	result := make([]string, 0)
+	for n := commentBlockStart(lines, inst.Range.Start.Line); n <= instructionEndLine(inst); n++ {
+		result = append(result, lineText(lines, n))
+	}
+	return result
+}
+
+// installedToolchains returns the compiler packages installed in the stage
+func installedToolchains(df *parser.ParsedDockerfile, stage *parser.Stage) []string {
+	found := make([]string, 0)
+	for i := range stage.Instructions {
+		inst := &stage.Instructions[i]
+		if inst.Command != "RUN" {
+			continue
+		}
+		for _, c := range instructionCommands(df, inst) {
+			if managerFor(c) == nil {
+				continue
+			}
+			for _, pkg := range c.operands() {
+				// Drop version pins such as gcc=12.2.0-14
+				name := strings.FieldsFunc(pkg, func(r rune) bool { return r == '=' || r == '~' || r == '<' || r == '>' })
+				if len(name) > 0 && toolchainPackages[name[0]] && !containsString(found, name[0]) {
+					found = append(found, name[0])
+				}
+			}
+		}
+	}
+	return found
+}
+
+// buildSteps finds the RUN instructions of the stage that build artifacts
+func buildSteps(df *parser.ParsedDockerfile, stage *parser.Stage) []buildStep {
+	steps := make([]buildStep, 0)
+	for i := range stage.Instructions {
+		inst := &stage.Instructions[i]
+		if inst.Command != "RUN" {
+			continue
+		}
+		workdir := workdirAt(stage, i)
+		for _, c := range instructionCommands(df, inst) {
+			if step, ok := recognizeBuild(c, workdir, stage); ok {
+				step.index = i
+				steps = append(steps, step)
+			}
+		}
+	}
+	return steps
+}
+
+// recognizeBuild matches a command against the supported build tools
+func recognizeBuild(c command, workdir string, stage *parser.Stage) (buildStep, bool) {
+	inWorkdir := func(p string) string {
+		if path.IsAbs(p) {
+			return p
+		}
+		return path.Join(workdir, p)
+	}
+	guessBinary := func() string {
+		if workdir == "/" {
+			return "/app"
+		}
+		return path.Join(workdir, path.Base(workdir))
+	}
+
+	sub := c.subcommand()
+	switch c.name {
+	case "go":
+		if sub != "build" {
+			return buildStep{}, false
+		}
+		output := flagValue(c.args, "-o")
+		if output == "" {
+			output = guessBinary()
+		}
+		cgo := true
+		if v, ok := stage.Variables["CGO_ENABLED"]; ok && v.Value == "0" {
+			cgo = false
+		}
+		for _, a := range c.call.Assigns {
+			if a.Name == "CGO_ENABLED" && a.Value != nil && a.Value.Text() == "0" {
+				cgo = false
+			}
+		}
+		return buildStep{kind: "go", label: "go build", artifacts: []string{inWorkdir(output)}, cgo: cgo}, true
+
+	case "npm", "yarn", "pnpm":
+		script := sub
+		if sub == "run" || sub == "run-script" {
+			if ops := c.operands(); len(ops) > 0 {
+				script = ops[0]
+			}
+		}
+		if script != "build" {
+			return buildStep{}, false
+		}
+		return buildStep{kind: "node", label: c.name + " run build", artifacts: []string{
+			inWorkdir("package.json"), inWorkdir("node_modules"), inWorkdir("dist"),
+		}}, true
+
+	case "mvn", "mvnw":
+		for _, arg := range c.args {
+			if arg == "package" || arg == "install" || arg == "verify" {
+				return buildStep{kind: "java", label: "mvn " + arg, artifacts: []string{inWorkdir("target/*.jar")}}, true
+			}
+		}
+
+	case "gradle", "gradlew":
+		for _, arg := range c.args {
+			if arg == "build" || arg == "assemble" || arg == "bootJar" {
+				return buildStep{kind: "java", label: "gradle " + arg, artifacts: []string{inWorkdir("build/libs/*.jar")}}, true
+			}
+		}
+
+	case "cargo":
+		if sub != "build" && sub != "install" {
+			return buildStep{}, false
+		}
+		profile := "debug"
+		if c.hasFlag("--release") {
+			profile = "release"
+		}
+		binary := path.Base(guessBinary())
+		return buildStep{kind: "native", label: "cargo " + sub, artifacts: []string{inWorkdir("target/" + profile + "/" + binary)}}, true
+
+	case "make", "ninja":
+		dir := workdir
+		if d := flagValue(c.args, "-C"); d != "" {
+			dir = inWorkdir(d)
+		}
+		if containsString(c.args, "install") {
+			prefix := "/usr/local"
+			for _, arg := range c.args {
+				if v, ok := strings.CutPrefix(arg, "PREFIX="); ok && path.IsAbs(v) {
+					prefix = v
+				}
+			}
+			return buildStep{kind: "native", label: c.name + " install", artifacts: []string{prefix}}, true
+		}
+		return buildStep{kind: "native", label: c.name, artifacts: []string{path.Join(dir, path.Base(guessBinary()))}}, true
+
+	case "cmake":
+		if dir := flagValue(c.args, "--build"); dir != "" {
+			return buildStep{kind: "native", label: "cmake --build", artifacts: []string{inWorkdir(dir)}}, true
+		}
+		if c.hasFlag("--install") {
+			prefix := flagValue(c.args, "--prefix")
+			if !path.IsAbs(prefix) {
+				prefix = "/usr/local"
+			}
+			return buildStep{kind: "native", label: "cmake --install", artifacts: []string{prefix}}, true
+		}
+
+	case "gcc", "g++", "cc", "c++", "clang", "clang++":
+		if output := flagValue(c.args, "-o"); output != "" && !strings.HasSuffix(output, ".o") {
+			return buildStep{kind: "native", label: c.name, artifacts: []string{inWorkdir(output)}}, true
+		}
+	}
+	return buildStep{}, false
+}
+
+// flagValue returns the value of a flag given as "-o value" or "-o=value"
+func flagValue(args []string, flag string) string {
+	for i, arg := range args {
+		if arg == flag && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(arg, flag+"=") {
+			return arg[len(flag)+1:]
+		}
+	}
+	return ""
+}
+
+var javaVersionPattern = regexp.MustCompile(`(?:temurin|jdk|openjdk|java)-?(\d+)`)
+
+// runtimeImage picks a slim base for the artifacts of the last build step
+func runtimeImage(stage *parser.Stage, step buildStep) string {
+	ref, _ := image.ParseReference(stage.BaseImage)
+	switch step.kind {
+	case "go":
+		if !step.cgo {
+			return "gcr.io/distroless/static-debian12"
+		}
+		return "debian:bookworm-slim"
+	case "node":
+		if ref.Repository() == "node" {
+			tag := ref.TagOrDefault()
+			switch {
+			case strings.Contains(tag, "slim") || strings.Contains(tag, "alpine"):
+				return ref.Name + ":" + tag
+			case tag == image.DefaultTag:
+				return ref.Name + ":slim"
+			default:
+				return ref.Name + ":" + tag + "-slim"
+			}
+		}
+		return "node:lts-slim"
+	case "java":
+		version := "17"
+		if m := javaVersionPattern.FindStringSubmatch(ref.TagOrDefault()); m != nil {
+			version = m[1]
+		}
+		return "eclipse-temurin:" + version + "-jre"
+	default:
+		return "debian:bookworm-slim"
+	}
+}
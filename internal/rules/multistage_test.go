@@ -0,0 +1,95 @@
+package rules
+
+import "testing"
+
+func TestBuildInFinalStage(t *testing.T) {
+	runRuleTests(t, "build-in-final-stage", []ruleTest{
+		{
+			name: "go build on the golang image",
+			dockerfile: `FROM golang:1.22
+WORKDIR /src
+COPY . .
+RUN CGO_ENABLED=0 go build -o /out/app .
+EXPOSE 8080
+CMD ["/out/app"]`,
+			want: []string{"Final stage builds the application and ships its toolchain (toolchain base image golang:1.22, go build)"},
+			alternate: `FROM golang:1.22 AS builder
+WORKDIR /src
+COPY . .
+RUN CGO_ENABLED=0 go build -o /out/app .
+
+FROM gcr.io/distroless/static-debian12
+WORKDIR /src
+# Copy any other files the application needs at runtime from the builder stage
+COPY --from=builder /out/app /out/app
+EXPOSE 8080
+CMD ["/out/app"]`,
+		},
+		{
+			name: "node build",
+			dockerfile: `FROM node:20
+WORKDIR /app
+COPY . .
+RUN npm ci && npm run build
+CMD ["node", "dist/index.js"]`,
+			want: []string{"(npm run build)"},
+			alternate: `FROM node:20 AS builder
+WORKDIR /app
+COPY . .
+RUN npm ci && npm run build
+
+FROM node:20-slim
+WORKDIR /app
+# Copy any other files the application needs at runtime from the builder stage
+COPY --from=builder /app/package.json /app/package.json
+COPY --from=builder /app/node_modules /app/node_modules
+COPY --from=builder /app/dist /app/dist
+CMD ["node", "dist/index.js"]`,
+		},
+		{
+			name: "make install into a prefix",
+			dockerfile: `FROM debian:bookworm
+RUN apt-get update && apt-get install -y build-essential
+WORKDIR /src
+COPY . .
+RUN make install PREFIX=/opt/tool
+CMD ["/opt/tool/bin/tool"]`,
+			want: []string{"(build-essential, make install)"},
+			alternate: `FROM debian:bookworm AS builder
+RUN apt-get update && apt-get install -y build-essential
+WORKDIR /src
+COPY . .
+RUN make install PREFIX=/opt/tool
+
+FROM debian:bookworm-slim
+WORKDIR /src
+# Copy any other files the application needs at runtime from the builder stage
+COPY --from=builder /opt/tool /opt/tool
+CMD ["/opt/tool/bin/tool"]`,
+		},
+		{
+			name: "cmake build directory",
+			dockerfile: `FROM gcc:13
+WORKDIR /src
+COPY . .
+RUN cmake -S . -B build && cmake --build build`,
+			want: []string{"(toolchain base image gcc:13, cmake --build)"},
+		},
+		{
+			name: "toolchain without a recognised build step",
+			dockerfile: `FROM python:3.12
+RUN apt-get update && apt-get install -y gcc
+COPY . .
+RUN pip install -r requirements.txt`,
+			want: []string{"Final stage ships a build toolchain (gcc); install it in a separate build stage"},
+		},
+		{
+			name: "already multi-stage",
+			dockerfile: `FROM golang:1.22 AS build
+COPY . .
+RUN go build -o /app
+FROM alpine
+COPY --from=build /app /app`,
+		},
+	})
+}
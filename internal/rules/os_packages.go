@@ -0,0 +1,478 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/catalog"
+	"github.com/yourusername/dockerfile-parser/internal/image"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// OS package install hygiene. apk add without --no-cache is reported by
+// package-cache together with the other managers' caches.
+func init() {
+	Register(aptInstallFlagsRule{})
+	Register(aptUpdateLayerRule{})
+	Register(packageUpgradeRule{})
+	Register(unpinnedPackageRule{})
+	Register(packageManagerDistroRule{})
+}
+
+// OS package managers and the distribution family they belong to
+var osPackageManagers = map[string]string{
+	"apt-get": "debian", "apt": "debian",
+	"apk": "alpine",
+	"yum": "rhel", "dnf": "rhel", "microdnf": "rhel",
+	"zypper": "suse",
+}
+
+// Repositories of base images outside the catalog and their family
+var distroRepositories = map[string]string{
+	"centos": "rhel", "fedora": "rhel", "rockylinux": "rhel", "almalinux": "rhel",
+	"amazonlinux": "rhel", "oraclelinux": "rhel", "redhat/ubi8": "rhel", "redhat/ubi9": "rhel",
+	"ubi8/ubi": "rhel", "ubi9/ubi": "rhel", "ubi8/ubi-minimal": "rhel", "ubi9/ubi-minimal": "rhel",
+	"opensuse/leap": "suse", "opensuse/tumbleweed": "suse",
+	"buildpack-deps": "debian",
+}
+
+// osInstall is an OS package manager command in a RUN
+type osInstall struct {
+	inst *parser.Instruction
+	cmd  command
+}
+
+// osCommands returns the OS package manager commands of a stage in order
+func osCommands(df *parser.ParsedDockerfile, stage *parser.Stage) []osInstall {
+	result := make([]osInstall, 0)
+	for i := range stage.Instructions {
+		inst := &stage.Instructions[i]
+		if inst.Command != "RUN" {
+			continue
+		}
+		for _, c := range instructionCommands(df, inst) {
+			if _, ok := osPackageManagers[c.name]; ok {
+				result = append(result, osInstall{inst: inst, cmd: c})
+			}
+		}
+	}
+	return result
+}
+
+// isAptInstall reports whether c installs Debian packages
+func isAptInstall(c command) bool {
+	return (c.name == "apt-get" || c.name == "apt") && c.subcommand() == "install"
+}
+
+// aptInstallFlagsRule flags apt-get install without -y, which aborts
+// without a terminal, or without --no-install-recommends, which pulls in
+// optional packages
+type aptInstallFlagsRule struct{}
+
+func (aptInstallFlagsRule) ID() string { return "apt-install-flags" }
+
+func (aptInstallFlagsRule) Description() string {
+	return "apt-get install should use -y and --no-install-recommends"
+}
+
+func (aptInstallFlagsRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+func (r aptInstallFlagsRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+
+	for _, stage := range df.Stages {
+		for _, oc := range osCommands(df, stage) {
+			c := oc.cmd
+			if !isAptInstall(c) {
+				continue
+			}
+			missing := make([]string, 0, 2)
+			if !c.hasShortFlag('y') && !c.hasFlag("--yes", "--assume-yes") {
+				missing = append(missing, "-y")
+			}
+			if !c.hasFlag("--no-install-recommends") && !c.mentions("Install-Recommends") {
+				missing = append(missing, "--no-install-recommends")
+			}
+			if len(missing) == 0 {
+				continue
+			}
+
+			w := newWarning(r, oc.inst, fmt.Sprintf("%s install without %s", c.name, strings.Join(missing, " or ")))
+			w.Position = c.position()
+			if missing[0] == "-y" {
+				w.Message += "; the build aborts at the confirmation prompt"
+				w.Level = parser.WarnMedium
+			} else {
+				w.Message += " installs recommended packages the image does not need"
+			}
+			if fix := insertAfterWord(lines, c, "install", " "+strings.Join(missing, " ")); fix != nil {
+				fix.Description = "Add " + strings.Join(missing, " ") + " to " + c.name + " install"
+				w.Fix = fix
+			}
+			warnings = append(warnings, w)
+		}
+	}
+
+	return warnings
+}
+
+// insertAfterWord builds a fix inserting text after the first argument of
+// c equal to word. Heredoc commands are left alone.
+func insertAfterWord(lines []string, c command, word, text string) *parser.Fix {
+	idx := c.argIndex(word)
+	if idx < 0 || c.heredoc {
+		return nil
+	}
+	pos := c.wordEnd(idx)
+	line := lineText(lines, pos.Line)
+	if pos.Column < 1 || pos.Column > len(line)+1 {
+		return nil
+	}
+	return &parser.Fix{
+		Edits: []parser.TextEdit{replaceLines(pos.Line, pos.Line, line[:pos.Column-1]+text+line[pos.Column-1:])},
+	}
+}
+
+// aptUpdateLayerRule flags apt-get update and apt-get install in separate
+// RUN instructions. The update layer stays cached while the install line
+// changes, so installs run against stale package lists.
+type aptUpdateLayerRule struct{}
+
+func (aptUpdateLayerRule) ID() string { return "apt-update-layer" }
+
+func (aptUpdateLayerRule) Description() string {
+	return "apt-get update and apt-get install should run in the same RUN"
+}
+
+func (aptUpdateLayerRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+func (r aptUpdateLayerRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+
+	for _, stage := range df.Stages {
+		commands := osCommands(df, stage)
+		updated := make(map[*parser.Instruction]bool)
+		installs := make(map[*parser.Instruction]bool)
+		for _, oc := range commands {
+			if oc.cmd.name != "apt-get" && oc.cmd.name != "apt" {
+				continue
+			}
+			switch oc.cmd.subcommand() {
+			case "update":
+				updated[oc.inst] = true
+			case "install", "upgrade", "dist-upgrade", "full-upgrade":
+				installs[oc.inst] = true
+			}
+		}
+
+		// The update layer stays the source of the package lists for every
+		// later install, so later installs join its warning
+		var lastUpdate *parser.Instruction
+		updateWarning := -1
+		listed := false
+		reported := make(map[*parser.Instruction]bool)
+		for _, oc := range commands {
+			switch {
+			case updated[oc.inst] && !installs[oc.inst]:
+				lastUpdate, updateWarning, listed = oc.inst, -1, true
+
+			case updated[oc.inst]:
+				listed = true
+
+			case isAptInstall(oc.cmd) && !reported[oc.inst]:
+				reported[oc.inst] = true
+				related := parser.RelatedLocation{Position: oc.inst.Range.Start, Message: "installs without updating"}
+				if updateWarning >= 0 {
+					warnings[updateWarning].Related = append(warnings[updateWarning].Related, related)
+					continue
+				}
+				if lastUpdate != nil {
+					w := newWarning(r, lastUpdate, fmt.Sprintf(
+						"apt-get update runs in its own layer; once cached, the install at line %d uses stale package lists",
+						oc.inst.Range.Start.Line))
+					w.Related = []parser.RelatedLocation{related}
+					updateWarning = len(warnings)
+					warnings = append(warnings, w)
+					continue
+				}
+				if listed || stage.BaseStage != nil {
+					continue
+				}
+				w := newWarning(r, oc.inst, fmt.Sprintf(
+					"%s install runs without apt-get update; base images ship without package lists", oc.cmd.name))
+				w.Position = oc.cmd.position()
+				if fix := insertBeforeCommand(lines, oc.cmd, "apt-get update && "); fix != nil {
+					fix.Description = "Run apt-get update first"
+					w.Fix = fix
+				}
+				warnings = append(warnings, w)
+			}
+		}
+	}
+
+	return warnings
+}
+
+// insertBeforeCommand builds a fix inserting text where c starts
+func insertBeforeCommand(lines []string, c command, text string) *parser.Fix {
+	if c.heredoc {
+		return nil
+	}
+	pos := c.position()
+	line := lineText(lines, pos.Line)
+	if pos.Column < 1 || pos.Column > len(line)+1 {
+		return nil
+	}
+	return &parser.Fix{
+		Edits: []parser.TextEdit{replaceLines(pos.Line, pos.Line, line[:pos.Column-1]+text+line[pos.Column-1:])},
+	}
+}
+
+// packageUpgradeRule flags upgrades of every installed package, which make
+// the image depend on the day it was built instead of on its base image
+type packageUpgradeRule struct{}
+
+func (packageUpgradeRule) ID() string { return "package-upgrade" }
+
+func (packageUpgradeRule) Description() string {
+	return "Upgrading all OS packages makes builds unreproducible; update the base image instead"
+}
+
+func (packageUpgradeRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+// Subcommands that upgrade every installed package when given no operands
+var upgradeSubcommands = map[string][]string{
+	"apt-get":  {"upgrade", "dist-upgrade", "full-upgrade"},
+	"apt":      {"upgrade", "dist-upgrade", "full-upgrade"},
+	"apk":      {"upgrade"},
+	"yum":      {"update", "upgrade"},
+	"dnf":      {"update", "upgrade", "distro-sync"},
+	"microdnf": {"update", "upgrade"},
+	"zypper":   {"update", "up", "dist-upgrade", "dup"},
+}
+
+func (r packageUpgradeRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	for _, stage := range df.Stages {
+		for _, oc := range osCommands(df, stage) {
+			c := oc.cmd
+			sub := c.subcommand()
+			if !containsString(upgradeSubcommands[c.name], sub) || len(c.operands()) > 0 {
+				continue
+			}
+			w := newWarning(r, oc.inst, fmt.Sprintf(
+				"%s %s upgrades every package at build time, so rebuilds of the same Dockerfile differ; "+
+					"use a newer base image tag instead", c.name, sub))
+			w.Position = c.position()
+			warnings = append(warnings, w)
+		}
+	}
+
+	return warnings
+}
+
+// unpinnedPackageRule flags OS packages installed without a version. Most
+// images track their base distribution release instead, so it is opt-in.
+type unpinnedPackageRule struct{}
+
+func (unpinnedPackageRule) ID() string { return "unpinned-package" }
+
+func (unpinnedPackageRule) Description() string {
+	return "OS packages should be installed at pinned versions"
+}
+
+func (unpinnedPackageRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+func (unpinnedPackageRule) OptIn() bool { return true }
+
+func (r unpinnedPackageRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	for _, stage := range df.Stages {
+		for _, oc := range osCommands(df, stage) {
+			c := oc.cmd
+			if !isPackageInstall(c) {
+				continue
+			}
+			unpinned := make([]string, 0)
+			for _, pkg := range c.operands() {
+				if !packagePinned(c.name, pkg) {
+					unpinned = append(unpinned, pkg)
+				}
+			}
+			if len(unpinned) == 0 {
+				continue
+			}
+			w := newWarning(r, oc.inst, fmt.Sprintf("%s installs %s without a version (%s)",
+				c.name, strings.Join(unpinned, ", "), pinSyntax(c.name)))
+			w.Position = c.position()
+			warnings = append(warnings, w)
+		}
+	}
+
+	return warnings
+}
+
+// isPackageInstall reports whether c installs named OS packages
+func isPackageInstall(c command) bool {
+	switch c.name {
+	case "apk":
+		return c.subcommand() == "add"
+	case "zypper":
+		return c.subcommand() == "install" || c.subcommand() == "in"
+	}
+	return c.subcommand() == "install"
+}
+
+// packagePinned reports whether a package operand names a version, or is
+// not a repository package at all
+func packagePinned(manager, pkg string) bool {
+	if strings.ContainsAny(pkg, "$/") || strings.HasSuffix(pkg, ".deb") || strings.HasSuffix(pkg, ".rpm") {
+		return true
+	}
+	switch osPackageManagers[manager] {
+	case "debian":
+		return strings.Contains(pkg, "=")
+	case "alpine":
+		return strings.ContainsAny(pkg, "=~<>")
+	default:
+		// name-1.2.3 or name-1.2.3-4.el9
+		for i := 0; i+1 < len(pkg); i++ {
+			if pkg[i] == '-' && pkg[i+1] >= '0' && pkg[i+1] <= '9' {
+				return true
+			}
+		}
+		return strings.ContainsAny(pkg, "=<>")
+	}
+}
+
+// pinSyntax shows how a manager pins versions
+func pinSyntax(manager string) string {
+	switch osPackageManagers[manager] {
+	case "debian":
+		return "pin as name=version"
+	case "alpine":
+		return "pin as name=version or name~version"
+	default:
+		return "pin as name-version"
+	}
+}
+
+// packageManagerDistroRule flags package managers that do not belong to the
+// base image's distribution, and stages that mix managers of several
+// distributions
+type packageManagerDistroRule struct{}
+
+func (packageManagerDistroRule) ID() string { return "package-manager-distro" }
+
+func (packageManagerDistroRule) Description() string {
+	return "The package manager should match the base image distribution"
+}
+
+func (packageManagerDistroRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+func (r packageManagerDistroRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	for _, stage := range df.Stages {
+		distro := baseDistro(stage)
+		family := distro
+		if family == "ubuntu" {
+			family = "debian"
+		}
+
+		var first *osInstall
+		reported := make(map[string]bool)
+		commands := osCommands(df, stage)
+		for i := range commands {
+			oc := &commands[i]
+			manager := osPackageManagers[oc.cmd.name]
+			if reported[oc.cmd.name] {
+				continue
+			}
+
+			switch {
+			case distro == "distroless" || distro == "none":
+				reported[oc.cmd.name] = true
+				w := newWarning(r, oc.inst, fmt.Sprintf("%s is not available: base image %s has no package manager",
+					oc.cmd.name, rootBase(stage).BaseImage))
+				w.Position = oc.cmd.position()
+				warnings = append(warnings, w)
+
+			case family != "" && manager != family:
+				reported[oc.cmd.name] = true
+				w := newWarning(r, oc.inst, fmt.Sprintf("%s belongs to %s, but base image %s is %s",
+					oc.cmd.name, distroFamilyName(manager), rootBase(stage).BaseImage, distroFamilyName(family)))
+				w.Position = oc.cmd.position()
+				warnings = append(warnings, w)
+
+			case first == nil:
+				first = oc
+
+			case family == "" && osPackageManagers[first.cmd.name] != manager:
+				reported[oc.cmd.name] = true
+				w := newWarning(r, oc.inst, fmt.Sprintf("%s belongs to %s, but the stage already uses %s at line %d",
+					oc.cmd.name, distroFamilyName(manager), first.cmd.name, first.inst.Range.Start.Line))
+				w.Position = oc.cmd.position()
+				w.Related = []parser.RelatedLocation{{Position: first.cmd.position(), Message: first.cmd.name + " used here"}}
+				warnings = append(warnings, w)
+			}
+		}
+	}
+
+	return warnings
+}
+
+// rootBase returns the stage whose FROM names an image the stage builds on
+func rootBase(stage *parser.Stage) *parser.Stage {
+	seen := make(map[*parser.Stage]bool)
+	for stage.BaseStage != nil && !seen[stage] {
+		seen[stage] = true
+		stage = stage.BaseStage
+	}
+	return stage
+}
+
+// baseDistro determines the distribution of a stage's base image from the
+// catalog, well-known repositories or the tag, or "" when unknown
+func baseDistro(stage *parser.Stage) string {
+	ref, err := image.ParseReference(rootBase(stage).BaseImage)
+	if err != nil || strings.Contains(ref.Name, "$") {
+		return ""
+	}
+	if distro := catalog.Default().Distro(ref); distro != "" {
+		return distro
+	}
+
+	repo := strings.TrimPrefix(ref.Repository(), "library/")
+	if distro, ok := distroRepositories[repo]; ok {
+		return distro
+	}
+	tag := strings.ToLower(ref.Tag)
+	switch {
+	case strings.Contains(tag, "alpine"):
+		return "alpine"
+	case strings.Contains(tag, "jammy"), strings.Contains(tag, "noble"), strings.Contains(tag, "focal"):
+		return "ubuntu"
+	case strings.Contains(tag, "bookworm"), strings.Contains(tag, "bullseye"),
+		strings.Contains(tag, "buster"), strings.Contains(tag, "trixie"):
+		return "debian"
+	case strings.HasPrefix(tag, "ubi"), strings.Contains(tag, "-ubi"):
+		return "rhel"
+	}
+	return ""
+}
+
+// distroFamilyName describes a distribution family in messages
+func distroFamilyName(family string) string {
+	names := map[string]string{
+		"debian": "Debian/Ubuntu", "alpine": "Alpine", "rhel": "RHEL/Fedora", "suse": "SUSE",
+	}
+	if name, ok := names[family]; ok {
+		return name
+	}
+	return family
+}
@@ -0,0 +1,122 @@
+package rules
+
+import "testing"
+
+func TestAptInstallFlags(t *testing.T) {
+	runRuleTests(t, "apt-install-flags", []ruleTest{
+		{
+			name: "no -y",
+			dockerfile: `FROM debian
+RUN apt-get update && apt-get install curl`,
+			want: []string{"apt-get install without -y or --no-install-recommends; the build aborts at the confirmation prompt"},
+			fixed: `FROM debian
+RUN apt-get update && apt-get install -y --no-install-recommends curl`,
+		},
+		{
+			name: "recommended packages",
+			dockerfile: `FROM debian
+RUN apt-get update && apt-get install -y curl`,
+			want: []string{"apt-get install without --no-install-recommends installs recommended packages"},
+			fixed: `FROM debian
+RUN apt-get update && apt-get install --no-install-recommends -y curl`,
+		},
+		{
+			name: "both flags",
+			dockerfile: `FROM debian
+RUN apt-get update && apt-get install -y --no-install-recommends curl`,
+		},
+	})
+}
+
+func TestAptUpdateLayer(t *testing.T) {
+	runRuleTests(t, "apt-update-layer", []ruleTest{
+		{
+			name: "install without update",
+			dockerfile: `FROM debian
+RUN apt-get install -y curl`,
+			want: []string{"apt-get install runs without apt-get update; base images ship without package lists"},
+			fixed: `FROM debian
+RUN apt-get update && apt-get install -y curl`,
+		},
+		{
+			name: "update in its own layer covers every later install",
+			dockerfile: `FROM debian
+RUN apt-get update
+RUN apt-get install -y curl
+RUN apt-get install -y git`,
+			want: []string{"apt-get update runs in its own layer; once cached, the install at line 3 uses stale package lists"},
+		},
+		{
+			name: "install after an update in the same stage",
+			dockerfile: `FROM debian
+RUN apt-get update && apt-get install -y curl
+RUN apt-get install -y git`,
+		},
+		{
+			name: "stage built on an earlier stage",
+			dockerfile: `FROM debian AS base
+RUN apt-get update && apt-get install -y curl
+FROM base
+RUN apt-get install -y git`,
+		},
+	})
+}
+
+func TestPackageUpgrade(t *testing.T) {
+	runRuleTests(t, "package-upgrade", []ruleTest{
+		{
+			name: "apt-get upgrade",
+			dockerfile: `FROM debian
+RUN apt-get update && apt-get upgrade -y && apt-get install -y curl`,
+			want: []string{"apt-get upgrade upgrades every package at build time"},
+		},
+		{
+			name: "apk upgrade",
+			dockerfile: `FROM alpine
+RUN apk upgrade --no-cache`,
+			want: []string{"apk upgrade"},
+		},
+		{
+			name: "install only",
+			dockerfile: `FROM debian
+RUN apt-get update && apt-get install -y curl`,
+		},
+	})
+}
+
+func TestUnpinnedPackage(t *testing.T) {
+	runRuleTests(t, "unpinned-package", []ruleTest{
+		{
+			name: "apt package without a version",
+			dockerfile: `FROM debian
+RUN apt-get update && apt-get install -y curl=7.88.1-10 git`,
+			want: []string{"apt-get installs git without a version (pin as name=version)"},
+		},
+		{
+			name: "pinned packages",
+			dockerfile: `FROM debian
+RUN apt-get update && apt-get install -y curl=7.88.1-10`,
+		},
+	})
+}
+
+func TestPackageManagerDistro(t *testing.T) {
+	runRuleTests(t, "package-manager-distro", []ruleTest{
+		{
+			name: "apt-get on alpine",
+			dockerfile: `FROM alpine:3.19
+RUN apt-get update && apt-get install -y curl`,
+			want: []string{"apt-get belongs to Debian/Ubuntu, but base image alpine:3.19 is Alpine"},
+		},
+		{
+			name: "apk on alpine",
+			dockerfile: `FROM alpine:3.19
+RUN apk add --no-cache curl`,
+		},
+		{
+			name: "unknown base image",
+			dockerfile: `FROM example.com/custom:1
+RUN apk add --no-cache curl`,
+		},
+	})
+}
@@ -0,0 +1,183 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(overriddenInstructionRule{})
+	Register(duplicateInstructionRule{})
+}
+
+// Instructions of which only the last one in a stage takes effect
+var lastOneWins = map[string]bool{"CMD": true, "ENTRYPOINT": true, "HEALTHCHECK": true}
+
+// overriddenInstructionRule reports CMD, ENTRYPOINT and HEALTHCHECK
+// instructions that a later one in the same stage replaces
+type overriddenInstructionRule struct{}
+
+func (overriddenInstructionRule) ID() string { return "overridden-instruction" }
+
+func (overriddenInstructionRule) Description() string {
+	return "Only the last CMD, ENTRYPOINT and HEALTHCHECK of a stage take effect"
+}
+
+func (overriddenInstructionRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+func (r overriddenInstructionRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	for _, stage := range df.Stages {
+		last := make(map[string]*parser.Instruction)
+		for i := range stage.Instructions {
+			if inst := &stage.Instructions[i]; lastOneWins[inst.Command] {
+				last[inst.Command] = inst
+			}
+		}
+
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			winner, ok := last[inst.Command]
+			if !ok || winner == inst {
+				continue
+			}
+			w := newWarning(r, inst, fmt.Sprintf("%s has no effect; the %s at line %d replaces it",
+				inst.Command, inst.Command, winner.Range.Start.Line))
+			w.Related = []parser.RelatedLocation{{Position: winner.Range.Start, Message: "this " + inst.Command + " takes effect"}}
+			w.Fix = removeInstructionFix(inst)
+			warnings = append(warnings, w)
+		}
+	}
+
+	return warnings
+}
+
+// duplicateInstructionRule reports EXPOSE ports, LABEL keys and ENV keys
+// that are declared again before the first declaration matters
+type duplicateInstructionRule struct{}
+
+func (duplicateInstructionRule) ID() string { return "duplicate-instruction" }
+
+func (duplicateInstructionRule) Description() string {
+	return "EXPOSE ports, LABEL keys and ENV keys should be declared once"
+}
+
+func (duplicateInstructionRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+// declaration is one key declared by an EXPOSE, LABEL or ENV instruction
+type declaration struct {
+	inst  *parser.Instruction
+	index int // Instruction index in the stage
+}
+
+func (r duplicateInstructionRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	for _, stage := range df.Stages {
+		ports := make(map[string]declaration)
+		labels := make(map[string]declaration)
+		envs := make(map[string]declaration)
+
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			switch inst.Command {
+			case "EXPOSE":
+				dups := make([]string, 0)
+				var first *parser.Instruction
+				for _, port := range inst.Args {
+					key := normalizePort(port)
+					if prev, ok := ports[key]; ok {
+						dups = append(dups, port)
+						if first == nil {
+							first = prev.inst
+						}
+						continue
+					}
+					ports[key] = declaration{inst: inst, index: i}
+				}
+				if len(dups) == 0 {
+					continue
+				}
+				// The repeated EXPOSE is the redundant one
+				w := newWarning(r, inst, fmt.Sprintf("EXPOSE %s repeats the port already exposed at line %d",
+					strings.Join(dups, " "), first.Range.Start.Line))
+				w.Related = []parser.RelatedLocation{{Position: first.Range.Start, Message: "first exposed here"}}
+				if len(dups) == len(inst.Args) {
+					w.Fix = removeInstructionFix(inst)
+				}
+				warnings = append(warnings, w)
+
+			case "LABEL":
+				for _, pair := range inst.Args {
+					key, _, _ := strings.Cut(pair, "=")
+					key = strings.Trim(key, `"'`)
+					if prev, ok := labels[key]; ok {
+						warnings = append(warnings, r.overridden(prev.inst, inst, "LABEL", key))
+					}
+					labels[key] = declaration{inst: inst, index: i}
+				}
+
+			case "ENV":
+				for _, pair := range envPairs(inst) {
+					key, value := pair[0], pair[1]
+					prev, ok := envs[key]
+					if ok && !valueReferences(value, key) && !envUsedBetween(stage, prev.index, i, key) {
+						warnings = append(warnings, r.overridden(prev.inst, inst, "ENV", key))
+					}
+					envs[key] = declaration{inst: inst, index: i}
+				}
+			}
+		}
+	}
+
+	return warnings
+}
+
+// overridden reports the earlier declaration of key, pointing at the later
+// one that replaces it
+func (r duplicateInstructionRule) overridden(earlier, later *parser.Instruction, command, key string) parser.Warning {
+	w := newWarning(r, earlier, fmt.Sprintf("%s %s is redefined at line %d before anything uses this value",
+		command, key, later.Range.Start.Line))
+	if command == "LABEL" {
+		w.Message = fmt.Sprintf("LABEL %s is overridden at line %d", key, later.Range.Start.Line)
+	}
+	w.Related = []parser.RelatedLocation{{Position: later.Range.Start, Message: "the value set here wins"}}
+
+	// Only instructions that declare nothing else can go
+	if len(earlier.Args) == 1 {
+		w.Fix = removeInstructionFix(earlier)
+	}
+	return w
+}
+
+// envUsedBetween reports whether an instruction between from and to reads
+// the ENV key. Every RUN sees the environment, so a RUN counts as a use.
+func envUsedBetween(stage *parser.Stage, from, to int, key string) bool {
+	for i := from + 1; i < to; i++ {
+		inst := &stage.Instructions[i]
+		if inst.Command == "RUN" || referencesName(inst, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePort makes 80 and 80/tcp compare equal
+func normalizePort(port string) string {
+	port = strings.ToLower(port)
+	if !strings.Contains(port, "/") {
+		port += "/tcp"
+	}
+	return port
+}
+
+// removeInstructionFix deletes an instruction
+func removeInstructionFix(inst *parser.Instruction) *parser.Fix {
+	return &parser.Fix{
+		Description: fmt.Sprintf("Remove %s at line %d", inst.Command, inst.Range.Start.Line),
+		Edits:       []parser.TextEdit{deleteLines(inst.Range.Start.Line, instructionEndLine(inst))},
+	}
+}
@@ -0,0 +1,72 @@
+package rules
+
+import "testing"
+
+func TestOverriddenInstruction(t *testing.T) {
+	runRuleTests(t, "overridden-instruction", []ruleTest{
+		{
+			name: "second CMD replaces the first",
+			dockerfile: `FROM node:20
+CMD ["npm", "test"]
+WORKDIR /app
+CMD ["npm", "start"]`,
+			want: []string{"CMD has no effect; the CMD at line 4 replaces it"},
+			fixed: `FROM node:20
+WORKDIR /app
+CMD ["npm", "start"]`,
+		},
+		{
+			name: "ENTRYPOINT and HEALTHCHECK",
+			dockerfile: `FROM node:20
+ENTRYPOINT ["a"]
+ENTRYPOINT ["b"]
+HEALTHCHECK CMD true
+HEALTHCHECK CMD false`,
+			want: []string{
+				"ENTRYPOINT has no effect; the ENTRYPOINT at line 3 replaces it",
+				"HEALTHCHECK has no effect; the HEALTHCHECK at line 5 replaces it",
+			},
+			fixed: `FROM node:20
+ENTRYPOINT ["b"]
+HEALTHCHECK CMD false`,
+		},
+		{
+			name: "one CMD per stage",
+			dockerfile: `FROM node:20 AS test
+CMD ["npm", "test"]
+FROM node:20
+CMD ["npm", "start"]`,
+		},
+	})
+}
+
+func TestDuplicateInstruction(t *testing.T) {
+	runRuleTests(t, "duplicate-instruction", []ruleTest{
+		{
+			name: "repeated port, label and environment variable",
+			dockerfile: `FROM node:20
+EXPOSE 80
+EXPOSE 80
+LABEL team=web
+LABEL team=web
+ENV MODE=dev
+ENV MODE=prod`,
+			want: []string{
+				"EXPOSE 80 repeats the port already exposed at line 2",
+				"LABEL team is overridden at line 5",
+				"ENV MODE is redefined at line 7 before anything uses this value",
+			},
+			fixed: `FROM node:20
+EXPOSE 80
+LABEL team=web
+ENV MODE=prod`,
+		},
+		{
+			name: "environment variable used before it is redefined",
+			dockerfile: `FROM node:20
+ENV DIR=/app
+WORKDIR $DIR
+ENV DIR=/srv`,
+		},
+	})
+}
@@ -0,0 +1,424 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(packageCacheRule{})
+}
+
+// packageCacheRule finds package installs that leave download caches or
+// documentation in the RUN layer
+type packageCacheRule struct{}
+
+func (packageCacheRule) ID() string { return "package-cache" }
+
+func (packageCacheRule) Description() string {
+	return "Package manager caches left in a layer increase image size"
+}
+
+func (packageCacheRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+// packageManager describes how one package manager leaves files behind and
+// how to avoid it
+type packageManager struct {
+	names     []string // Program names
+	installs  []string // Subcommands that download packages
+	leftover  string   // What stays in the layer
+	wasteMB   int      // Typical size of the leftover
+	flag      string   // Install option that avoids the leftover, if any
+	cleanup   string   // Command that removes the leftover, if any
+	cacheDirs []string // Cache mount targets, if a cache mount applies
+	locked    bool     // Whether cache mounts need sharing=locked
+	noCache   string   // Environment variable that disables the cache
+	cleaned   func(install command, later []command) bool
+	bare      func(c command) bool // Whether a call without a subcommand installs; nil when none does
+}
+
+// Estimates are typical sizes for a handful of packages on common base
+// images; they are only meant to rank findings
+var packageManagers = []packageManager{
+	{
+		names:     []string{"apt-get", "apt"},
+		installs:  []string{"install", "dist-upgrade", "upgrade"},
+		leftover:  "apt package lists",
+		wasteMB:   40,
+		cleanup:   "rm -rf /var/lib/apt/lists/*",
+		cacheDirs: []string{"/var/cache/apt", "/var/lib/apt"},
+		locked:    true,
+		cleaned: func(_ command, later []command) bool {
+			return removes(later, "/var/lib/apt/lists")
+		},
+	},
+	{
+		names:     []string{"apk"},
+		installs:  []string{"add", "upgrade"},
+		leftover:  "apk index cache",
+		wasteMB:   3,
+		flag:      "--no-cache",
+		cacheDirs: []string{"/var/cache/apk"},
+		cleaned: func(install command, later []command) bool {
+			return install.hasFlag("--no-cache") || removes(later, "/var/cache/apk")
+		},
+	},
+	{
+		names:     []string{"yum"},
+		installs:  []string{"install", "update", "upgrade", "groupinstall"},
+		leftover:  "yum metadata and package cache",
+		wasteMB:   100,
+		cleanup:   "yum clean all && rm -rf /var/cache/yum",
+		cacheDirs: []string{"/var/cache/yum"},
+		cleaned: func(_ command, later []command) bool {
+			return runs(later, "yum", "clean") || removes(later, "/var/cache/yum")
+		},
+	},
+	{
+		names:     []string{"dnf", "microdnf"},
+		installs:  []string{"install", "update", "upgrade", "groupinstall"},
+		leftover:  "dnf metadata and package cache",
+		wasteMB:   100,
+		cleanup:   "dnf clean all",
+		cacheDirs: []string{"/var/cache/dnf"},
+		cleaned: func(_ command, later []command) bool {
+			return runs(later, "dnf", "clean") || runs(later, "microdnf", "clean") || removes(later, "/var/cache/dnf")
+		},
+	},
+	{
+		names:     []string{"pip", "pip3"},
+		installs:  []string{"install"},
+		leftover:  "pip download cache",
+		wasteMB:   50,
+		flag:      "--no-cache-dir",
+		cacheDirs: []string{"/root/.cache/pip"},
+		noCache:   "PIP_NO_CACHE_DIR",
+		cleaned: func(install command, later []command) bool {
+			return install.hasFlag("--no-cache-dir") || runs(later, "pip", "cache") ||
+				runs(later, "pip3", "cache") || removes(later, ".cache/pip")
+		},
+	},
+	{
+		names:     []string{"npm"},
+		installs:  []string{"install", "i", "ci", "add"},
+		leftover:  "npm cache",
+		wasteMB:   50,
+		cleanup:   "npm cache clean --force",
+		cacheDirs: []string{"/root/.npm"},
+		cleaned: func(_ command, later []command) bool {
+			return runs(later, "npm", "cache") || removes(later, ".npm")
+		},
+	},
+	{
+		names:     []string{"yarn"},
+		installs:  []string{"install", "add"},
+		bare:      yarnBareInstall,
+		leftover:  "yarn cache",
+		wasteMB:   50,
+		cleanup:   "yarn cache clean",
+		cacheDirs: []string{"/usr/local/share/.cache/yarn"},
+		cleaned: func(install command, later []command) bool {
+			return install.hasFlag("--cache-folder") || runs(later, "yarn", "cache") || removes(later, ".cache/yarn")
+		},
+	},
+	{
+		names:    []string{"gem"},
+		installs: []string{"install", "update"},
+		leftover: "gem documentation",
+		wasteMB:  10,
+		flag:     "--no-document",
+		cleaned: func(install command, _ []command) bool {
+			return install.hasFlag("--no-document", "--no-doc", "-N", "--no-rdoc", "--no-ri")
+		},
+	},
+}
+
+// packageFinding is one install that leaves files behind
+type packageFinding struct {
+	manager *packageManager
+	install command
+}
+
+func (r packageCacheRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+
+	for _, stage := range df.Stages {
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			if inst.Command != "RUN" {
+				continue
+			}
+
+			findings := packageFindings(stage, inst, instructionCommands(df, inst))
+			if len(findings) == 0 {
+				continue
+			}
+
+			leftovers := make([]string, 0, len(findings))
+			total := 0
+			for _, f := range findings {
+				leftovers = append(leftovers, fmt.Sprintf("%s (~%d MB)", f.manager.leftover, f.manager.wasteMB))
+				total += f.manager.wasteMB
+			}
+
+			w := newWarning(r, inst, fmt.Sprintf(
+				"RUN leaves %s in the layer, an estimated %d MB of waste",
+				strings.Join(leftovers, ", "), total))
+			w.Fix = cleanupFix(df, lines, inst, findings)
+			if mount := cacheMountFix(lines, inst, findings); mount != nil {
+				w.Alternatives = append(w.Alternatives, *mount)
+			}
+			warnings = append(warnings, w)
+		}
+	}
+
+	return warnings
+}
+
+// packageFindings matches each install command against the commands that
+// follow it in the same RUN
+func packageFindings(stage *parser.Stage, inst *parser.Instruction, commands []command) []packageFinding {
+	findings := make([]packageFinding, 0)
+	reported := make(map[*packageManager]bool)
+
+	for i, c := range commands {
+		pm := managerFor(c)
+		if pm == nil || reported[pm] {
+			continue
+		}
+		if pm.cleaned(c, commands[i+1:]) || cacheMounted(inst, pm) || envDisablesCache(stage, inst, pm) {
+			continue
+		}
+		reported[pm] = true
+		findings = append(findings, packageFinding{manager: pm, install: c})
+	}
+	return findings
+}
+
+// managerFor returns the package manager that c installs packages with
+func managerFor(c command) *packageManager {
+	for i := range packageManagers {
+		pm := &packageManagers[i]
+		if !containsString(pm.names, c.name) {
+			continue
+		}
+		if containsString(pm.installs, c.subcommand()) || (pm.bare != nil && pm.bare(c)) {
+			return pm
+		}
+	}
+	return nil
+}
+
+// Options of yarn install, and those of them that take a value
+var (
+	yarnInstallOptions = map[string]bool{
+		"--frozen-lockfile": true, "--pure-lockfile": true, "--immutable": true, "--immutable-cache": true,
+		"--production": true, "--prod": true, "--prefer-offline": true, "--offline": true,
+		"--ignore-scripts": true, "--ignore-engines": true, "--ignore-optional": true, "--ignore-platform": true,
+		"--non-interactive": true, "--silent": true, "--no-progress": true, "--check-files": true,
+		"--force": true, "--no-lockfile": true, "--check-cache": true, "--inline-builds": true,
+		"--network-timeout": true, "--cache-folder": true, "--modules-folder": true, "--mutex": true,
+		"--network-concurrency": true, "--registry": true,
+	}
+	yarnValueOptions = map[string]bool{
+		"--network-timeout": true, "--cache-folder": true, "--modules-folder": true, "--mutex": true,
+		"--network-concurrency": true, "--registry": true,
+	}
+)
+
+// yarnBareInstall reports whether yarn without a subcommand installs: with
+// no arguments, or with only install options. Options such as --version
+// run something else.
+func yarnBareInstall(c command) bool {
+	for i, arg := range c.args {
+		if !strings.HasPrefix(arg, "-") {
+			if i > 0 && yarnValueOptions[c.args[i-1]] {
+				continue
+			}
+			return false
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		if !yarnInstallOptions[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheMounted reports whether the RUN already mounts a cache over one of
+// the manager's cache directories
+func cacheMounted(inst *parser.Instruction, pm *packageManager) bool {
+	mounts := inst.Flags["mount"]
+	if !strings.Contains(mounts, "type=cache") {
+		return false
+	}
+	for _, dir := range pm.cacheDirs {
+		if strings.Contains(mounts, "target="+dir) || strings.Contains(mounts, "dst="+dir) ||
+			strings.Contains(mounts, "destination="+dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// envDisablesCache reports whether an earlier ENV in the stage turns the
+// manager's cache off
+func envDisablesCache(stage *parser.Stage, inst *parser.Instruction, pm *packageManager) bool {
+	if pm.noCache == "" {
+		return false
+	}
+	v, ok := stage.Variables[pm.noCache]
+	if !ok || v.Type != parser.EnvType || v.Position.Line > inst.Range.Start.Line {
+		return false
+	}
+	switch strings.ToLower(v.Value) {
+	case "", "0", "false", "no", "off":
+		return false
+	}
+	return true
+}
+
+// removes reports whether one of the commands deletes a path containing dir
+func removes(commands []command, dir string) bool {
+	for _, c := range commands {
+		if c.name == "rm" && c.mentions(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// runs reports whether one of the commands is name with the subcommand
+func runs(commands []command, name, subcommand string) bool {
+	for _, c := range commands {
+		if c.name == name && c.subcommand() == subcommand {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanupFix adds the install flags and cleanup commands for the findings.
+// Heredoc bodies, background jobs and trailing comments are left alone.
+func cleanupFix(df *parser.ParsedDockerfile, lines []string, inst *parser.Instruction, findings []packageFinding) *parser.Fix {
+	type insertion struct {
+		column int
+		text   string
+	}
+	inserts := make(map[int][]insertion)
+	cleanups := make([]string, 0)
+	endLine := 0
+
+	for _, f := range findings {
+		if f.install.heredoc {
+			return nil
+		}
+		script := f.install.script
+		if end := script.Position(script.End()).Line; end > endLine {
+			endLine = end
+		}
+		for _, stmt := range script.Stmts {
+			if stmt.Background {
+				return nil
+			}
+		}
+
+		if f.manager.flag != "" {
+			idx := f.install.argIndex(f.install.subcommand())
+			if idx < 0 {
+				return nil
+			}
+			pos := f.install.wordEnd(idx)
+			inserts[pos.Line] = append(inserts[pos.Line], insertion{column: pos.Column, text: " " + f.manager.flag})
+		} else {
+			cleanups = append(cleanups, f.manager.cleanup)
+		}
+	}
+
+	updated := make(map[int]string)
+	for line, list := range inserts {
+		text := lineText(lines, line)
+		sort.Slice(list, func(i, j int) bool { return list[i].column > list[j].column })
+		for _, ins := range list {
+			at := ins.column - 1
+			if at < 0 || at > len(text) {
+				return nil
+			}
+			text = text[:at] + ins.text + text[at:]
+		}
+		updated[line] = text
+	}
+
+	if len(cleanups) > 0 {
+		text, ok := updated[endLine]
+		if !ok {
+			text = lineText(lines, endLine)
+		}
+		if strings.Contains(text, " #") {
+			return nil
+		}
+		text = strings.TrimRight(text, " \t\r")
+		joiner := "&& "
+		if strings.HasSuffix(text, ";") {
+			joiner = ""
+		}
+		updated[endLine] = text + continuationSuffix(df) + "\n    " + joiner + strings.Join(cleanups, " && ")
+	}
+
+	changed := make([]int, 0, len(updated))
+	for line := range updated {
+		changed = append(changed, line)
+	}
+	sort.Ints(changed)
+
+	fix := &parser.Fix{Description: "Keep package manager caches out of the layer"}
+	for _, line := range changed {
+		fix.Edits = append(fix.Edits, replaceLines(line, line, updated[line]))
+	}
+	return fix
+}
+
+// cacheMountFix keeps the caches out of the image with BuildKit cache mounts,
+// which only works when every finding has a cache directory
+func cacheMountFix(lines []string, inst *parser.Instruction, findings []packageFinding) *parser.Fix {
+	mounts := make([]string, 0)
+	for _, f := range findings {
+		if len(f.manager.cacheDirs) == 0 {
+			return nil
+		}
+		for _, dir := range f.manager.cacheDirs {
+			mount := "--mount=type=cache,target=" + dir
+			if f.manager.locked {
+				mount += ",sharing=locked"
+			}
+			mounts = append(mounts, mount)
+		}
+	}
+
+	first := inst.Range.Start.Line
+	text := lineText(lines, first)
+	keyword := strings.Index(strings.ToUpper(text), "RUN")
+	if keyword < 0 {
+		return nil
+	}
+	at := keyword + len("RUN")
+	text = text[:at] + " " + strings.Join(mounts, " ") + text[at:]
+
+	return &parser.Fix{
+		Description: "Use BuildKit cache mounts so package caches never reach the layer",
+		Edits:       []parser.TextEdit{replaceLines(first, first, text)},
+	}
+}
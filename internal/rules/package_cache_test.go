@@ -0,0 +1,71 @@
+package rules
+
+import "testing"
+
+func TestPackageCache(t *testing.T) {
+	runRuleTests(t, "package-cache", []ruleTest{
+		{
+			name: "pip download cache",
+			dockerfile: `FROM python:3.12
+RUN pip install flask`,
+			want: []string{"RUN leaves pip download cache (~50 MB) in the layer"},
+			fixed: `FROM python:3.12
+RUN pip install --no-cache-dir flask`,
+			alternate: `FROM python:3.12
+RUN --mount=type=cache,target=/root/.cache/pip pip install flask`,
+		},
+		{
+			name: "npm cache",
+			dockerfile: `FROM node:20
+RUN npm ci`,
+			want: []string{"npm cache"},
+			fixed: `FROM node:20
+RUN npm ci \
+    && npm cache clean --force`,
+		},
+		{
+			name: "apt package lists",
+			dockerfile: `FROM debian
+RUN apt-get update && apt-get install -y curl`,
+			want: []string{"apt package lists (~40 MB)"},
+			fixed: `FROM debian
+RUN apt-get update && apt-get install -y curl \
+    && rm -rf /var/lib/apt/lists/*`,
+			alternate: `FROM debian
+RUN --mount=type=cache,target=/var/cache/apt,sharing=locked --mount=type=cache,target=/var/lib/apt,sharing=locked apt-get update && apt-get install -y curl`,
+		},
+		{
+			name: "apk index",
+			dockerfile: `FROM alpine
+RUN apk add curl`,
+			want: []string{"apk index cache"},
+			fixed: `FROM alpine
+RUN apk add --no-cache curl`,
+		},
+		{
+			name: "bare yarn installs",
+			dockerfile: `FROM node:20
+RUN yarn --frozen-lockfile`,
+			want: []string{"yarn cache"},
+			fixed: `FROM node:20
+RUN yarn --frozen-lockfile \
+    && yarn cache clean`,
+		},
+		{
+			name: "yarn options that do not install",
+			dockerfile: `FROM node:20
+RUN yarn --version`,
+		},
+		{
+			name: "caches already cleaned",
+			dockerfile: `FROM python:3.12
+RUN pip install --no-cache-dir flask
+RUN apt-get update && apt-get install -y curl && rm -rf /var/lib/apt/lists/*`,
+		},
+		{
+			name: "cache mount",
+			dockerfile: `FROM node:20
+RUN --mount=type=cache,target=/root/.npm npm ci`,
+		},
+	})
+}
@@ -0,0 +1,188 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+	"github.com/yourusername/dockerfile-parser/internal/shell"
+)
+
+func init() {
+	Register(pipefailRule{})
+}
+
+// SHELL line the fix inserts
+const pipefailShell = `SHELL ["/bin/bash", "-o", "pipefail", "-c"]`
+
+// pipefailRule flags RUN pipelines whose shell ignores failures of all but
+// the last command, so a failed download piped into tar still builds
+type pipefailRule struct{}
+
+func (pipefailRule) ID() string { return "pipefail" }
+
+func (pipefailRule) Description() string {
+	return "RUN pipelines should run with -o pipefail so that failures stop the build"
+}
+
+func (pipefailRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+func (r pipefailRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+
+	for _, stage := range df.Stages {
+		fixed := false
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			if inst.Command != "RUN" {
+				continue
+			}
+			shellArgs, _ := effectiveShell(stage, i)
+			if shellSetsPipefail(shellArgs) || !posixShell(shellArgs) {
+				continue
+			}
+
+			pipe, script := unguardedPipeline(df, inst)
+			if pipe == nil {
+				continue
+			}
+			stmts := shell.PipelineStmts(pipe)
+			w := newWarning(r, inst, fmt.Sprintf(
+				"Pipeline into %s succeeds even if an earlier command fails; the shell does not set -o pipefail",
+				stmtName(stmts[len(stmts)-1], script)))
+			w.Position = script.Position(pipe.Pos())
+
+			// One SHELL covers every later RUN of the stage
+			if !fixed && shellArgs == nil && hasBash(df, stage, i) {
+				fixed = true
+				at := commentBlockStart(lines, inst.Range.Start.Line)
+				w.Fix = &parser.Fix{
+					Description: "Run the stage's RUN instructions with bash -o pipefail",
+					Edits:       []parser.TextEdit{insertBefore(at, pipefailShell)},
+				}
+			}
+			warnings = append(warnings, w)
+		}
+	}
+
+	return warnings
+}
+
+// effectiveShell returns the arguments of the SHELL in effect for
+// instruction idx of the stage, or nil for the default /bin/sh -c
+func effectiveShell(stage *parser.Stage, idx int) ([]string, *parser.Instruction) {
+	for s, limit := stage, idx; s != nil; s = s.BaseStage {
+		for i := limit - 1; i >= 0; i-- {
+			if inst := &s.Instructions[i]; inst.Command == "SHELL" {
+				return inst.Args, inst
+			}
+		}
+		if s.BaseStage != nil {
+			limit = len(s.BaseStage.Instructions)
+		}
+	}
+	return nil, nil
+}
+
+// shellSetsPipefail reports whether SHELL arguments enable pipefail, as in
+// -o pipefail or -euo pipefail
+func shellSetsPipefail(args []string) bool {
+	for _, arg := range args {
+		if arg == "pipefail" || strings.Contains(arg, "-o pipefail") {
+			return true
+		}
+	}
+	return false
+}
+
+// posixShell reports whether SHELL arguments run a POSIX shell; Windows
+// shells such as cmd and powershell have no pipefail
+func posixShell(args []string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	switch strings.ToLower(args[0][strings.LastIndexAny(args[0], `/\`)+1:]) {
+	case "sh", "bash", "ash", "dash", "zsh", "ksh", "mksh":
+		return true
+	}
+	return false
+}
+
+// Commands whose failure in a pipeline does not matter
+var infallibleCommands = map[string]bool{"echo": true, "printf": true, "yes": true, "true": true}
+
+// unguardedPipeline returns the first pipeline of a RUN that is not
+// preceded by set -o pipefail in the same script, ignoring pipelines fed
+// only by commands such as echo that cannot fail
+func unguardedPipeline(df *parser.ParsedDockerfile, inst *parser.Instruction) (*shell.BinaryCmd, *shell.Script) {
+	for _, script := range shell.Scripts(df, inst) {
+		var guard shell.Pos = -1
+		for _, call := range shell.Calls(script) {
+			if call.Name() != "set" {
+				continue
+			}
+			for _, arg := range call.Args[1:] {
+				if text := arg.Text(); text == "pipefail" {
+					guard = call.Pos()
+					break
+				}
+			}
+			if guard >= 0 {
+				break
+			}
+		}
+
+		for _, pipe := range shell.Pipelines(script) {
+			if (guard < 0 || pipe.Pos() < guard) && !infallibleFeed(pipe, script) {
+				return pipe, script
+			}
+		}
+	}
+	return nil, nil
+}
+
+// infallibleFeed reports whether every command but the last of a pipeline
+// is one that cannot fail
+func infallibleFeed(pipe *shell.BinaryCmd, script *shell.Script) bool {
+	stmts := shell.PipelineStmts(pipe)
+	for _, stmt := range stmts[:len(stmts)-1] {
+		if c, ok := stmtCommand(stmt, script); !ok || !infallibleCommands[c.name] {
+			return false
+		}
+	}
+	return true
+}
+
+// stmtName names the command of a pipeline element in messages
+func stmtName(stmt *shell.Stmt, script *shell.Script) string {
+	if c, ok := stmtCommand(stmt, script); ok {
+		return c.name
+	}
+	return "a compound command"
+}
+
+// hasBash reports whether bash is available to instruction idx: the base
+// image's distribution ships it, or an earlier RUN installed it
+func hasBash(df *parser.ParsedDockerfile, stage *parser.Stage, idx int) bool {
+	switch baseDistro(stage) {
+	case "debian", "ubuntu", "rhel", "suse":
+		return true
+	}
+	for s, limit := stage, idx; s != nil; s = s.BaseStage {
+		for i := 0; i < limit; i++ {
+			if s.Instructions[i].Command != "RUN" {
+				continue
+			}
+			for _, c := range instructionCommands(df, &s.Instructions[i]) {
+				if _, ok := osPackageManagers[c.name]; ok && isPackageInstall(c) && containsString(c.operands(), "bash") {
+					return true
+				}
+			}
+		}
+		if s.BaseStage != nil {
+			limit = len(s.BaseStage.Instructions)
+		}
+	}
+	return false
+}
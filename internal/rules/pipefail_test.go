@@ -0,0 +1,34 @@
+package rules
+
+import "testing"
+
+func TestPipefail(t *testing.T) {
+	runRuleTests(t, "pipefail", []ruleTest{
+		{
+			name: "pipeline without pipefail",
+			dockerfile: `FROM debian
+RUN curl -fsSL https://example.com/a.tgz | tar xz`,
+			want: []string{"Pipeline into tar succeeds even if an earlier command fails"},
+			fixed: `FROM debian
+SHELL ["/bin/bash", "-o", "pipefail", "-c"]
+RUN curl -fsSL https://example.com/a.tgz | tar xz`,
+		},
+		{
+			name: "alpine has no bash to switch to",
+			dockerfile: `FROM alpine
+RUN curl -fsSL https://example.com/a.tgz | tar xz`,
+			want: []string{"Pipeline into tar succeeds even if an earlier command fails"},
+		},
+		{
+			name: "SHELL already sets pipefail",
+			dockerfile: `FROM debian
+SHELL ["/bin/bash", "-o", "pipefail", "-c"]
+RUN curl -fsSL https://example.com/a.tgz | tar xz`,
+		},
+		{
+			name: "no pipeline",
+			dockerfile: `FROM debian
+RUN echo hi`,
+		},
+	})
+}
@@ -0,0 +1,271 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/catalog"
+	"github.com/yourusername/dockerfile-parser/internal/image"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(rootUserRule{})
+}
+
+// rootUserRule flags final images that run as root and RUN steps that need
+// root after USER has switched away from it
+type rootUserRule struct{}
+
+func (rootUserRule) ID() string { return "root-user" }
+
+func (rootUserRule) Description() string {
+	return "The final stage should run as an unprivileged user"
+}
+
+func (rootUserRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+// Name of the user created by the fix
+const unprivilegedUser = "app"
+
+// Commands that fail without root privileges
+var rootOnlyCommands = map[string]bool{
+	"useradd": true, "groupadd": true, "adduser": true, "addgroup": true,
+	"usermod": true, "groupmod": true, "chown": true, "update-ca-certificates": true,
+	"ldconfig": true, "setcap": true,
+}
+
+// effectiveUser is the user a stage runs as at some point
+type effectiveUser struct {
+	name  string              // USER value, or the base image default
+	set   *parser.Instruction // USER instruction that set it, nil for the base default
+	known bool                // False when the base image default is unknown
+}
+
+// isRoot reports whether the user is root; unknown defaults count as root,
+// which is what Docker uses when an image sets no user
+func (u effectiveUser) isRoot() bool {
+	if !u.known {
+		return true
+	}
+	name := u.name
+	if colon := strings.IndexByte(name, ':'); colon >= 0 {
+		name = name[:colon]
+	}
+	return name == "root" || name == "0"
+}
+
+func (r rootUserRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	return r.CheckEnv(df, Env{})
+}
+
+func (r rootUserRule) CheckEnv(df *parser.ParsedDockerfile, env Env) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	if len(df.Stages) == 0 {
+		return warnings
+	}
+
+	for _, stage := range df.Stages {
+		user := stageStartUser(stage)
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			switch inst.Command {
+			case "USER":
+				user = userFrom(inst, user)
+			case "RUN":
+				if user.isRoot() || strings.Contains(user.name, "$") {
+					continue
+				}
+				if cmd, ok := needsRoot(df, inst); ok {
+					warnings = append(warnings, r.runAsUser(df, inst, user, cmd))
+				}
+			}
+		}
+	}
+
+	// Every image that gets built ships, not just the last stage
+	targets := []*parser.Stage{df.Stages[len(df.Stages)-1]}
+	for _, name := range append([]string{df.ParseOptions.TargetStage}, env.Targets...) {
+		if stage := stageByRef(df, name); stage != nil && !containsStage(targets, stage) {
+			targets = append(targets, stage)
+		}
+	}
+	for _, stage := range targets {
+		if user := stageEndUser(stage); user.isRoot() && !strings.Contains(user.name, "$") {
+			warnings = append(warnings, r.finalRoot(df, stage, user))
+		}
+	}
+
+	return warnings
+}
+
+// finalRoot reports a final or target stage running as root and offers to
+// create an unprivileged user before CMD/ENTRYPOINT
+func (r rootUserRule) finalRoot(df *parser.ParsedDockerfile, stage *parser.Stage, user effectiveUser) parser.Warning {
+	what := "Final stage"
+	if stage != df.Stages[len(df.Stages)-1] {
+		what = "Target stage " + stageName(stage)
+	}
+	var w parser.Warning
+	switch {
+	case user.set != nil:
+		w = newWarning(r, user.set, fmt.Sprintf("%s runs as %s, set at line %d", what, user.name, user.set.Range.Start.Line))
+	case user.known:
+		w = newWarning(r, &stage.Instructions[0], what+" runs as root, the base image default; add a USER instruction")
+	default:
+		w = newWarning(r, &stage.Instructions[0], what+" sets no USER and runs as root unless the base image changes the default")
+	}
+
+	lines := sourceLines(df)
+	insertAt := instructionEndLine(&stage.Instructions[len(stage.Instructions)-1]) + 1
+	lastRun := -1
+	for i, inst := range stage.Instructions {
+		if inst.Command == "RUN" {
+			lastRun = i
+		}
+	}
+	for i := lastRun + 1; i < len(stage.Instructions); i++ {
+		if c := stage.Instructions[i].Command; c == "CMD" || c == "ENTRYPOINT" {
+			insertAt = commentBlockStart(lines, stage.Instructions[i].Range.Start.Line)
+			break
+		}
+	}
+
+	// A USER root after the insertion point would undo the switch, so it is
+	// replaced instead
+	edit := insertBefore(insertAt, createUserLines(df, stage))
+	if user.set != nil && user.set.Range.Start.Line >= insertAt {
+		n := user.set.Range.Start.Line
+		edit = replaceLines(n, instructionEndLine(user.set), createUserLines(df, stage))
+	}
+	w.Fix = &parser.Fix{
+		Description: "Create an unprivileged user and switch to it",
+		Edits:       []parser.TextEdit{edit},
+	}
+	return w
+}
+
+// runAsUser reports a RUN that needs root while a non-root USER is active
+// and offers to switch to root just for that step
+func (r rootUserRule) runAsUser(df *parser.ParsedDockerfile, inst *parser.Instruction, user effectiveUser, cmd string) parser.Warning {
+	where := "the base image default"
+	if user.set != nil {
+		where = fmt.Sprintf("line %d", user.set.Range.Start.Line)
+	}
+	w := newWarning(r, inst, fmt.Sprintf("%s needs root but runs as USER %s (from %s)", cmd, user.name, where))
+	w.Level = parser.WarnHigh
+
+	lines := sourceLines(df)
+	w.Fix = &parser.Fix{
+		Description: "Switch to root for this RUN and back to " + user.name,
+		Edits: []parser.TextEdit{
+			insertBefore(commentBlockStart(lines, inst.Range.Start.Line), "USER root"),
+			insertBefore(instructionEndLine(inst)+1, "USER "+user.name),
+		},
+	}
+	return w
+}
+
+// needsRoot returns the first command of the RUN that requires root
+func needsRoot(df *parser.ParsedDockerfile, inst *parser.Instruction) (string, bool) {
+	for _, c := range instructionCommands(df, inst) {
+		if c.call.Name() == "sudo" || c.call.Name() == "doas" {
+			continue
+		}
+		if rootOnlyCommands[c.name] {
+			return c.name, true
+		}
+		if managerFor(c) != nil && !containsString([]string{"pip", "pip3", "npm", "yarn", "gem"}, c.name) {
+			return c.name + " " + c.subcommand(), true
+		}
+	}
+	return "", false
+}
+
+// containsStage reports whether stages includes stage
+func containsStage(stages []*parser.Stage, stage *parser.Stage) bool {
+	for _, s := range stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// stageStartUser returns the user in effect at the FROM of a stage
+func stageStartUser(stage *parser.Stage) effectiveUser {
+	if stage.BaseStage != nil {
+		return stageEndUser(stage.BaseStage)
+	}
+	ref, err := image.ParseReference(stage.BaseImage)
+	if err != nil {
+		return effectiveUser{}
+	}
+	name, known := catalog.Default().DefaultUser(ref)
+	return effectiveUser{name: name, known: known}
+}
+
+// stageEndUser returns the user in effect after the last instruction
+func stageEndUser(stage *parser.Stage) effectiveUser {
+	user := stageStartUser(stage)
+	for i := range stage.Instructions {
+		if stage.Instructions[i].Command == "USER" {
+			user = userFrom(&stage.Instructions[i], user)
+		}
+	}
+	return user
+}
+
+// userFrom applies a USER instruction
+func userFrom(inst *parser.Instruction, prev effectiveUser) effectiveUser {
+	if len(inst.Args) == 0 {
+		return prev
+	}
+	return effectiveUser{name: inst.Args[0], set: inst, known: true}
+}
+
+// createUserLines returns the instructions that add and switch to an
+// unprivileged user, in the form the stage's distribution supports
+func createUserLines(df *parser.ParsedDockerfile, stage *parser.Stage) string {
+	switch stageDistro(df, stage) {
+	case "alpine":
+		return fmt.Sprintf("RUN addgroup -S %[1]s && adduser -S -G %[1]s -H %[1]s\nUSER %[1]s", unprivilegedUser)
+	case "debian", "ubuntu", "rhel":
+		return fmt.Sprintf("RUN groupadd --system %[1]s && useradd --system --gid %[1]s --no-create-home %[1]s\nUSER %[1]s", unprivilegedUser)
+	case "distroless":
+		return "USER nonroot"
+	default:
+		// A numeric ID works without a shell or an /etc/passwd entry
+		return "USER 10001:10001"
+	}
+}
+
+// stageDistro determines the distribution of a stage from the catalog, or
+// from the package manager its RUN instructions use
+func stageDistro(df *parser.ParsedDockerfile, stage *parser.Stage) string {
+	for s := stage; s != nil; s = s.BaseStage {
+		if s.BaseStage == nil {
+			if ref, err := image.ParseReference(s.BaseImage); err == nil {
+				if distro := catalog.Default().Distro(ref); distro != "" {
+					return distro
+				}
+			}
+		}
+		for i := range s.Instructions {
+			if s.Instructions[i].Command != "RUN" {
+				continue
+			}
+			for _, c := range instructionCommands(df, &s.Instructions[i]) {
+				switch c.name {
+				case "apk":
+					return "alpine"
+				case "apt-get", "apt":
+					return "debian"
+				case "yum", "dnf", "microdnf":
+					return "rhel"
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package rules
+
+import "testing"
+
+func TestRootUser(t *testing.T) {
+	runRuleTests(t, "root-user", []ruleTest{
+		{
+			name: "debian without USER",
+			dockerfile: `FROM debian
+RUN echo hi`,
+			want: []string{"Final stage runs as root, the base image default; add a USER instruction"},
+			fixed: `FROM debian
+RUN echo hi
+RUN groupadd --system app && useradd --system --gid app --no-create-home app
+USER app`,
+		},
+		{
+			name: "alpine uses busybox adduser",
+			dockerfile: `FROM alpine
+COPY app /app`,
+			want: []string{"Final stage runs as root"},
+			fixed: `FROM alpine
+COPY app /app
+RUN addgroup -S app && adduser -S -G app -H app
+USER app`,
+		},
+		{
+			name: "distroless has no shell",
+			dockerfile: `FROM gcr.io/distroless/static
+COPY app /app`,
+			want: []string{"Final stage sets no USER and runs as root unless the base image changes the default"},
+			fixed: `FROM gcr.io/distroless/static
+COPY app /app
+USER 10001:10001`,
+		},
+		{
+			name: "switches back to root",
+			dockerfile: `FROM debian
+USER app
+RUN make
+USER root`,
+			want: []string{"Final stage runs as root, set at line 4"},
+			fixed: `FROM debian
+USER app
+RUN make
+USER root
+RUN groupadd --system app && useradd --system --gid app --no-create-home app
+USER app`,
+		},
+		{
+			name: "numeric user",
+			dockerfile: `FROM debian
+USER 1000`,
+		},
+	})
+}
@@ -0,0 +1,110 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/yourusername/dockerfile-parser/internal/lockfile"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// Rule is a single check over a parsed Dockerfile
+type Rule interface {
+	ID() string
+	Description() string
+	DefaultSeverity() parser.WarnLevel
+	Check(df *parser.ParsedDockerfile) []parser.Warning
+}
+
+// OptInRule is implemented by rules that only run when explicitly enabled
+type OptInRule interface {
+	Rule
+	OptIn() bool
+}
+
+// Env carries inputs beyond the Dockerfile that some rules use
+type Env struct {
+	Lockfile *lockfile.Lockfile // Locked base image digests, may be nil
+	Targets  []string           // Stages built as targets besides the final stage
+}
+
+// EnvRule is implemented by rules that use the analysis environment. The
+// engine calls CheckEnv instead of Check for them.
+type EnvRule interface {
+	Rule
+	CheckEnv(df *parser.ParsedDockerfile, env Env) []parser.Warning
+}
+
+// Registry holds the set of known rules keyed by ID
+type Registry struct {
+	mu    sync.RWMutex
+	rules map[string]Rule
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		rules: make(map[string]Rule),
+	}
+}
+
+// Register adds a rule, rejecting duplicate IDs
+func (r *Registry) Register(rule Rule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := rule.ID()
+	if id == "" {
+		return fmt.Errorf("rule has an empty ID")
+	}
+	if _, exists := r.rules[id]; exists {
+		return fmt.Errorf("rule %s is already registered", id)
+	}
+	r.rules[id] = rule
+	return nil
+}
+
+// MustRegister adds a rule and panics on duplicates; meant for init functions
+func (r *Registry) MustRegister(rule Rule) {
+	if err := r.Register(rule); err != nil {
+		panic(err)
+	}
+}
+
+// Get returns the rule with the given ID
+func (r *Registry) Get(id string) (Rule, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rule, ok := r.rules[id]
+	return rule, ok
+}
+
+// Rules returns all registered rules ordered by ID
+func (r *Registry) Rules() []Rule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]Rule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		result = append(result, rule)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID() < result[j].ID()
+	})
+	return result
+}
+
+// defaultRegistry holds the built-in rules, which register themselves
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the registry of built-in rules
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register adds a rule to the default registry
+func Register(rule Rule) {
+	defaultRegistry.MustRegister(rule)
+}
@@ -0,0 +1,88 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// ruleTest is a Dockerfile run through one rule, with the warnings it must
+// produce and the source after applying their fixes
+type ruleTest struct {
+	name       string
+	dockerfile string
+	context    map[string]string // Build context files; sets BuildContext when non-nil
+	target     string            // ParseOptions.TargetStage
+	env        Env
+	want       []string // Substrings of the warning messages, in order
+	fixed      string   // Source after applying the fixes; empty when nothing is fixed
+	alternate  string   // Source after applying the first alternative of the first warning
+}
+
+// parseDockerfile parses src, failing the test on error
+func parseDockerfile(t *testing.T, src string) *parser.ParsedDockerfile {
+	t.Helper()
+	df, err := parser.NewParser().Parse(src)
+	require.NoError(t, err)
+	return df
+}
+
+// writeContext creates a build context holding files
+func writeContext(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	return dir
+}
+
+// runRuleTests checks each test against the registered rule id
+func runRuleTests(t *testing.T, id string, tests []ruleTest) {
+	t.Helper()
+	rule, ok := DefaultRegistry().Get(id)
+	require.True(t, ok, "rule %s is not registered", id)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			df := parseDockerfile(t, tt.dockerfile)
+			if tt.context != nil {
+				df.ParseOptions.BuildContext = writeContext(t, tt.context)
+			}
+			df.ParseOptions.TargetStage = tt.target
+
+			warnings, err := runRule(rule, df, tt.env)
+			require.NoError(t, err)
+
+			messages := make([]string, 0, len(warnings))
+			for _, w := range warnings {
+				assert.Equal(t, id, w.RuleID)
+				messages = append(messages, w.Message)
+			}
+			require.Len(t, messages, len(tt.want), "warnings: %q", messages)
+			for i, want := range tt.want {
+				assert.Contains(t, messages[i], want)
+			}
+
+			fixed, applied := ApplyFixes(tt.dockerfile, warnings)
+			if tt.fixed == "" {
+				assert.Zero(t, applied, "unexpected fix:\n%s", fixed)
+			} else {
+				assert.Equal(t, tt.fixed, fixed)
+			}
+
+			if tt.alternate != "" {
+				require.NotEmpty(t, warnings[0].Alternatives)
+				alternate, _ := ApplyFixes(tt.dockerfile, []parser.Warning{{Fix: &warnings[0].Alternatives[0]}})
+				assert.Equal(t, tt.alternate, alternate)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(secretRule{})
+}
+
+// secretRule finds credentials written into instructions and heredocs,
+// where they end up in image layers or the build history
+type secretRule struct{}
+
+func (secretRule) ID() string { return "secret-leak" }
+
+func (secretRule) Description() string {
+	return "Credentials must not be stored in ENV, ARG, LABEL, RUN or heredocs"
+}
+
+func (secretRule) DefaultSeverity() parser.WarnLevel { return parser.WarnHigh }
+
+// secretPattern is a known credential format
+type secretPattern struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var secretPatterns = []secretPattern{
+	{"private key", regexp.MustCompile(`-----BEGIN ((RSA|EC|DSA|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`)},
+	{"AWS access key ID", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{"Stripe secret key", regexp.MustCompile(`\b[sr]k_live_[0-9A-Za-z]{24,}\b`)},
+	{"npm token", regexp.MustCompile(`\bnpm_[A-Za-z0-9]{36}\b`)},
+	{"Azure storage key", regexp.MustCompile(`AccountKey=[A-Za-z0-9+/=]{80,}`)},
+	{"JSON Web Token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+}
+
+// Names that suggest a secret value. The short words auth and pwd only
+// count as the last _-separated part of a name, so that AUTHOR and
+// OAUTH_CALLBACK_URL are not taken for credentials.
+const secretNameExpr = `(?:[A-Za-z0-9_]*(?i:password|passwd|token|secret|api_?key|access_?key|private_?key|credentials?)[A-Za-z0-9_]*` +
+	`|(?:[A-Za-z0-9]+_)*(?i:auth|pwd))`
+
+var (
+	// NAME=value or NAME: value
+	secretAssignment = regexp.MustCompile(`\b(` + secretNameExpr + `)\s*[=:]\s*["']?([^\s"'` + "`" + `;&|]+)`)
+	// --password=value or --token value on a command line
+	secretFlag = regexp.MustCompile(`--(?i:password|passwd|token|secret|api-key|access-key)[= ]["']?([^\s"';&|]+)`)
+	// ENV NAME value, the legacy space-separated form
+	secretEnvLegacy = regexp.MustCompile(`^\s*(?i:env)\s+(` + secretNameExpr + `)\s+["']?([^\s"'=]+)`)
+	secretName      = regexp.MustCompile(`^` + secretNameExpr + `$`)
+)
+
+// Values that are obviously not real secrets
+var secretPlaceholders = []string{"changeme", "change_me", "placeholder", "example", "xxxxx", "dummy", "redacted", "none", "null", "false", "true", "/run/secrets/"}
+
+// minSecretEntropy is the Shannon entropy in bits per character above
+// which a value assigned to a secret-looking name counts as a credential
+const minSecretEntropy = 3.0
+
+// secretMatch is a credential found at a byte span of a source line
+type secretMatch struct {
+	kind       string
+	line       int
+	start, end int
+}
+
+func (r secretRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+	offsets := lineOffsets(lines)
+
+	for _, inst := range allInstructions(df) {
+		switch inst.Command {
+		case "ENV", "ARG", "LABEL", "RUN":
+		case "COPY", "ADD":
+			if inst.Heredoc == nil {
+				continue
+			}
+		default:
+			continue
+		}
+
+		for _, m := range findSecrets(lines, inst) {
+			pos := parser.Position{
+				Line:     m.line,
+				Column:   m.start + 1,
+				Offset:   m.start,
+				FilePath: inst.Range.Start.FilePath,
+			}
+			if m.line-1 < len(offsets) {
+				pos.Offset += offsets[m.line-1]
+			}
+			w := newWarning(r, inst, fmt.Sprintf(
+				"Possible %s in %s; it is stored in the image and its history. Pass it with RUN --mount=type=secret instead",
+				m.kind, inst.Command))
+			w.Position = pos
+			w.Context = maskSecret(lineText(lines, m.line), m.start, m.end)
+			warnings = append(warnings, w)
+		}
+
+		if inst.Command == "ARG" {
+			warnings = append(warnings, r.secretBuildArgs(inst)...)
+		}
+	}
+
+	return warnings
+}
+
+// secretBuildArgs reports ARGs without a value whose names suggest a secret;
+// build argument values are recorded in the image history
+func (r secretRule) secretBuildArgs(inst *parser.Instruction) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	for _, arg := range inst.Args {
+		if strings.Contains(arg, "=") || !secretName.MatchString(arg) {
+			continue
+		}
+		w := newWarning(r, inst, fmt.Sprintf(
+			"ARG %s looks like a secret; build arguments are visible in the image history. Use RUN --mount=type=secret,id=%s instead",
+			arg, strings.ToLower(arg)))
+		w.Level = parser.WarnMedium
+		warnings = append(warnings, w)
+	}
+	return warnings
+}
+
+// findSecrets scans the source lines of inst, including any heredoc body.
+// Known formats take precedence over the name-based heuristics.
+func findSecrets(lines []string, inst *parser.Instruction) []secretMatch {
+	matches := make([]secretMatch, 0)
+	overlaps := func(line, start, end int) bool {
+		for _, m := range matches {
+			if m.line == line && start < m.end && m.start < end {
+				return true
+			}
+		}
+		return false
+	}
+
+	for n := inst.Range.Start.Line; n <= instructionEndLine(inst); n++ {
+		text := lineText(lines, n)
+		if isCommentLine(text) && n != inst.Range.Start.Line {
+			continue
+		}
+
+		for _, p := range secretPatterns {
+			for _, loc := range p.pattern.FindAllStringIndex(text, -1) {
+				if !overlaps(n, loc[0], loc[1]) {
+					matches = append(matches, secretMatch{kind: p.name, line: n, start: loc[0], end: loc[1]})
+				}
+			}
+		}
+
+		heuristics := []*regexp.Regexp{secretAssignment, secretFlag}
+		if n == inst.Range.Start.Line {
+			heuristics = append(heuristics, secretEnvLegacy)
+		}
+		for _, re := range heuristics {
+			for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+				// The value is always the last group
+				start, end := loc[len(loc)-2], loc[len(loc)-1]
+				if start < 0 || overlaps(n, start, end) || !looksLikeSecret(text[start:end]) {
+					continue
+				}
+				matches = append(matches, secretMatch{kind: "hard-coded credential", line: n, start: start, end: end})
+			}
+		}
+	}
+	return matches
+}
+
+// looksLikeSecret filters out references, placeholders and low-entropy values
+func looksLikeSecret(value string) bool {
+	if len(value) < 8 || strings.ContainsAny(value, "$") {
+		return false
+	}
+	lower := strings.ToLower(value)
+	for _, p := range secretPlaceholders {
+		if strings.Contains(lower, p) {
+			return false
+		}
+	}
+	return shannonEntropy(value) >= minSecretEntropy
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, c := range counts {
+		p := float64(c) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// maskSecret hides all but the first characters of the secret in line
+func maskSecret(line string, start, end int) string {
+	keep := 4
+	if end-start <= keep*2 {
+		keep = 1
+	}
+	masked := line[:start] + line[start:start+keep] + strings.Repeat("*", 8) + line[end:]
+	if len(masked) > 120 {
+		masked = masked[:117] + "..."
+	}
+	return strings.TrimSpace(masked)
+}
+
+// lineOffsets returns the byte offset of the start of every line
+func lineOffsets(lines []string) []int {
+	offsets := make([]int, len(lines))
+	offset := 0
+	for i, line := range lines {
+		offsets[i] = offset
+		offset += len(line) + 1
+	}
+	return offsets
+}
@@ -0,0 +1,112 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/catalog"
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(slimBaseRule{})
+}
+
+// slimBaseRule suggests smaller variants of the base images that end up in
+// the final image, using the bundled image catalog
+type slimBaseRule struct{}
+
+func (slimBaseRule) ID() string { return "slim-base-image" }
+
+func (slimBaseRule) Description() string {
+	return "A smaller base image variant is available"
+}
+
+func (slimBaseRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+func (r slimBaseRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+	shipped := shippedStages(df)
+	images := catalog.Default()
+
+	for _, base := range baseImages(df, lines) {
+		stage := base.stage
+		if !shipped[stage] {
+			continue
+		}
+		match, ok := images.Lookup(base.ref)
+		if !ok {
+			continue
+		}
+		suggestions := images.Suggest(match)
+		if len(suggestions) == 0 {
+			continue
+		}
+
+		needsShell := stageNeedsShell(stage)
+		options := make([]string, 0, len(suggestions))
+		fixes := make([]parser.Fix, 0, len(suggestions))
+		for _, s := range suggestions {
+			option := fmt.Sprintf("%s (~%d MB smaller", s.Reference, s.SavingMB)
+			if len(s.Caveats) > 0 {
+				option += "; " + strings.Join(s.Caveats, "; ")
+			}
+			options = append(options, option+")")
+
+			// Without a shell the stage's own RUN instructions would fail, so
+			// the image only fits as the target of a multi-stage split
+			if needsShell && !s.Image.Shell {
+				continue
+			}
+			fixes = append(fixes, base.replaceFix(lines, s.Reference))
+		}
+
+		w := newWarning(r, base.from, fmt.Sprintf(
+			"Base image %s is about %d MB; smaller alternatives: %s",
+			base.ref, match.Image.SizeMB, strings.Join(options, ", ")))
+		w.Alternatives = fixes
+		warnings = append(warnings, w)
+	}
+
+	return warnings
+}
+
+// replaceFix swaps the image reference of the FROM line. A digest does not
+// carry over to a different image, so the new reference is unpinned.
+func (b baseImage) replaceFix(lines []string, ref string) parser.Fix {
+	n := b.from.Range.Start.Line
+	line := lineText(lines, n)
+	return parser.Fix{
+		Description: "Use base image " + ref,
+		Edits:       []parser.TextEdit{replaceLines(n, n, line[:b.start]+ref+line[b.end:])},
+	}
+}
+
+// shippedStages returns the final stage and the stages it is built FROM,
+// whose base images make up the final image
+func shippedStages(df *parser.ParsedDockerfile) map[*parser.Stage]bool {
+	shipped := make(map[*parser.Stage]bool)
+	if len(df.Stages) == 0 {
+		return shipped
+	}
+	for stage := df.Stages[len(df.Stages)-1]; stage != nil && !shipped[stage]; stage = stage.BaseStage {
+		shipped[stage] = true
+	}
+	return shipped
+}
+
+// stageNeedsShell reports whether the stage runs shell-form commands
+func stageNeedsShell(stage *parser.Stage) bool {
+	for _, inst := range stage.Instructions {
+		switch inst.Command {
+		case "RUN":
+			return true
+		case "CMD", "ENTRYPOINT":
+			if !inst.JSONForm {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package rules
+
+import "testing"
+
+func TestSlimBaseImage(t *testing.T) {
+	runRuleTests(t, "slim-base-image", []ruleTest{
+		{
+			name: "full node image",
+			dockerfile: `FROM node:20
+RUN npm ci`,
+			want: []string{"Base image node:20 is about 1100 MB; smaller alternatives: node:20-alpine"},
+			alternate: `FROM node:20-alpine
+RUN npm ci`,
+		},
+		{
+			name: "only the final stage is checked",
+			dockerfile: `FROM golang:1.22 AS build
+FROM debian:12`,
+			want: []string{"Base image debian:12 is about 120 MB; smaller alternatives: debian:12-slim (~45 MB smaller)"},
+			alternate: `FROM golang:1.22 AS build
+FROM debian:12-slim`,
+		},
+		{
+			name:       "already slim",
+			dockerfile: `FROM node:20-alpine`,
+		},
+	})
+}
@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// Inline suppression directives, written as comments:
+//
+//	# optimizer:ignore=RULE_ID[,RULE_ID...]        silences the next instruction
+//	# optimizer:ignore-stage=RULE_ID[,RULE_ID...]  silences the whole stage
+//
+// An ignore directive applies to the instruction that follows it, with only
+// comment lines in between. An ignore-stage directive placed directly above a
+// FROM applies to that stage; anywhere else it applies to the enclosing stage.
+const (
+	ignoreDirective      = "optimizer:ignore="
+	ignoreStageDirective = "optimizer:ignore-stage="
+)
+
+// suppression silences rules over an inclusive line span
+type suppression struct {
+	startLine int
+	endLine   int
+	ruleIDs   map[string]bool
+}
+
+type suppressionSet []suppression
+
+// suppressed reports whether w falls inside a suppression for its rule
+func (s suppressionSet) suppressed(w parser.Warning) bool {
+	for _, sup := range s {
+		if sup.ruleIDs[w.RuleID] && w.Position.Line >= sup.startLine && w.Position.Line <= sup.endLine {
+			return true
+		}
+	}
+	return false
+}
+
+// collectSuppressions scans the raw Dockerfile for suppression directives
+func collectSuppressions(df *parser.ParsedDockerfile) suppressionSet {
+	lines := strings.Split(df.Raw, "\n")
+	instructions := allInstructions(df)
+	set := make(suppressionSet, 0)
+
+	for i, line := range lines {
+		lineNo := i + 1
+		text := strings.TrimSpace(line)
+		if !strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimSpace(strings.TrimPrefix(text, "#"))
+
+		switch {
+		case strings.HasPrefix(text, ignoreStageDirective):
+			ids := parseRuleIDs(strings.TrimPrefix(text, ignoreStageDirective))
+			next := nextInstruction(lines, instructions, lineNo)
+			stage := enclosingStage(df, lineNo)
+			if next != nil && next.Command == "FROM" {
+				stage = next.Stage
+			}
+			if stage != nil {
+				start, end := stageLineSpan(df, stage, len(lines))
+				set = append(set, suppression{startLine: start, endLine: end, ruleIDs: ids})
+			}
+		case strings.HasPrefix(text, ignoreDirective):
+			ids := parseRuleIDs(strings.TrimPrefix(text, ignoreDirective))
+			if next := nextInstruction(lines, instructions, lineNo); next != nil {
+				set = append(set, suppression{
+					startLine: next.Range.Start.Line,
+					endLine:   instructionEndLine(next),
+					ruleIDs:   ids,
+				})
+			}
+		}
+	}
+
+	return set
+}
+
+// parseRuleIDs splits a comma separated list of rule IDs
+func parseRuleIDs(list string) map[string]bool {
+	ids := make(map[string]bool)
+	// Anything after whitespace is a free-form reason
+	if fields := strings.Fields(list); len(fields) > 0 {
+		list = fields[0]
+	}
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// nextInstruction returns the instruction that directly follows lineNo,
+// allowing only comment lines in between
+func nextInstruction(lines []string, instructions []*parser.Instruction, lineNo int) *parser.Instruction {
+	idx := sort.Search(len(instructions), func(i int) bool {
+		return instructions[i].Range.Start.Line > lineNo
+	})
+	if idx == len(instructions) {
+		return nil
+	}
+
+	next := instructions[idx]
+	for l := lineNo + 1; l < next.Range.Start.Line && l <= len(lines); l++ {
+		if !strings.HasPrefix(strings.TrimSpace(lines[l-1]), "#") {
+			return nil
+		}
+	}
+	return next
+}
+
+// enclosingStage returns the stage whose line span contains lineNo
+func enclosingStage(df *parser.ParsedDockerfile, lineNo int) *parser.Stage {
+	var found *parser.Stage
+	for _, stage := range df.Stages {
+		if stage.Range.Start.Line <= lineNo {
+			found = stage
+		}
+	}
+	return found
+}
+
+// stageLineSpan returns the lines from a stage's FROM up to the next FROM
+func stageLineSpan(df *parser.ParsedDockerfile, stage *parser.Stage, lastLine int) (int, int) {
+	end := lastLine
+	if stage.Index+1 < len(df.Stages) {
+		end = df.Stages[stage.Index+1].Range.Start.Line - 1
+	}
+	return stage.Range.Start.Line, end
+}
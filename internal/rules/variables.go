@@ -0,0 +1,477 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+func init() {
+	Register(unusedArgRule{})
+	Register(undefinedVariableRule{})
+	Register(envShadowsArgRule{})
+}
+
+// Build arguments that BuildKit defines in the global scope, so FROM can
+// use them without a declaration
+var platformArgs = map[string]bool{
+	"TARGETPLATFORM": true, "TARGETOS": true, "TARGETARCH": true, "TARGETVARIANT": true,
+	"BUILDPLATFORM": true, "BUILDOS": true, "BUILDARCH": true, "BUILDVARIANT": true,
+}
+
+// Build arguments that tools read from the environment without a reference
+var implicitArgs = map[string]bool{
+	"DEBIAN_FRONTEND": true, "SOURCE_DATE_EPOCH": true,
+	"HTTP_PROXY": true, "HTTPS_PROXY": true, "FTP_PROXY": true, "NO_PROXY": true, "ALL_PROXY": true,
+	"http_proxy": true, "https_proxy": true, "ftp_proxy": true, "no_proxy": true, "all_proxy": true,
+	"BUILDKIT_INLINE_CACHE": true, "BUILDKIT_MULTI_PLATFORM": true, "BUILDKIT_SYNTAX": true,
+	"BUILDKIT_CONTEXT_KEEP_GIT_DIR": true, "BUILDKIT_SANDBOX_HOSTNAME": true,
+	"CGO_ENABLED": true, "GOOS": true, "GOARCH": true, "GOARM": true, "GOAMD64": true,
+	"GOFLAGS": true, "GOPROXY": true, "GOPRIVATE": true, "GONOSUMDB": true, "GOSUMDB": true,
+	"NODE_ENV": true, "NODE_OPTIONS": true, "NPM_TOKEN": true,
+	"PYTHONDONTWRITEBYTECODE": true, "PYTHONUNBUFFERED": true,
+	"MAVEN_OPTS": true, "GRADLE_OPTS": true, "JAVA_OPTS": true, "RAILS_ENV": true,
+	"CC": true, "CXX": true, "CFLAGS": true, "CXXFLAGS": true, "LDFLAGS": true, "MAKEFLAGS": true,
+	"RUSTFLAGS": true,
+}
+
+// Prefixes of the environment variables package managers read their
+// configuration from
+var implicitArgPrefixes = []string{"NPM_CONFIG_", "npm_config_", "YARN_", "PIP_", "POETRY_", "BUNDLE_", "CARGO_"}
+
+// implicitArg reports whether tools read the build argument from the
+// environment without a reference
+func implicitArg(name string) bool {
+	if implicitArgs[name] {
+		return true
+	}
+	for _, prefix := range implicitArgPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Environment variables that base images commonly set, which instructions
+// may reference without declaring them
+var imageEnv = map[string]bool{
+	"PATH": true, "HOME": true, "HOSTNAME": true, "TERM": true, "LANG": true, "LC_ALL": true,
+	"USER": true, "PWD": true, "SHELL": true, "LD_LIBRARY_PATH": true,
+	"GOPATH": true, "GOROOT": true, "GOLANG_VERSION": true, "GOTOOLCHAIN": true,
+	"JAVA_HOME": true, "JAVA_VERSION": true, "MAVEN_HOME": true, "GRADLE_HOME": true,
+	"NODE_VERSION": true, "YARN_VERSION": true,
+	"PYTHON_VERSION": true, "PYTHON_PIP_VERSION": true, "PYTHON_SHA256": true,
+	"RUBY_VERSION": true, "GEM_HOME": true, "BUNDLE_PATH": true, "BUNDLE_APP_CONFIG": true,
+	"CARGO_HOME": true, "RUSTUP_HOME": true, "RUST_VERSION": true, "NGINX_VERSION": true,
+}
+
+// Instructions whose arguments the builder expands; RUN, CMD, ENTRYPOINT
+// and HEALTHCHECK are left to the shell
+var expandedInstructions = map[string]bool{
+	"ADD": true, "COPY": true, "ENV": true, "EXPOSE": true, "FROM": true, "LABEL": true,
+	"STOPSIGNAL": true, "USER": true, "VOLUME": true, "WORKDIR": true, "ARG": true,
+}
+
+// unusedArgRule reports ARGs that nothing references
+type unusedArgRule struct{}
+
+func (unusedArgRule) ID() string { return "unused-arg" }
+
+func (unusedArgRule) Description() string {
+	return "Declared build arguments should be used"
+}
+
+func (unusedArgRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+func (r unusedArgRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	// Global ARGs are used by FROM lines, by other global ARGs and by
+	// stages that re-declare them
+	for _, name := range sortedGlobalArgs(df) {
+		v := df.GlobalArgs[name]
+		if implicitArg(name) || globalArgUsed(df, name) {
+			continue
+		}
+		inst := globalArgInstruction(v)
+		w := newWarning(r, inst, fmt.Sprintf("Global ARG %s is not used by any FROM and no stage re-declares it", name))
+		w.Fix = &parser.Fix{
+			Description: "Remove ARG " + name,
+			Edits:       []parser.TextEdit{deleteLines(v.Position.Line, v.Position.Line)},
+		}
+		warnings = append(warnings, w)
+	}
+
+	for _, stage := range df.Stages {
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			name := argName(inst)
+			if inst.Command != "ARG" || name == "" || implicitArg(name) {
+				continue
+			}
+
+			// RUN steps see build arguments as environment variables, so
+			// scripts may read it without a reference
+			used := false
+			later := stage.Instructions[i+1:]
+			for j := range later {
+				if later[j].Command == "RUN" || referencesName(&later[j], name) {
+					used = true
+					break
+				}
+			}
+			if used {
+				continue
+			}
+
+			w := newWarning(r, inst, fmt.Sprintf("ARG %s is declared but never used in stage %s", name, stageName(stage)))
+			w.Fix = &parser.Fix{
+				Description: "Remove ARG " + name,
+				Edits:       []parser.TextEdit{deleteLines(inst.Range.Start.Line, instructionEndLine(inst))},
+			}
+			warnings = append(warnings, w)
+		}
+	}
+
+	return warnings
+}
+
+// undefinedVariableRule reports references to variables that are not
+// declared where they are used
+type undefinedVariableRule struct{}
+
+func (undefinedVariableRule) ID() string { return "undefined-variable" }
+
+func (undefinedVariableRule) Description() string {
+	return "Variables must be declared with ARG or ENV before they are referenced"
+}
+
+func (undefinedVariableRule) DefaultSeverity() parser.WarnLevel { return parser.WarnMedium }
+
+func (r undefinedVariableRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+	lines := sourceLines(df)
+	firstLine := firstInstructionLine(df)
+
+	reportedGlobal := make(map[string]bool)
+	for _, stage := range df.Stages {
+		if len(stage.Instructions) == 0 {
+			continue
+		}
+		from := &stage.Instructions[0]
+
+		// FROM only sees ARGs declared before the first FROM
+		for _, ref := range instructionReferences(from) {
+			if ref.Guarded || platformArgs[ref.Name] || reportedGlobal[ref.Name] {
+				continue
+			}
+			if _, ok := df.GlobalArgs[ref.Name]; ok {
+				continue
+			}
+			reportedGlobal[ref.Name] = true
+			w := newWarning(r, from, fmt.Sprintf("FROM uses $%s, which is not declared by an ARG before the first FROM", ref.Name))
+			w.Position = referencePosition(lines, from, ref.text)
+			w.Fix = &parser.Fix{
+				Description: "Declare ARG " + ref.Name + " before the first FROM",
+				Edits:       []parser.TextEdit{insertBefore(firstLine, "ARG "+ref.Name)},
+			}
+			warnings = append(warnings, w)
+		}
+
+		declared := inheritedEnv(stage)
+		reported := make(map[string]bool)
+		for i := 1; i < len(stage.Instructions); i++ {
+			inst := &stage.Instructions[i]
+			if expandedInstructions[inst.Command] {
+				for _, ref := range instructionReferences(inst) {
+					if ref.Guarded || declared[ref.Name] || reported[ref.Name] {
+						continue
+					}
+					if w, ok := r.undeclared(df, lines, from, inst, ref); ok {
+						reported[ref.Name] = true
+						warnings = append(warnings, w)
+					}
+				}
+			}
+			for _, name := range declaredNames(inst) {
+				declared[name] = true
+			}
+		}
+	}
+
+	return warnings
+}
+
+// undeclared builds the warning for a reference that the stage does not
+// declare. Names base images usually set are left alone.
+func (r undefinedVariableRule) undeclared(df *parser.ParsedDockerfile, lines []string, from, inst *parser.Instruction, ref namedReference) (parser.Warning, bool) {
+	_, global := df.GlobalArgs[ref.Name]
+	if global || platformArgs[ref.Name] {
+		msg := fmt.Sprintf("Global ARG %s is not visible inside the stage; re-declare it with ARG %s after FROM", ref.Name, ref.Name)
+		if !global {
+			msg = fmt.Sprintf("$%s is only set inside a stage after ARG %s", ref.Name, ref.Name)
+		}
+		w := newWarning(r, inst, msg)
+		w.Position = referencePosition(lines, inst, ref.text)
+		w.Fix = &parser.Fix{
+			Description: "Re-declare ARG " + ref.Name + " in the stage",
+			Edits:       []parser.TextEdit{insertBefore(instructionEndLine(from)+1, "ARG "+ref.Name)},
+		}
+		return w, true
+	}
+	if imageEnv[ref.Name] {
+		return parser.Warning{}, false
+	}
+
+	w := newWarning(r, inst, fmt.Sprintf("$%s is not declared by an ARG or ENV in this stage and expands to an empty string unless the base image sets it", ref.Name))
+	w.Position = referencePosition(lines, inst, ref.text)
+	w.Level = parser.WarnLow
+	return w, true
+}
+
+// envShadowsArgRule reports ENV and ARG declarations of the same name,
+// where ENV silently wins over the build argument
+type envShadowsArgRule struct{}
+
+func (envShadowsArgRule) ID() string { return "env-shadows-arg" }
+
+func (envShadowsArgRule) Description() string {
+	return "ENV should not hide a build argument of the same name"
+}
+
+func (envShadowsArgRule) DefaultSeverity() parser.WarnLevel { return parser.WarnLow }
+
+func (r envShadowsArgRule) Check(df *parser.ParsedDockerfile) []parser.Warning {
+	warnings := make([]parser.Warning, 0)
+
+	for _, stage := range df.Stages {
+		args := make(map[string]*parser.Instruction)
+		envs := make(map[string]*parser.Instruction)
+		for name := range inheritedEnv(stage) {
+			envs[name] = nil
+		}
+
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			switch inst.Command {
+			case "ARG":
+				name := argName(inst)
+				env, ok := envs[name]
+				if !ok {
+					args[name] = inst
+					continue
+				}
+				where := "the base stage"
+				if env != nil {
+					where = fmt.Sprintf("line %d", env.Range.Start.Line)
+				}
+				warnings = append(warnings, newWarning(r, inst, fmt.Sprintf(
+					"ARG %s has no effect because ENV %s from %s takes precedence", name, name, where)))
+
+			case "ENV":
+				for _, pair := range envPairs(inst) {
+					name, value := pair[0], pair[1]
+					envs[name] = inst
+					arg, ok := args[name]
+					if !ok || valueReferences(value, name) {
+						// ENV NAME=$NAME is the idiom for keeping a build
+						// argument at runtime
+						continue
+					}
+					warnings = append(warnings, newWarning(r, inst, fmt.Sprintf(
+						"ENV %s overrides ARG %s from line %d, so --build-arg %s has no effect after this line",
+						name, name, arg.Range.Start.Line, name)))
+				}
+			}
+		}
+	}
+
+	return warnings
+}
+
+// namedReference is a variable reference with the text it was written as
+type namedReference struct {
+	parser.VariableReference
+	text string
+}
+
+// instructionReferences lists the variable references in an instruction's
+// arguments and flags
+func instructionReferences(inst *parser.Instruction) []namedReference {
+	texts := append([]string(nil), inst.Args...)
+	if inst.Command == "ARG" {
+		texts = append(texts, inst.Flags["default"])
+	} else {
+		for key, value := range inst.Flags {
+			if key != "stage" {
+				texts = append(texts, value)
+			}
+		}
+	}
+	if inst.Heredoc != nil && inst.Command == "RUN" {
+		texts = append(texts, inst.Heredoc.Content)
+	}
+
+	refs := make([]namedReference, 0)
+	for _, text := range texts {
+		for _, ref := range parser.VariableReferences(text) {
+			refs = append(refs, namedReference{VariableReference: ref, text: text[ref.Start:ref.End]})
+		}
+	}
+	return refs
+}
+
+// referencesName reports whether the instruction references name
+func referencesName(inst *parser.Instruction, name string) bool {
+	for _, ref := range instructionReferences(inst) {
+		if ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// valueReferences reports whether value references name
+func valueReferences(value, name string) bool {
+	for _, ref := range parser.VariableReferences(value) {
+		if ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// argName returns the name an ARG instruction declares
+func argName(inst *parser.Instruction) string {
+	if inst.Command != "ARG" || len(inst.Args) == 0 {
+		return ""
+	}
+	name, _, _ := strings.Cut(inst.Args[0], "=")
+	return name
+}
+
+// declaredNames returns the names an ARG or ENV instruction declares
+func declaredNames(inst *parser.Instruction) []string {
+	switch inst.Command {
+	case "ARG":
+		if name := argName(inst); name != "" {
+			return []string{name}
+		}
+	case "ENV":
+		names := make([]string, 0, len(inst.Args))
+		for _, pair := range envPairs(inst) {
+			names = append(names, pair[0])
+		}
+		return names
+	}
+	return nil
+}
+
+// envPairs returns the name and value of each ENV declaration, accepting
+// the legacy ENV NAME value form
+func envPairs(inst *parser.Instruction) [][2]string {
+	pairs := make([][2]string, 0, len(inst.Args))
+	if len(inst.Args) > 0 && !strings.Contains(inst.Args[0], "=") {
+		return append(pairs, [2]string{inst.Args[0], strings.Join(inst.Args[1:], " ")})
+	}
+	for _, arg := range inst.Args {
+		name, value, _ := strings.Cut(arg, "=")
+		pairs = append(pairs, [2]string{name, value})
+	}
+	return pairs
+}
+
+// inheritedEnv returns the ENV names a stage inherits from its base stages
+func inheritedEnv(stage *parser.Stage) map[string]bool {
+	names := make(map[string]bool)
+	for base := stage.BaseStage; base != nil; base = base.BaseStage {
+		for i := range base.Instructions {
+			if base.Instructions[i].Command == "ENV" {
+				for _, name := range declaredNames(&base.Instructions[i]) {
+					names[name] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+// sortedGlobalArgs returns the global ARG names in declaration order
+func sortedGlobalArgs(df *parser.ParsedDockerfile) []string {
+	names := make([]string, 0, len(df.GlobalArgs))
+	for name := range df.GlobalArgs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return df.GlobalArgs[names[i]].Position.Line < df.GlobalArgs[names[j]].Position.Line
+	})
+	return names
+}
+
+// globalArgInstruction rebuilds the ARG instruction of a global variable,
+// which the parser does not keep
+func globalArgInstruction(v parser.Variable) *parser.Instruction {
+	arg := v.Name
+	if v.Default != "" {
+		arg += "=" + v.Default
+	}
+	return &parser.Instruction{
+		Command: "ARG",
+		Args:    []string{arg},
+		Range:   parser.Range{Start: v.Position, End: v.Position},
+	}
+}
+
+// globalArgUsed reports whether a FROM, another global ARG's default or a
+// stage re-declaration uses the global ARG name
+func globalArgUsed(df *parser.ParsedDockerfile, name string) bool {
+	for other, v := range df.GlobalArgs {
+		if other != name && valueReferences(v.Default, name) {
+			return true
+		}
+	}
+	for _, stage := range df.Stages {
+		for i := range stage.Instructions {
+			inst := &stage.Instructions[i]
+			if inst.Command == "FROM" && referencesName(inst, name) {
+				return true
+			}
+			if inst.Command == "ARG" && argName(inst) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// firstInstructionLine returns the line of the first instruction, which
+// follows any parser directives
+func firstInstructionLine(df *parser.ParsedDockerfile) int {
+	line := 0
+	if len(df.Stages) > 0 {
+		line = df.Stages[0].Range.Start.Line
+	}
+	for _, v := range df.GlobalArgs {
+		if line == 0 || v.Position.Line < line {
+			line = v.Position.Line
+		}
+	}
+	if line == 0 {
+		return 1
+	}
+	return line
+}
+
+// referencePosition locates the written reference in the instruction's
+// source lines, falling back to the instruction start
+func referencePosition(lines []string, inst *parser.Instruction, text string) parser.Position {
+	for n := inst.Range.Start.Line; n <= instructionEndLine(inst); n++ {
+		if col := strings.Index(lineText(lines, n), text); col >= 0 {
+			return parser.Position{Line: n, Column: col + 1, FilePath: inst.Range.Start.FilePath}
+		}
+	}
+	return inst.Range.Start
+}
@@ -0,0 +1,78 @@
+package rules
+
+import "testing"
+
+func TestUnusedArg(t *testing.T) {
+	runRuleTests(t, "unused-arg", []ruleTest{
+		{
+			name: "stage ARG never referenced",
+			dockerfile: `FROM debian
+ARG VERSION=1
+COPY a /a`,
+			want: []string{"ARG VERSION is declared but never used in stage #0"},
+			fixed: `FROM debian
+COPY a /a`,
+		},
+		{
+			name: "global ARG unused by FROM",
+			dockerfile: `ARG BASE=debian
+ARG UNUSED=1
+FROM $BASE
+COPY a /a`,
+			want: []string{"Global ARG UNUSED is not used by any FROM and no stage re-declares it"},
+			fixed: `ARG BASE=debian
+FROM $BASE
+COPY a /a`,
+		},
+		{
+			name: "tool reads the ARG from the environment",
+			dockerfile: `FROM golang:1.22
+ARG CGO_ENABLED=0
+COPY . .`,
+		},
+		{
+			name: "a later RUN may read the ARG",
+			dockerfile: `FROM debian
+ARG VERSION=1
+RUN make`,
+		},
+	})
+}
+
+func TestUndefinedVariable(t *testing.T) {
+	runRuleTests(t, "undefined-variable", []ruleTest{
+		{
+			name: "never declared",
+			dockerfile: `FROM debian
+WORKDIR $APP_HOME`,
+			want: []string{"$APP_HOME is not declared by an ARG or ENV in this stage"},
+		},
+		{
+			name: "declared ARG and well-known variable",
+			dockerfile: `FROM debian
+ARG APP_HOME=/app
+WORKDIR $APP_HOME
+COPY a ${HOME}/a`,
+		},
+	})
+}
+
+func TestEnvShadowsArg(t *testing.T) {
+	runRuleTests(t, "env-shadows-arg", []ruleTest{
+		{
+			name: "ENV replaces the build argument",
+			dockerfile: `FROM debian
+ARG MODE=dev
+ENV MODE=prod
+RUN echo $MODE`,
+			want: []string{"ENV MODE overrides ARG MODE from line 2, so --build-arg MODE has no effect after this line"},
+		},
+		{
+			name: "ENV carries the build argument",
+			dockerfile: `FROM debian
+ARG MODE=dev
+ENV MODE=$MODE
+RUN echo $MODE`,
+		},
+	})
+}
@@ -0,0 +1,343 @@
+package shell
+
+// Pos is a byte offset into the parsed shell source
+type Pos int
+
+// Node is implemented by every syntax tree element
+type Node interface {
+	Pos() Pos // First byte of the node
+	End() Pos // Byte just past the node
+}
+
+// Command is the body of a statement
+type Command interface {
+	Node
+	commandNode()
+}
+
+// WordPart is a piece of a word: literal text, quoted text or an expansion
+type WordPart interface {
+	Node
+	wordPartNode()
+}
+
+// Stmt is a command together with its redirections and modifiers
+type Stmt struct {
+	Position   Pos
+	EndPos     Pos
+	Cmd        Command
+	Redirs     []*Redirect
+	Negated    bool // Preceded by !
+	Background bool // Terminated by &
+}
+
+func (s *Stmt) Pos() Pos { return s.Position }
+func (s *Stmt) End() Pos { return s.EndPos }
+
+// BinaryOp is a command list or pipe operator
+type BinaryOp int
+
+const (
+	AndOp  BinaryOp = iota // &&
+	OrOp                   // ||
+	PipeOp                 // |
+)
+
+func (op BinaryOp) String() string {
+	switch op {
+	case AndOp:
+		return "&&"
+	case OrOp:
+		return "||"
+	default:
+		return "|"
+	}
+}
+
+// BinaryCmd joins two statements with &&, || or |
+type BinaryCmd struct {
+	OpPos Pos
+	Op    BinaryOp
+	X, Y  *Stmt
+}
+
+func (c *BinaryCmd) Pos() Pos { return c.X.Pos() }
+func (c *BinaryCmd) End() Pos { return c.Y.End() }
+
+// CallExpr is a simple command: assignments followed by words
+type CallExpr struct {
+	Assigns []*Assign
+	Args    []*Word
+}
+
+func (c *CallExpr) Pos() Pos {
+	if len(c.Assigns) > 0 {
+		return c.Assigns[0].Pos()
+	}
+	if len(c.Args) > 0 {
+		return c.Args[0].Pos()
+	}
+	return 0
+}
+
+func (c *CallExpr) End() Pos {
+	if len(c.Args) > 0 {
+		return c.Args[len(c.Args)-1].End()
+	}
+	if len(c.Assigns) > 0 {
+		return c.Assigns[len(c.Assigns)-1].End()
+	}
+	return 0
+}
+
+// Name returns the literal command name, or "" when it is computed
+func (c *CallExpr) Name() string {
+	if len(c.Args) == 0 {
+		return ""
+	}
+	name, _ := c.Args[0].Lit()
+	return name
+}
+
+// Subshell is a ( list )
+type Subshell struct {
+	Lparen, Rparen Pos
+	Stmts          []*Stmt
+}
+
+func (s *Subshell) Pos() Pos { return s.Lparen }
+func (s *Subshell) End() Pos { return s.Rparen + 1 }
+
+// Block is a { list; } group
+type Block struct {
+	Lbrace, Rbrace Pos
+	Stmts          []*Stmt
+}
+
+func (b *Block) Pos() Pos { return b.Lbrace }
+func (b *Block) End() Pos { return b.Rbrace + 1 }
+
+// IfClause is an if, elif or else branch. Else branches have no Cond.
+type IfClause struct {
+	Position, EndPos Pos
+	Cond             []*Stmt
+	Then             []*Stmt
+	Else             *IfClause
+}
+
+func (c *IfClause) Pos() Pos { return c.Position }
+func (c *IfClause) End() Pos { return c.EndPos }
+
+// WhileClause is a while or until loop
+type WhileClause struct {
+	Position, EndPos Pos
+	Until            bool
+	Cond             []*Stmt
+	Do               []*Stmt
+}
+
+func (c *WhileClause) Pos() Pos { return c.Position }
+func (c *WhileClause) End() Pos { return c.EndPos }
+
+// ForClause is a for NAME [in WORDS]; do ...; done loop
+type ForClause struct {
+	Position, EndPos Pos
+	Name             string
+	InPresent        bool
+	Items            []*Word
+	Do               []*Stmt
+}
+
+func (c *ForClause) Pos() Pos { return c.Position }
+func (c *ForClause) End() Pos { return c.EndPos }
+
+// CaseClause is a case WORD in ... esac statement
+type CaseClause struct {
+	Position, EndPos Pos
+	Word             *Word
+	Items            []*CaseItem
+}
+
+func (c *CaseClause) Pos() Pos { return c.Position }
+func (c *CaseClause) End() Pos { return c.EndPos }
+
+// CaseItem is one pattern list and its commands
+type CaseItem struct {
+	Position, EndPos Pos
+	Patterns         []*Word
+	Stmts            []*Stmt
+}
+
+func (c *CaseItem) Pos() Pos { return c.Position }
+func (c *CaseItem) End() Pos { return c.EndPos }
+
+func (*BinaryCmd) commandNode()   {}
+func (*CallExpr) commandNode()    {}
+func (*Subshell) commandNode()    {}
+func (*Block) commandNode()       {}
+func (*IfClause) commandNode()    {}
+func (*WhileClause) commandNode() {}
+func (*ForClause) commandNode()   {}
+func (*CaseClause) commandNode()  {}
+
+// Assign is a NAME=value prefix of a simple command
+type Assign struct {
+	NamePos Pos
+	Name    string
+	Value   *Word // nil for NAME=
+}
+
+func (a *Assign) Pos() Pos { return a.NamePos }
+func (a *Assign) End() Pos {
+	if a.Value != nil {
+		return a.Value.End()
+	}
+	return a.NamePos + Pos(len(a.Name)) + 1
+}
+
+// Redirect is an I/O redirection such as 2>&1, >file or <<EOF
+type Redirect struct {
+	OpPos   Pos
+	N       string // Explicit file descriptor, if any
+	Op      string // <, >, >>, <<, <<-, <<<, <&, >&, <>, >|, &>, &>>
+	Word    *Word
+	Heredoc string // Body of a << or <<- here-document
+}
+
+func (r *Redirect) Pos() Pos { return r.OpPos - Pos(len(r.N)) }
+func (r *Redirect) End() Pos {
+	if r.Word != nil {
+		return r.Word.End()
+	}
+	return r.OpPos + Pos(len(r.Op))
+}
+
+// Word is a shell word made of one or more parts
+type Word struct {
+	Parts []WordPart
+}
+
+func (w *Word) Pos() Pos { return w.Parts[0].Pos() }
+func (w *Word) End() Pos { return w.Parts[len(w.Parts)-1].End() }
+
+// Lit returns the word's value when it involves no expansions, with quotes
+// removed, and whether it was fully literal
+func (w *Word) Lit() (string, bool) {
+	value := ""
+	for _, part := range w.Parts {
+		switch p := part.(type) {
+		case *Lit:
+			value += p.Value
+		case *SglQuoted:
+			value += p.Value
+		case *DblQuoted:
+			for _, inner := range p.Parts {
+				lit, ok := inner.(*Lit)
+				if !ok {
+					return value, false
+				}
+				value += lit.Value
+			}
+		default:
+			return value, false
+		}
+	}
+	return value, true
+}
+
+// Text renders the word with quotes removed and expansions written as
+// $NAME, ${...}, $(...) or $((...)); it is meant for heuristics, not execution
+func (w *Word) Text() string {
+	return partsText(w.Parts)
+}
+
+func partsText(parts []WordPart) string {
+	text := ""
+	for _, part := range parts {
+		switch p := part.(type) {
+		case *Lit:
+			text += p.Value
+		case *SglQuoted:
+			text += p.Value
+		case *DblQuoted:
+			text += partsText(p.Parts)
+		case *ParamExp:
+			if p.Short {
+				text += "$" + p.Name
+			} else {
+				text += "${" + p.Name + "}"
+			}
+		case *CmdSubst:
+			text += "$(...)"
+		case *ArithExp:
+			text += "$((" + p.Expr + "))"
+		}
+	}
+	return text
+}
+
+// Lit is unquoted literal text, with backslash escapes resolved
+type Lit struct {
+	ValuePos, ValueEnd Pos
+	Value              string
+}
+
+func (l *Lit) Pos() Pos { return l.ValuePos }
+func (l *Lit) End() Pos { return l.ValueEnd }
+
+// SglQuoted is '...' text
+type SglQuoted struct {
+	Left, Right Pos
+	Value       string
+}
+
+func (q *SglQuoted) Pos() Pos { return q.Left }
+func (q *SglQuoted) End() Pos { return q.Right + 1 }
+
+// DblQuoted is "..." text, which may contain expansions
+type DblQuoted struct {
+	Left, Right Pos
+	Parts       []WordPart
+}
+
+func (q *DblQuoted) Pos() Pos { return q.Left }
+func (q *DblQuoted) End() Pos { return q.Right + 1 }
+
+// ParamExp is a variable reference: $NAME or ${NAME[op word]}
+type ParamExp struct {
+	Dollar, EndPos Pos
+	Short          bool   // $NAME without braces
+	Length         bool   // ${#NAME}
+	Name           string // Variable name or special parameter
+	Op             string // Modifier such as :-, :+, %, ##
+	Word           *Word  // Operand of Op, may be nil
+}
+
+func (p *ParamExp) Pos() Pos { return p.Dollar }
+func (p *ParamExp) End() Pos { return p.EndPos }
+
+// CmdSubst is $(...) or `...` command substitution
+type CmdSubst struct {
+	Left, Right Pos
+	Backquotes  bool
+	Stmts       []*Stmt
+}
+
+func (c *CmdSubst) Pos() Pos { return c.Left }
+func (c *CmdSubst) End() Pos { return c.Right + 1 }
+
+// ArithExp is $((...)) arithmetic expansion, kept as raw text
+type ArithExp struct {
+	Left, Right Pos
+	Expr        string
+}
+
+func (a *ArithExp) Pos() Pos { return a.Left }
+func (a *ArithExp) End() Pos { return a.Right + 2 }
+
+func (*Lit) wordPartNode()       {}
+func (*SglQuoted) wordPartNode() {}
+func (*DblQuoted) wordPartNode() {}
+func (*ParamExp) wordPartNode()  {}
+func (*CmdSubst) wordPartNode()  {}
+func (*ArithExp) wordPartNode()  {}
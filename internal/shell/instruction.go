@@ -0,0 +1,284 @@
+package shell
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/dockerfile-parser/internal/parser"
+)
+
+// ErrExecForm is returned for instructions written in JSON exec form, which
+// are not interpreted by a shell
+var ErrExecForm = errors.New("instruction uses exec form and has no shell command")
+
+// Interpreters that run a RUN heredoc body as a shell script
+var shellInterpreters = map[string]bool{
+	"sh": true, "bash": true, "ash": true, "dash": true, "zsh": true, "ksh": true,
+	"/bin/sh": true, "/bin/bash": true, "/bin/ash": true, "/bin/dash": true,
+	"/usr/bin/bash": true,
+}
+
+// segment maps a run of shell source bytes to a Dockerfile line and column
+type segment struct {
+	offset int
+	pos    parser.Position
+}
+
+// Position maps an offset in the script source back to the Dockerfile.
+// Scripts from Parse, which have no Dockerfile, count lines within src.
+func (s *Script) Position(p Pos) parser.Position {
+	offset := int(p)
+	if len(s.mapping) == 0 {
+		prefix := s.Source
+		if offset <= len(prefix) && offset >= 0 {
+			prefix = prefix[:offset]
+		}
+		line := strings.Count(prefix, "\n") + 1
+		column := len(prefix) - strings.LastIndexByte(prefix, '\n')
+		return parser.Position{Line: line, Column: column, Offset: offset}
+	}
+
+	idx := sort.Search(len(s.mapping), func(i int) bool {
+		return s.mapping[i].offset > offset
+	}) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	seg := s.mapping[idx]
+	pos := seg.pos
+	pos.Column += offset - seg.offset
+	pos.Offset += offset - seg.offset
+	return pos
+}
+
+// ParseInstruction parses the shell-form command of a RUN, CMD, ENTRYPOINT
+// or HEALTHCHECK instruction. Positions map back to the Dockerfile source,
+// across line continuations and comment lines.
+func ParseInstruction(df *parser.ParsedDockerfile, inst *parser.Instruction) (*Script, error) {
+	if inst.JSONForm {
+		return nil, ErrExecForm
+	}
+	if inst.Command == "HEALTHCHECK" && len(inst.Args) > 0 && inst.Args[0] == "NONE" {
+		return nil, ErrExecForm
+	}
+
+	src, mapping := commandSource(df, inst)
+	if inst.Command == "HEALTHCHECK" && strings.HasPrefix(strings.TrimSpace(src), "[") {
+		return nil, ErrExecForm
+	}
+	return parseMapped(src, mapping)
+}
+
+// ParseHeredoc parses the heredoc body of a RUN instruction when the body is
+// executed as a shell script, as with "RUN <<EOF" or "RUN bash <<EOF". It
+// returns nil without error when the instruction has no shell heredoc.
+func ParseHeredoc(df *parser.ParsedDockerfile, inst *parser.Instruction) (*Script, error) {
+	if inst.Command != "RUN" || inst.Heredoc == nil {
+		return nil, nil
+	}
+
+	command, err := ParseInstruction(df, inst)
+	if err != nil || !runsHeredocAsScript(command) {
+		return nil, err
+	}
+
+	lines := strings.Split(df.Raw, "\n")
+	start := commandEndLine(df, lines, inst.Range.Start.Line) + 1
+	delimiter := heredocDelimiter(inst.Heredoc)
+
+	var src strings.Builder
+	mapping := make([]segment, 0)
+	for n := start; n <= len(lines); n++ {
+		line := lines[n-1]
+		check := line
+		if inst.Heredoc.StripLeadingTabs {
+			check = strings.TrimLeft(line, "\t")
+		}
+		if strings.TrimSpace(check) == delimiter {
+			break
+		}
+		mapping = append(mapping, segment{
+			offset: src.Len(),
+			pos:    parser.Position{Line: n, Column: 1, FilePath: inst.Range.Start.FilePath},
+		})
+		src.WriteString(line)
+		src.WriteByte('\n')
+	}
+
+	return parseMapped(src.String(), mapping)
+}
+
+// Scripts returns the parsed shell code of an instruction: its command and,
+// for RUN heredocs run by a shell, the heredoc body. Code that is not shell
+// or fails to parse is left out.
+func Scripts(df *parser.ParsedDockerfile, inst *parser.Instruction) []*Script {
+	scripts := make([]*Script, 0, 2)
+	switch inst.Command {
+	case "RUN", "CMD", "ENTRYPOINT", "HEALTHCHECK":
+	default:
+		return scripts
+	}
+
+	if script, err := ParseInstruction(df, inst); err == nil {
+		scripts = append(scripts, script)
+	}
+	if body, err := ParseHeredoc(df, inst); err == nil && body != nil {
+		scripts = append(scripts, body)
+	}
+	return scripts
+}
+
+// parseMapped parses src and attaches its Dockerfile mapping
+func parseMapped(src string, mapping []segment) (*Script, error) {
+	script, err := Parse(src)
+	if err != nil {
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) && len(mapping) > 0 {
+			pos := (&Script{Source: src, mapping: mapping}).Position(syntaxErr.Pos)
+			return nil, parser.NewSyntaxError(pos, "Invalid shell syntax: "+syntaxErr.Message, "")
+		}
+		return nil, err
+	}
+	script.mapping = mapping
+	return script, nil
+}
+
+// commandSource reconstructs the shell command of inst the way the
+// Dockerfile frontend sees it: the instruction keyword and flags removed,
+// escaped newlines joined and comment lines dropped
+func commandSource(df *parser.ParsedDockerfile, inst *parser.Instruction) (string, []segment) {
+	lines := strings.Split(df.Raw, "\n")
+	escape := byte('\\')
+	if df.EscapeChar == '`' {
+		escape = '`'
+	}
+
+	var src strings.Builder
+	mapping := make([]segment, 0)
+	for n := inst.Range.Start.Line; n >= 1 && n <= len(lines); n++ {
+		line := strings.TrimSuffix(lines[n-1], "\r")
+		column := 0
+		if n == inst.Range.Start.Line {
+			column = headerLength(inst.Command, line)
+		} else if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		text := line[column:]
+		trimmed := strings.TrimRight(text, " \t")
+		continued := strings.HasSuffix(trimmed, string(escape))
+		if continued {
+			text = trimmed[:len(trimmed)-1]
+		}
+
+		mapping = append(mapping, segment{
+			offset: src.Len(),
+			pos:    parser.Position{Line: n, Column: column + 1, FilePath: inst.Range.Start.FilePath},
+		})
+		src.WriteString(text)
+		if !continued {
+			break
+		}
+	}
+	return src.String(), mapping
+}
+
+// commandEndLine returns the last line of the command that starts at line
+func commandEndLine(df *parser.ParsedDockerfile, lines []string, line int) int {
+	escape := "\\"
+	if df.EscapeChar == '`' {
+		escape = "`"
+	}
+	for n := line; n <= len(lines); n++ {
+		text := lines[n-1]
+		if n != line && strings.HasPrefix(strings.TrimSpace(text), "#") {
+			continue
+		}
+		if !strings.HasSuffix(strings.TrimRight(text, " \t\r"), escape) {
+			return n
+		}
+	}
+	return len(lines)
+}
+
+// headerLength returns how many bytes of the first line precede the shell
+// command: the keyword, RUN flags, or HEALTHCHECK options and CMD
+func headerLength(command, line string) int {
+	i := 0
+	skipSpace := func() {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+	}
+	skipWord := func() {
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+	}
+
+	skipSpace()
+	skipWord() // Instruction keyword
+	skipSpace()
+
+	switch command {
+	case "RUN":
+		for strings.HasPrefix(line[i:], "--") {
+			skipWord()
+			skipSpace()
+		}
+	case "HEALTHCHECK":
+		for strings.HasPrefix(line[i:], "--") {
+			skipWord()
+			skipSpace()
+		}
+		if len(line)-i >= 3 && strings.EqualFold(line[i:i+3], "CMD") {
+			i += 3
+			skipSpace()
+		}
+	}
+	return i
+}
+
+// heredocDelimiter returns the terminating word of a heredoc, unquoted
+func heredocDelimiter(h *parser.Heredoc) string {
+	delimiter := h.Delimiter
+	if delimiter == "" {
+		delimiter = h.Identifier
+	}
+	delimiter = strings.TrimPrefix(delimiter, "<<")
+	delimiter = strings.TrimPrefix(delimiter, "-")
+	return strings.Trim(delimiter, `"'`)
+}
+
+// runsHeredocAsScript reports whether a RUN command feeds its heredoc to a
+// shell: either the command is only the heredoc, or a shell reads it
+func runsHeredocAsScript(command *Script) bool {
+	if len(command.Stmts) != 1 {
+		return false
+	}
+	stmt := command.Stmts[0]
+	call, ok := stmt.Cmd.(*CallExpr)
+	if !ok {
+		return false
+	}
+
+	hasHeredoc := false
+	for _, r := range stmt.Redirs {
+		if r.Op == "<<" || r.Op == "<<-" {
+			hasHeredoc = true
+		}
+	}
+	if !hasHeredoc {
+		return false
+	}
+	if len(call.Args) == 0 {
+		return true
+	}
+
+	name := call.Name()
+	if name == "/usr/bin/env" && len(call.Args) > 1 {
+		name, _ = call.Args[1].Lit()
+	}
+	return shellInterpreters[name]
+}
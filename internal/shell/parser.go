@@ -0,0 +1,971 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxNesting bounds recursion so that hostile input cannot exhaust the stack
+const maxNesting = 500
+
+// Script is a parsed shell program
+type Script struct {
+	Stmts  []*Stmt
+	Source string // Shell source the positions refer to
+
+	mapping []segment // Maps source offsets back to Dockerfile positions
+}
+
+func (s *Script) Pos() Pos { return 0 }
+func (s *Script) End() Pos { return Pos(len(s.Source)) }
+
+// Text returns the source text spanned by node
+func (s *Script) Text(node Node) string {
+	start, end := int(node.Pos()), int(node.End())
+	if start < 0 || end > len(s.Source) || start > end {
+		return ""
+	}
+	return s.Source[start:end]
+}
+
+// SyntaxError describes a shell syntax error at an offset in the source
+type SyntaxError struct {
+	Pos     Pos
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("shell syntax error at offset %d: %s", e.Pos, e.Message)
+}
+
+// Parse parses a POSIX shell program, with the bash redirections commonly
+// found in Dockerfiles (&>, <<<). Positions are byte offsets into src.
+func Parse(src string) (script *Script, err error) {
+	p := &shellParser{src: src, end: len(src)}
+
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxErr, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			script = nil
+			err = syntaxErr
+		}
+	}()
+
+	stmts := p.stmts(func() bool { return false })
+	if p.pos < p.end {
+		p.fail(fmt.Sprintf("unexpected %q", p.src[p.pos:p.pos+1]))
+	}
+	return &Script{Stmts: stmts, Source: src}, nil
+}
+
+// shellParser is a recursive descent parser over the raw source
+type shellParser struct {
+	src     string
+	pos     int
+	end     int         // Parsing stops here; narrowed inside backquotes
+	depth   int         // Current nesting depth
+	pending []*Redirect // Here-documents whose bodies follow the next newline
+}
+
+func (p *shellParser) fail(message string) {
+	panic(&SyntaxError{Pos: Pos(p.pos), Message: message})
+}
+
+func (p *shellParser) enter() {
+	p.depth++
+	if p.depth > maxNesting {
+		p.fail("nesting too deep")
+	}
+}
+
+func (p *shellParser) leave() {
+	p.depth--
+}
+
+func (p *shellParser) eof() bool {
+	return p.pos >= p.end
+}
+
+func (p *shellParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *shellParser) peekAt(offset int) byte {
+	if p.pos+offset >= p.end {
+		return 0
+	}
+	return p.src[p.pos+offset]
+}
+
+// skipBlanks skips spaces, tabs, escaped newlines and comments
+func (p *shellParser) skipBlanks() {
+	for !p.eof() {
+		switch ch := p.peek(); {
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			p.pos++
+		case ch == '\\' && p.peekAt(1) == '\n':
+			p.pos += 2
+		case ch == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipNewlines skips blank lines, reading pending here-document bodies
+func (p *shellParser) skipNewlines() {
+	for {
+		p.skipBlanks()
+		if p.peek() != '\n' {
+			return
+		}
+		p.newline()
+	}
+}
+
+// newline consumes a newline and any here-document bodies that follow it
+func (p *shellParser) newline() {
+	p.pos++
+	pending := p.pending
+	p.pending = nil
+	for _, r := range pending {
+		p.heredocBody(r)
+	}
+}
+
+// heredocBody reads lines up to the delimiter of r
+func (p *shellParser) heredocBody(r *Redirect) {
+	delimiter := ""
+	if r.Word != nil {
+		delimiter = r.Word.Text()
+	}
+
+	var body strings.Builder
+	for !p.eof() {
+		lineEnd := strings.IndexByte(p.src[p.pos:p.end], '\n')
+		next := p.end
+		line := p.src[p.pos:p.end]
+		if lineEnd >= 0 {
+			line = p.src[p.pos : p.pos+lineEnd]
+			next = p.pos + lineEnd + 1
+		}
+		p.pos = next
+
+		check := line
+		if r.Op == "<<-" {
+			check = strings.TrimLeft(line, "\t")
+		}
+		if check == delimiter {
+			break
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	r.Heredoc = body.String()
+}
+
+// operators in longest-match order
+var operators = []string{
+	"&>>", "<<-", "<<<",
+	"&&", "||", ";;", "<<", ">>", "<&", ">&", "<>", ">|", "&>",
+	";", "&", "|", "(", ")", "<", ">",
+}
+
+// op returns the operator at the current position, if any
+func (p *shellParser) op() string {
+	rest := p.src[p.pos:p.end]
+	for _, op := range operators {
+		if strings.HasPrefix(rest, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isRedirectOp(op string) bool {
+	switch op {
+	case "<", ">", ">>", "<<", "<<-", "<<<", "<&", ">&", "<>", ">|", "&>", "&>>":
+		return true
+	}
+	return false
+}
+
+// isMeta reports whether ch ends an unquoted word
+func isMeta(ch byte) bool {
+	switch ch {
+	case ' ', '\t', '\r', '\n', ';', '&', '|', '(', ')', '<', '>':
+		return true
+	}
+	return false
+}
+
+// atWord reports whether the next word is exactly the reserved word w
+func (p *shellParser) atWord(w string) bool {
+	if !strings.HasPrefix(p.src[p.pos:p.end], w) {
+		return false
+	}
+	next := p.pos + len(w)
+	return next >= p.end || isMeta(p.src[next])
+}
+
+// expectWord consumes reserved word w or fails
+func (p *shellParser) expectWord(w string) Pos {
+	p.skipNewlines()
+	if !p.atWord(w) {
+		p.fail("expected " + w)
+	}
+	pos := Pos(p.pos)
+	p.pos += len(w)
+	return pos
+}
+
+// stmts parses a command list until stop reports true or input ends
+func (p *shellParser) stmts(stop func() bool) []*Stmt {
+	p.enter()
+	defer p.leave()
+
+	list := make([]*Stmt, 0)
+	for {
+		p.skipNewlines()
+		if p.eof() || stop() {
+			return list
+		}
+
+		stmt := p.andOr()
+		list = append(list, stmt)
+
+		p.skipBlanks()
+		switch p.op() {
+		case ";":
+			p.pos++
+		case "&":
+			stmt.Background = true
+			p.pos++
+		default:
+			if p.peek() == '\n' {
+				p.newline()
+			} else if !p.eof() && !stop() {
+				p.fail(fmt.Sprintf("unexpected %q", p.src[p.pos:p.pos+1]))
+			}
+		}
+	}
+}
+
+// stopAt builds a stop function for the given reserved words and operators
+func (p *shellParser) stopAt(words ...string) func() bool {
+	return func() bool {
+		for _, w := range words {
+			if w == ")" || w == ";;" {
+				if p.op() == w {
+					return true
+				}
+				continue
+			}
+			if p.atWord(w) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// andOr parses pipelines joined by && and ||
+func (p *shellParser) andOr() *Stmt {
+	x := p.pipeline()
+	for {
+		p.skipBlanks()
+		op := p.op()
+		if op != "&&" && op != "||" {
+			return x
+		}
+		opPos := Pos(p.pos)
+		p.pos += 2
+		p.skipNewlines()
+		y := p.pipeline()
+
+		binOp := AndOp
+		if op == "||" {
+			binOp = OrOp
+		}
+		x = &Stmt{
+			Position: x.Position,
+			EndPos:   y.EndPos,
+			Cmd:      &BinaryCmd{OpPos: opPos, Op: binOp, X: x, Y: y},
+		}
+	}
+}
+
+// pipeline parses [!] command | command ...
+func (p *shellParser) pipeline() *Stmt {
+	p.skipBlanks()
+	negated := false
+	start := Pos(p.pos)
+	if p.atWord("!") {
+		negated = true
+		p.pos++
+		p.skipBlanks()
+	}
+
+	x := p.command()
+	for {
+		p.skipBlanks()
+		if p.op() != "|" {
+			break
+		}
+		opPos := Pos(p.pos)
+		p.pos++
+		p.skipNewlines()
+		y := p.command()
+		x = &Stmt{
+			Position: x.Position,
+			EndPos:   y.EndPos,
+			Cmd:      &BinaryCmd{OpPos: opPos, Op: PipeOp, X: x, Y: y},
+		}
+	}
+
+	if negated {
+		x.Negated = true
+		x.Position = start
+	}
+	return x
+}
+
+// command parses a simple or compound command with its redirections
+func (p *shellParser) command() *Stmt {
+	p.enter()
+	defer p.leave()
+
+	p.skipBlanks()
+	stmt := &Stmt{Position: Pos(p.pos)}
+
+	switch {
+	case p.op() == "(":
+		stmt.Cmd = p.subshell()
+	case p.atWord("{"):
+		stmt.Cmd = p.block()
+	case p.atWord("if"):
+		stmt.Cmd = p.ifClause()
+	case p.atWord("while"), p.atWord("until"):
+		stmt.Cmd = p.whileClause()
+	case p.atWord("for"):
+		stmt.Cmd = p.forClause()
+	case p.atWord("case"):
+		stmt.Cmd = p.caseClause()
+	default:
+		p.simpleCommand(stmt)
+		return stmt
+	}
+
+	// Compound commands may be followed by redirections
+	for {
+		p.skipBlanks()
+		if r := p.redirect(); r != nil {
+			stmt.Redirs = append(stmt.Redirs, r)
+			continue
+		}
+		break
+	}
+	stmt.EndPos = stmt.Cmd.End()
+	if n := len(stmt.Redirs); n > 0 {
+		stmt.EndPos = stmt.Redirs[n-1].End()
+	}
+	return stmt
+}
+
+// simpleCommand parses assignments, words and redirections into stmt
+func (p *shellParser) simpleCommand(stmt *Stmt) {
+	call := &CallExpr{}
+	end := Pos(p.pos)
+
+	for {
+		p.skipBlanks()
+		if p.eof() {
+			break
+		}
+		if r := p.redirect(); r != nil {
+			stmt.Redirs = append(stmt.Redirs, r)
+			end = r.End()
+			continue
+		}
+		if isMeta(p.peek()) {
+			break
+		}
+
+		word := p.word()
+		if word == nil {
+			break
+		}
+		if len(call.Args) == 0 {
+			if assign := toAssign(word); assign != nil {
+				call.Assigns = append(call.Assigns, assign)
+				end = word.End()
+				continue
+			}
+		}
+		call.Args = append(call.Args, word)
+		end = word.End()
+	}
+
+	if len(call.Args) == 0 && len(call.Assigns) == 0 && len(stmt.Redirs) == 0 {
+		if p.eof() {
+			p.fail("expected a command")
+		}
+		p.fail(fmt.Sprintf("unexpected %q", p.src[p.pos:p.pos+1]))
+	}
+
+	stmt.Cmd = call
+	stmt.EndPos = end
+}
+
+// toAssign converts a NAME=value word into an assignment
+func toAssign(word *Word) *Assign {
+	lit, ok := word.Parts[0].(*Lit)
+	if !ok {
+		return nil
+	}
+	eq := strings.IndexByte(lit.Value, '=')
+	if eq <= 0 || !isName(lit.Value[:eq]) {
+		return nil
+	}
+
+	assign := &Assign{NamePos: lit.ValuePos, Name: lit.Value[:eq]}
+	parts := make([]WordPart, 0, len(word.Parts))
+	if rest := lit.Value[eq+1:]; rest != "" {
+		parts = append(parts, &Lit{ValuePos: lit.ValuePos + Pos(eq+1), ValueEnd: lit.ValueEnd, Value: rest})
+	}
+	parts = append(parts, word.Parts[1:]...)
+	if len(parts) > 0 {
+		assign.Value = &Word{Parts: parts}
+	}
+	return assign
+}
+
+// redirect parses [n]op word, returning nil when none is present
+func (p *shellParser) redirect() *Redirect {
+	start := p.pos
+	n := ""
+	for i := p.pos; i < p.end && p.src[i] >= '0' && p.src[i] <= '9'; i++ {
+		n += string(p.src[i])
+	}
+	p.pos += len(n)
+
+	op := p.op()
+	if !isRedirectOp(op) {
+		p.pos = start
+		return nil
+	}
+
+	r := &Redirect{OpPos: Pos(p.pos), N: n, Op: op}
+	p.pos += len(op)
+	p.skipBlanks()
+	if p.eof() || isMeta(p.peek()) {
+		p.fail("expected a word after " + op)
+	}
+	r.Word = p.word()
+
+	if op == "<<" || op == "<<-" {
+		p.pending = append(p.pending, r)
+	}
+	return r
+}
+
+// subshell parses ( list )
+func (p *shellParser) subshell() *Subshell {
+	s := &Subshell{Lparen: Pos(p.pos)}
+	p.pos++
+	s.Stmts = p.stmts(p.stopAt(")"))
+	if p.op() != ")" {
+		p.fail("expected )")
+	}
+	s.Rparen = Pos(p.pos)
+	p.pos++
+	return s
+}
+
+// block parses { list; }
+func (p *shellParser) block() *Block {
+	b := &Block{Lbrace: Pos(p.pos)}
+	p.pos++
+	b.Stmts = p.stmts(p.stopAt("}"))
+	b.Rbrace = p.expectWord("}")
+	return b
+}
+
+// ifClause parses if/elif/else/fi
+func (p *shellParser) ifClause() *IfClause {
+	return p.ifBranch(len("if"))
+}
+
+// ifBranch parses an if or elif branch whose keyword is keywordLen bytes
+func (p *shellParser) ifBranch(keywordLen int) *IfClause {
+	clause := &IfClause{Position: Pos(p.pos)}
+	p.pos += keywordLen
+	clause.Cond = p.stmts(p.stopAt("then"))
+	p.expectWord("then")
+	clause.Then = p.stmts(p.stopAt("elif", "else", "fi"))
+
+	p.skipNewlines()
+	switch {
+	case p.atWord("elif"):
+		// elif is parsed as a nested if sharing the closing fi
+		clause.Else = p.ifBranch(len("elif"))
+		clause.EndPos = clause.Else.EndPos
+		return clause
+	case p.atWord("else"):
+		elsePos := Pos(p.pos)
+		p.pos += len("else")
+		clause.Else = &IfClause{Position: elsePos}
+		clause.Else.Then = p.stmts(p.stopAt("fi"))
+	}
+
+	fi := p.expectWord("fi")
+	clause.EndPos = fi + Pos(len("fi"))
+	if clause.Else != nil {
+		clause.Else.EndPos = clause.EndPos
+	}
+	return clause
+}
+
+// whileClause parses while/until ...; do ...; done
+func (p *shellParser) whileClause() *WhileClause {
+	clause := &WhileClause{Position: Pos(p.pos), Until: p.atWord("until")}
+	if clause.Until {
+		p.pos += len("until")
+	} else {
+		p.pos += len("while")
+	}
+	clause.Cond = p.stmts(p.stopAt("do"))
+	p.expectWord("do")
+	clause.Do = p.stmts(p.stopAt("done"))
+	done := p.expectWord("done")
+	clause.EndPos = done + Pos(len("done"))
+	return clause
+}
+
+// forClause parses for NAME [in WORDS]; do ...; done
+func (p *shellParser) forClause() *ForClause {
+	clause := &ForClause{Position: Pos(p.pos)}
+	p.pos += len("for")
+	p.skipBlanks()
+
+	name := p.word()
+	if name == nil {
+		p.fail("expected a loop variable")
+	}
+	value, ok := name.Lit()
+	if !ok || !isName(value) {
+		p.fail("invalid loop variable")
+	}
+	clause.Name = value
+
+	p.skipNewlines()
+	if p.atWord("in") {
+		clause.InPresent = true
+		p.pos += len("in")
+		for {
+			p.skipBlanks()
+			if p.eof() || isMeta(p.peek()) {
+				break
+			}
+			clause.Items = append(clause.Items, p.word())
+		}
+	}
+
+	p.skipBlanks()
+	if p.op() == ";" {
+		p.pos++
+	}
+	p.expectWord("do")
+	clause.Do = p.stmts(p.stopAt("done"))
+	done := p.expectWord("done")
+	clause.EndPos = done + Pos(len("done"))
+	return clause
+}
+
+// caseClause parses case WORD in [(]pattern[|pattern]) list ;; ... esac
+func (p *shellParser) caseClause() *CaseClause {
+	clause := &CaseClause{Position: Pos(p.pos)}
+	p.pos += len("case")
+	p.skipBlanks()
+	clause.Word = p.word()
+	if clause.Word == nil {
+		p.fail("expected a word after case")
+	}
+	p.expectWord("in")
+
+	for {
+		p.skipNewlines()
+		if p.atWord("esac") {
+			break
+		}
+		if p.eof() {
+			p.fail("expected esac")
+		}
+
+		item := &CaseItem{Position: Pos(p.pos)}
+		if p.op() == "(" {
+			p.pos++
+		}
+		for {
+			p.skipBlanks()
+			pattern := p.word()
+			if pattern == nil {
+				p.fail("expected a case pattern")
+			}
+			item.Patterns = append(item.Patterns, pattern)
+			p.skipBlanks()
+			if p.op() == "|" {
+				p.pos++
+				continue
+			}
+			break
+		}
+		if p.op() != ")" {
+			p.fail("expected ) after case pattern")
+		}
+		p.pos++
+
+		item.Stmts = p.stmts(p.stopAt(";;", "esac"))
+		item.EndPos = Pos(p.pos)
+		if p.op() == ";;" {
+			p.pos += 2
+		}
+		clause.Items = append(clause.Items, item)
+	}
+
+	esac := p.expectWord("esac")
+	clause.EndPos = esac + Pos(len("esac"))
+	return clause
+}
+
+// word parses one word, returning nil if none starts here
+func (p *shellParser) word() *Word {
+	parts := p.wordParts(isMeta)
+	if len(parts) == 0 {
+		return nil
+	}
+	return &Word{Parts: parts}
+}
+
+// wordParts reads word parts until stop reports true for an unquoted byte
+func (p *shellParser) wordParts(stop func(byte) bool) []WordPart {
+	parts := make([]WordPart, 0)
+	var lit strings.Builder
+	litStart := p.pos
+
+	flush := func() {
+		if lit.Len() > 0 {
+			parts = append(parts, &Lit{ValuePos: Pos(litStart), ValueEnd: Pos(p.pos), Value: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for !p.eof() {
+		ch := p.peek()
+		if stop(ch) {
+			break
+		}
+
+		switch ch {
+		case '\\':
+			if lit.Len() == 0 {
+				litStart = p.pos
+			}
+			if p.peekAt(1) == '\n' {
+				// Line continuation inside a word
+				p.pos += 2
+				continue
+			}
+			if p.pos+1 < p.end {
+				lit.WriteByte(p.src[p.pos+1])
+				p.pos += 2
+			} else {
+				p.pos++
+			}
+		case '\'':
+			flush()
+			parts = append(parts, p.singleQuoted())
+			litStart = p.pos
+		case '"':
+			flush()
+			parts = append(parts, p.doubleQuoted())
+			litStart = p.pos
+		case '$':
+			flush()
+			parts = append(parts, p.dollar())
+			litStart = p.pos
+		case '`':
+			flush()
+			parts = append(parts, p.backquote())
+			litStart = p.pos
+		default:
+			if lit.Len() == 0 {
+				litStart = p.pos
+			}
+			lit.WriteByte(ch)
+			p.pos++
+		}
+	}
+	flush()
+	return parts
+}
+
+// singleQuoted parses '...'
+func (p *shellParser) singleQuoted() *SglQuoted {
+	q := &SglQuoted{Left: Pos(p.pos)}
+	closing := strings.IndexByte(p.src[p.pos+1:p.end], '\'')
+	if closing < 0 {
+		p.fail("unterminated single quote")
+	}
+	q.Value = p.src[p.pos+1 : p.pos+1+closing]
+	q.Right = Pos(p.pos + 1 + closing)
+	p.pos = int(q.Right) + 1
+	return q
+}
+
+// doubleQuoted parses "..." with expansions inside
+func (p *shellParser) doubleQuoted() *DblQuoted {
+	q := &DblQuoted{Left: Pos(p.pos)}
+	p.pos++
+
+	var lit strings.Builder
+	litStart := p.pos
+	flush := func() {
+		if lit.Len() > 0 {
+			q.Parts = append(q.Parts, &Lit{ValuePos: Pos(litStart), ValueEnd: Pos(p.pos), Value: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for {
+		if p.eof() {
+			p.fail("unterminated double quote")
+		}
+		ch := p.peek()
+		switch ch {
+		case '"':
+			flush()
+			q.Right = Pos(p.pos)
+			p.pos++
+			return q
+		case '\\':
+			if lit.Len() == 0 {
+				litStart = p.pos
+			}
+			next := p.peekAt(1)
+			switch next {
+			case '\n':
+				p.pos += 2
+			case '$', '`', '"', '\\':
+				lit.WriteByte(next)
+				p.pos += 2
+			default:
+				lit.WriteByte(ch)
+				p.pos++
+			}
+		case '$':
+			flush()
+			q.Parts = append(q.Parts, p.dollar())
+			litStart = p.pos
+		case '`':
+			flush()
+			q.Parts = append(q.Parts, p.backquote())
+			litStart = p.pos
+		default:
+			if lit.Len() == 0 {
+				litStart = p.pos
+			}
+			lit.WriteByte(ch)
+			p.pos++
+		}
+	}
+}
+
+// dollar parses an expansion starting at $
+func (p *shellParser) dollar() WordPart {
+	start := p.pos
+	switch next := p.peekAt(1); {
+	case next == '(' && p.peekAt(2) == '(':
+		return p.arithmetic()
+	case next == '(':
+		p.pos += 2
+		subst := &CmdSubst{Left: Pos(start)}
+		subst.Stmts = p.stmts(p.stopAt(")"))
+		if p.op() != ")" {
+			p.fail("unterminated command substitution")
+		}
+		subst.Right = Pos(p.pos)
+		p.pos++
+		return subst
+	case next == '{':
+		return p.paramExp()
+	case isNameStart(next):
+		p.pos++
+		nameStart := p.pos
+		for !p.eof() && isNameByte(p.peek()) {
+			p.pos++
+		}
+		return &ParamExp{Dollar: Pos(start), EndPos: Pos(p.pos), Short: true, Name: p.src[nameStart:p.pos]}
+	case isSpecialParam(next):
+		p.pos += 2
+		return &ParamExp{Dollar: Pos(start), EndPos: Pos(p.pos), Short: true, Name: string(next)}
+	default:
+		// A lone $ is literal
+		p.pos++
+		return &Lit{ValuePos: Pos(start), ValueEnd: Pos(p.pos), Value: "$"}
+	}
+}
+
+// paramExpOps lists ${...} modifiers in longest-match order
+var paramExpOps = []string{":-", ":=", ":?", ":+", "%%", "##", "//", "-", "=", "?", "+", "%", "#", "/", ":"}
+
+// paramExp parses ${...}
+func (p *shellParser) paramExp() *ParamExp {
+	exp := &ParamExp{Dollar: Pos(p.pos)}
+	p.pos += 2
+
+	if p.peek() == '#' && p.peekAt(1) != '}' {
+		exp.Length = true
+		p.pos++
+	}
+
+	nameStart := p.pos
+	if isSpecialParam(p.peek()) {
+		p.pos++
+	} else {
+		for !p.eof() && isNameByte(p.peek()) {
+			p.pos++
+		}
+	}
+	exp.Name = p.src[nameStart:p.pos]
+	if exp.Name == "" {
+		p.fail("bad substitution")
+	}
+
+	if p.peek() != '}' {
+		rest := p.src[p.pos:p.end]
+		for _, op := range paramExpOps {
+			if strings.HasPrefix(rest, op) {
+				exp.Op = op
+				p.pos += len(op)
+				break
+			}
+		}
+		if exp.Op == "" {
+			p.fail("bad substitution")
+		}
+		braces := 0
+		parts := p.wordParts(func(ch byte) bool {
+			switch ch {
+			case '{':
+				braces++
+			case '}':
+				if braces == 0 {
+					return true
+				}
+				braces--
+			}
+			return false
+		})
+		if len(parts) > 0 {
+			exp.Word = &Word{Parts: parts}
+		}
+	}
+
+	if p.peek() != '}' {
+		p.fail("unterminated ${")
+	}
+	p.pos++
+	exp.EndPos = Pos(p.pos)
+	return exp
+}
+
+// arithmetic parses $((...)) as raw text
+func (p *shellParser) arithmetic() *ArithExp {
+	exp := &ArithExp{Left: Pos(p.pos)}
+	p.pos += 3
+	exprStart := p.pos
+
+	depth := 0
+	for !p.eof() {
+		switch p.peek() {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 && p.peekAt(1) == ')' {
+				exp.Expr = p.src[exprStart:p.pos]
+				exp.Right = Pos(p.pos)
+				p.pos += 2
+				return exp
+			}
+			depth--
+		}
+		p.pos++
+	}
+	p.fail("unterminated $((")
+	return nil
+}
+
+// backquote parses `...` by narrowing the parser to the quoted region
+func (p *shellParser) backquote() *CmdSubst {
+	subst := &CmdSubst{Left: Pos(p.pos), Backquotes: true}
+
+	closing := -1
+	for i := p.pos + 1; i < p.end; i++ {
+		if p.src[i] == '\\' {
+			i++
+			continue
+		}
+		if p.src[i] == '`' {
+			closing = i
+			break
+		}
+	}
+	if closing < 0 {
+		p.fail("unterminated backquote")
+	}
+
+	outerEnd := p.end
+	p.pos++
+	p.end = closing
+	subst.Stmts = p.stmts(func() bool { return false })
+	p.end = outerEnd
+
+	subst.Right = Pos(closing)
+	p.pos = closing + 1
+	return subst
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isNameByte(ch byte) bool {
+	return isNameStart(ch) || (ch >= '0' && ch <= '9')
+}
+
+func isSpecialParam(ch byte) bool {
+	return strings.IndexByte("@*#?$!-0123456789", ch) >= 0 && ch != 0
+}
+
+// isName reports whether s is a valid shell variable name
+func isName(s string) bool {
+	if s == "" || !isNameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isNameByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,178 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callTexts renders every simple command of the script as its source text
+func callTexts(script *Script) []string {
+	texts := make([]string, 0)
+	for _, call := range Calls(script) {
+		texts = append(texts, script.Text(call))
+	}
+	return texts
+}
+
+func TestParseCalls(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "simple command",
+			src:  "apt-get install -y curl",
+			want: []string{"apt-get install -y curl"},
+		},
+		{
+			name: "and list",
+			src:  "apt-get update && apt-get install -y curl && rm -rf /var/lib/apt/lists/*",
+			want: []string{"apt-get update", "apt-get install -y curl", "rm -rf /var/lib/apt/lists/*"},
+		},
+		{
+			name: "semicolons and newlines",
+			src:  "cd /app; make\nmake install",
+			want: []string{"cd /app", "make", "make install"},
+		},
+		{
+			name: "pipeline",
+			src:  "curl -fsSL https://example.com/install.sh | sh -s -- -y",
+			want: []string{"curl -fsSL https://example.com/install.sh", "sh -s -- -y"},
+		},
+		{
+			name: "assignments",
+			src:  "CGO_ENABLED=0 go build -o /out/app .",
+			want: []string{"CGO_ENABLED=0 go build -o /out/app ."},
+		},
+		{
+			name: "quoted words",
+			src:  `echo "a && b" 'c; d'`,
+			want: []string{`echo "a && b" 'c; d'`},
+		},
+		{
+			name: "command substitution",
+			src:  "echo $(uname -m)",
+			want: []string{"echo $(uname -m)", "uname -m"},
+		},
+		{
+			name: "if clause",
+			src:  "if [ -f go.mod ]; then go mod download; else true; fi",
+			want: []string{"[ -f go.mod ]", "go mod download", "true"},
+		},
+		{
+			name: "for loop",
+			src:  "for f in a b; do chmod +x $f; done",
+			want: []string{"chmod +x $f"},
+		},
+		{
+			name: "subshell and block",
+			src:  "(cd web && npm ci) || { echo failed; exit 1; }",
+			want: []string{"cd web", "npm ci", "echo failed", "exit 1"},
+		},
+		{
+			name: "case clause",
+			src:  "case \"$TARGETARCH\" in amd64) echo x86 ;; arm64) echo arm ;; esac",
+			want: []string{"echo x86", "echo arm"},
+		},
+		{
+			name: "redirections",
+			src:  "echo ok > /tmp/out 2>&1",
+			want: []string{"echo ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := Parse(tt.src)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, callTexts(script))
+		})
+	}
+}
+
+func TestParseOperators(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []BinaryOp
+	}{
+		{src: "a && b", want: []BinaryOp{AndOp}},
+		{src: "a || b", want: []BinaryOp{OrOp}},
+		{src: "a | b | c", want: []BinaryOp{PipeOp, PipeOp}},
+		{src: "a && b || c", want: []BinaryOp{OrOp, AndOp}},
+		{src: "a; b", want: []BinaryOp{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			script, err := Parse(tt.src)
+			require.NoError(t, err)
+			ops := make([]BinaryOp, 0)
+			Walk(script, func(n Node) bool {
+				if bin, ok := n.(*BinaryCmd); ok {
+					ops = append(ops, bin.Op)
+				}
+				return true
+			})
+			assert.Equal(t, tt.want, ops)
+		})
+	}
+}
+
+func TestParsePipelines(t *testing.T) {
+	script, err := Parse("curl -s https://example.com | tar -xz | wc -l && ls | sort")
+	require.NoError(t, err)
+
+	pipes := Pipelines(script)
+	require.Len(t, pipes, 2)
+
+	names := func(pipe *BinaryCmd) []string {
+		result := make([]string, 0)
+		for _, stmt := range PipelineStmts(pipe) {
+			result = append(result, stmt.Cmd.(*CallExpr).Name())
+		}
+		return result
+	}
+	assert.Equal(t, []string{"curl", "tar", "wc"}, names(pipes[0]))
+	assert.Equal(t, []string{"ls", "sort"}, names(pipes[1]))
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{name: "unterminated single quote", src: "echo 'abc"},
+		{name: "unterminated double quote", src: `echo "abc`},
+		{name: "unclosed subshell", src: "(cd /app && make"},
+		{name: "if without fi", src: "if true; then echo"},
+		{name: "dangling operator", src: "make &&"},
+		{name: "deep nesting", src: strings.Repeat("(", maxNesting+1) + "true" + strings.Repeat(")", maxNesting+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := Parse(tt.src)
+			assert.Nil(t, script)
+			var syntaxErr *SyntaxError
+			assert.ErrorAs(t, err, &syntaxErr)
+		})
+	}
+}
+
+func TestParseAssigns(t *testing.T) {
+	script, err := Parse("GOOS=linux GOARCH=arm64 go build")
+	require.NoError(t, err)
+
+	calls := Calls(script)
+	require.Len(t, calls, 1)
+	call := calls[0]
+	assert.Equal(t, "go", call.Name())
+	require.Len(t, call.Assigns, 2)
+	assert.Equal(t, "GOOS", call.Assigns[0].Name)
+	assert.Equal(t, "linux", call.Assigns[0].Value.Text())
+	assert.Equal(t, "GOARCH", call.Assigns[1].Name)
+}
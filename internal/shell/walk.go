@@ -0,0 +1,145 @@
+package shell
+
+// Walk traverses the tree rooted at node in depth-first order, calling fn for
+// each node. Children are skipped when fn returns false.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Script:
+		walkStmts(n.Stmts, fn)
+	case *Stmt:
+		if n.Cmd != nil {
+			Walk(n.Cmd, fn)
+		}
+		for _, r := range n.Redirs {
+			Walk(r, fn)
+		}
+	case *BinaryCmd:
+		Walk(n.X, fn)
+		Walk(n.Y, fn)
+	case *CallExpr:
+		for _, a := range n.Assigns {
+			Walk(a, fn)
+		}
+		for _, w := range n.Args {
+			Walk(w, fn)
+		}
+	case *Subshell:
+		walkStmts(n.Stmts, fn)
+	case *Block:
+		walkStmts(n.Stmts, fn)
+	case *IfClause:
+		walkStmts(n.Cond, fn)
+		walkStmts(n.Then, fn)
+		if n.Else != nil {
+			Walk(n.Else, fn)
+		}
+	case *WhileClause:
+		walkStmts(n.Cond, fn)
+		walkStmts(n.Do, fn)
+	case *ForClause:
+		for _, w := range n.Items {
+			Walk(w, fn)
+		}
+		walkStmts(n.Do, fn)
+	case *CaseClause:
+		Walk(n.Word, fn)
+		for _, item := range n.Items {
+			Walk(item, fn)
+		}
+	case *CaseItem:
+		for _, w := range n.Patterns {
+			Walk(w, fn)
+		}
+		walkStmts(n.Stmts, fn)
+	case *Assign:
+		if n.Value != nil {
+			Walk(n.Value, fn)
+		}
+	case *Redirect:
+		if n.Word != nil {
+			Walk(n.Word, fn)
+		}
+	case *Word:
+		for _, part := range n.Parts {
+			Walk(part, fn)
+		}
+	case *DblQuoted:
+		for _, part := range n.Parts {
+			Walk(part, fn)
+		}
+	case *ParamExp:
+		if n.Word != nil {
+			Walk(n.Word, fn)
+		}
+	case *CmdSubst:
+		walkStmts(n.Stmts, fn)
+	}
+}
+
+func walkStmts(stmts []*Stmt, fn func(Node) bool) {
+	for _, s := range stmts {
+		Walk(s, fn)
+	}
+}
+
+// Calls returns every simple command in the tree, including those inside
+// compound commands and command substitutions, in source order
+func Calls(node Node) []*CallExpr {
+	calls := make([]*CallExpr, 0)
+	Walk(node, func(n Node) bool {
+		if call, ok := n.(*CallExpr); ok && len(call.Args) > 0 {
+			calls = append(calls, call)
+		}
+		return true
+	})
+	return calls
+}
+
+// Pipelines returns every | pipeline in the tree in source order. A pipeline
+// of several commands is reported once, as its outermost operator.
+func Pipelines(node Node) []*BinaryCmd {
+	pipes := make([]*BinaryCmd, 0)
+	inner := make(map[*BinaryCmd]bool)
+	Walk(node, func(n Node) bool {
+		bin, ok := n.(*BinaryCmd)
+		if !ok || bin.Op != PipeOp {
+			return true
+		}
+		// Pipes parse left-associatively, so a|b|c nests a|b in X
+		if x, ok := bin.X.Cmd.(*BinaryCmd); ok && x.Op == PipeOp && !bin.X.Negated {
+			inner[x] = true
+		}
+		if !inner[bin] {
+			pipes = append(pipes, bin)
+		}
+		return true
+	})
+	return pipes
+}
+
+// PipelineStmts flattens a pipeline into its commands, left to right
+func PipelineStmts(pipe *BinaryCmd) []*Stmt {
+	stmts := make([]*Stmt, 0)
+	if x, ok := pipe.X.Cmd.(*BinaryCmd); ok && x.Op == PipeOp && !pipe.X.Negated {
+		stmts = append(stmts, PipelineStmts(x)...)
+	} else {
+		stmts = append(stmts, pipe.X)
+	}
+	return append(stmts, pipe.Y)
+}
+
+// Params returns every variable reference in the tree
+func Params(node Node) []*ParamExp {
+	params := make([]*ParamExp, 0)
+	Walk(node, func(n Node) bool {
+		if p, ok := n.(*ParamExp); ok {
+			params = append(params, p)
+		}
+		return true
+	})
+	return params
+}
@@ -0,0 +1,6 @@
+package version
+
+// Version identifies the optimizer build. It is part of every cache key so
+// that upgrades never reuse stale results, and can be set at link time with
+// -ldflags "-X github.com/yourusername/dockerfile-parser/internal/version.Version=v1.2.3".
+var Version = "0.1.0-dev"